@@ -0,0 +1,128 @@
+package datagame
+
+import (
+	"bytes"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+// Ambience is a background sound loop layered under the music, chosen per map
+type Ambience int
+
+const (
+	AmbientWind Ambience = iota
+	AmbientBirds
+	ambientCount
+)
+
+// ambienceNames are the names maps use in their "ambient" list
+var ambienceNames = map[string]Ambience{"wind": AmbientWind, "birds": AmbientBirds}
+
+// ambientLoopS is the length of each ambient loop; slow changes in it complete
+// whole cycles so the loop point can't be heard
+const ambientLoopS = 24.0
+
+// ambientVolume is the loudness of the ambience at full sound effects volume
+const ambientVolume = 0.6
+
+func (am Ambience) render() []byte {
+	s := newSynth(ambientLoopS)
+	seed := uint32(11 + am)
+	rnd := func() float64 {
+		seed = seed*1664525 + 1013904223
+		return float64(seed>>8) / float64(1<<24)
+	}
+	switch am {
+	case AmbientWind:
+		// low-passed noise, swelling and easing in gusts; each ear gets its own noise
+		var l, r float64
+		for i := range s.l {
+			t := float64(i) / sampleRate
+			gust := 0.55 + 0.3*math.Sin(2*math.Pi*2*t/ambientLoopS) + 0.15*math.Sin(2*math.Pi*5*t/ambientLoopS+1)
+			k := 0.02 + 0.03*gust
+			l += k * (rnd()*2 - 1 - l)
+			r += k * (rnd()*2 - 1 - r)
+			s.l[i] = float32(l * gust * 0.9)
+			s.r[i] = float32(r * gust * 0.9)
+		}
+	case AmbientBirds:
+		// a few chirps at a time from random places, each a quick rising whistle
+		for call := 0; call < 14; call++ {
+			start := rnd() * ambientLoopS
+			pan := rnd()*1.6 - 0.8
+			base := 2200 + rnd()*1600
+			for n := 0; n < 2+int(rnd()*4); n++ {
+				s.chirp(start+float64(n)*0.11, 0.07, base, base*1.4, 0.05, pan)
+			}
+		}
+	}
+	return s.pcm()
+}
+
+// chirp is a sine sweeping from f0 to f1 with a smooth swell
+func (s *synth) chirp(start, dur, f0, f1, gain, pan float64) {
+	n := len(s.l)
+	i0 := int(start * sampleRate)
+	phase := 0.0
+	for k := 0; k < int(dur*sampleRate); k++ {
+		u := float64(k) / (dur * sampleRate)
+		phase += (f0 + (f1-f0)*u) / sampleRate
+		v := math.Sin(2*math.Pi*phase) * math.Sin(math.Pi*u) * gain
+		s.l[(i0+k)%n] += float32(v * (1 - pan))
+		s.r[(i0+k)%n] += float32(v * (1 + pan))
+	}
+}
+
+// SetAmbience chooses the ambient loops to fade in; the rest fade out
+func (a *Audio) SetAmbience(list []Ambience) {
+	a.wantAmbient = [ambientCount]bool{}
+	for _, am := range list {
+		a.wantAmbient[am] = true
+	}
+}
+
+// ambientPlayer returns the looping player for am, or nil while it is being synthesised
+func (a *Audio) ambientPlayer(am Ambience) *audio.Player {
+	if a.ambient[am] != nil {
+		return a.ambient[am]
+	}
+	a.mu.Lock()
+	pcm := a.ambientPCM[am]
+	a.mu.Unlock()
+	if pcm == nil {
+		return nil
+	}
+	p, err := a.ctx.NewPlayer(audio.NewInfiniteLoop(bytes.NewReader(pcm), int64(len(pcm))))
+	if err != nil {
+		return nil
+	}
+	p.SetVolume(0)
+	a.ambient[am] = p
+	return p
+}
+
+// updateAmbience fades the ambient loops toward what the map wants, on the
+// sound effects volume; this channel is mixed separately from the music
+func (a *Audio) updateAmbience(step float64) {
+	for am := range ambientCount {
+		if a.wantAmbient[am] {
+			if p := a.ambientPlayer(am); p != nil && !p.IsPlaying() {
+				p.Play()
+			}
+		}
+		p := a.ambient[am]
+		if p == nil {
+			continue
+		}
+		if a.wantAmbient[am] {
+			a.ambientFade[am] = min(1, a.ambientFade[am]+step)
+		} else {
+			a.ambientFade[am] = max(0, a.ambientFade[am]-step)
+		}
+		p.SetVolume(a.ambientFade[am] * ambientVolume * a.mix.SFX)
+		if a.ambientFade[am] == 0 && p.IsPlaying() {
+			p.Pause()
+		}
+	}
+}
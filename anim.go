@@ -0,0 +1,72 @@
+package datagame
+
+// Anim is a clip of sprite-sheet frames (columns of one sprite row) played at a fixed rate
+type Anim struct {
+	Frames  []int
+	FrameMS float64
+	Loop    bool // otherwise the last frame holds once the clip ends
+}
+
+var (
+	animEnemyWalk = &Anim{Frames: []int{0, 1, 2, 3}, FrameMS: 120, Loop: true}
+	animBossWalk  = &Anim{Frames: []int{0, 1, 2, 3}, FrameMS: 200, Loop: true}
+	// turrets: normal and flame kick back when they fire, slow towers charge up before a pulse
+	animRecoil    = &Anim{Frames: []int{1, 2, 3, 0}, FrameMS: 50}
+	animFlameKick = &Anim{Frames: []int{1, 2, 3, 0}, FrameMS: 40}
+	animCharge    = &Anim{Frames: []int{0, 1, 2, 3}, FrameMS: 120}
+)
+
+// slowChargeMS is how long before its next pulse a slow tower starts charging
+const slowChargeMS = 480.0
+
+// AnimState is one entity's playback position; the zero value shows frame 0
+type AnimState struct {
+	Clip *Anim
+	T    float64 // ms since the clip started
+}
+
+// Play restarts the state on clip
+func (a *AnimState) Play(clip *Anim) {
+	a.Clip = clip
+	a.T = 0
+}
+
+// Update advances playback by dt ms (game time)
+func (a *AnimState) Update(dt float64) {
+	if a.Clip != nil {
+		a.T += dt
+	}
+}
+
+// Frame is the sheet column to draw
+func (a AnimState) Frame() int {
+	if a.Clip == nil {
+		return 0
+	}
+	i := int(a.T / a.Clip.FrameMS)
+	if a.Clip.Loop {
+		i %= len(a.Clip.Frames)
+	} else {
+		i = min(i, len(a.Clip.Frames)-1)
+	}
+	return a.Clip.Frames[i]
+}
+
+// fireAnim is the clip a tower plays when it shoots
+func fireAnim(typ string) *Anim {
+	switch towerKind(typ) {
+	case "flame":
+		return animFlameKick
+	case "slow":
+		return nil // back to the discharged frame
+	}
+	return animRecoil
+}
+
+// updateTowerAnim advances a tower's animation and starts a slow tower's charge-up
+func (tw *Tower) updateTowerAnim(dt float64) {
+	tw.Anim.Update(dt)
+	if towerKind(tw.Type) == "slow" && tw.Cd <= slowChargeMS && tw.Anim.Clip != animCharge {
+		tw.Anim.Play(animCharge)
+	}
+}
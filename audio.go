@@ -0,0 +1,236 @@
+package datagame
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+const sampleRate = 44100
+
+// MusicTrack is one of the background music loops
+type MusicTrack int
+
+const (
+	MusicMenu  MusicTrack = iota // overlays, drills and the end screen
+	MusicWave                    // normal waves
+	MusicBoss                    // while a boss is on the map
+	MusicPause                   // the pause between levels
+	musicCount
+)
+
+// musicFadeMS is how long a crossfade between tracks takes
+const musicFadeMS = 1500.0
+
+// musicVolume is the loudness of the music at full fade and full volume settings
+const musicVolume = 0.5
+
+// Mix is the player's volume settings as gains, 0-1, master volume included
+type Mix struct {
+	Music, SFX float64
+}
+
+// Audio owns the audio context and the music players. There is one for the
+// whole process (ebiten allows a single audio context), so it outlives the
+// Game values that restarts create.
+type Audio struct {
+	ctx     *audio.Context
+	music   [musicCount]*audio.Player // created once the track is synthesised
+	fade    [musicCount]float64       // current gain of each track, 0-1
+	current MusicTrack
+	mix     Mix
+
+	// map ambience, its own channel under the music
+	ambient     [ambientCount]*audio.Player
+	ambientFade [ambientCount]float64
+	wantAmbient [ambientCount]bool
+	// while a stinger plays the music is ducked under it (ms left)
+	duckMS float64
+
+	// synthesising all the music takes about a second, so it happens in the
+	// background; tracks start as they become ready
+	mu         sync.Mutex
+	pcm        [musicCount][]byte
+	ambientPCM [ambientCount][]byte
+
+	// sound effects are short, so they are synthesised on first use
+	sfx [sfxCount][]byte
+
+	// the question callout playing, stopped when the next one starts
+	voice *audio.Player
+}
+
+// sound is the process-wide audio manager, set up in main
+var sound *Audio
+
+// musicOrder renders the tracks needed soonest first
+var musicOrder = []MusicTrack{MusicWave, MusicPause, MusicMenu, MusicBoss}
+
+func newAudio() *Audio {
+	a := &Audio{ctx: audio.NewContext(sampleRate)}
+	go func() {
+		for _, t := range musicOrder {
+			pcm := songs[t].render()
+			a.mu.Lock()
+			a.pcm[t] = pcm
+			a.mu.Unlock()
+		}
+	}()
+	go func() {
+		for am := range ambientCount {
+			pcm := am.render()
+			a.mu.Lock()
+			a.ambientPCM[am] = pcm
+			a.mu.Unlock()
+		}
+	}()
+	return a
+}
+
+// SetMix applies the player's volume settings, from the next Update
+func (a *Audio) SetMix(m Mix) {
+	a.mix = m
+}
+
+// PlaySFX plays a sound effect at the sound effects volume, centred
+func (a *Audio) PlaySFX(s Sfx) {
+	a.PlaySFXAt(s, 0)
+}
+
+// PlaySFXAt plays a sound effect panned from -1 (left) to 1 (right); see Game.panAt
+func (a *Audio) PlaySFXAt(s Sfx, pan float64) {
+	if a.mix.SFX == 0 {
+		return
+	}
+	if a.sfx[s] == nil {
+		a.sfx[s] = s.render()
+	}
+	var p *audio.Player
+	if pan == 0 {
+		p = a.ctx.NewPlayerFromBytes(a.sfx[s])
+	} else {
+		var err error
+		p, err = a.ctx.NewPlayer(newPanReader(bytes.NewReader(a.sfx[s]), pan))
+		if err != nil {
+			return
+		}
+	}
+	p.SetVolume(a.mix.SFX)
+	p.Play()
+}
+
+// PlayVoice plays a recorded callout at the sound effects volume, cutting
+// off the previous one
+func (a *Audio) PlayVoice(pcm []byte) {
+	if a.voice != nil {
+		a.voice.Close()
+		a.voice = nil
+	}
+	if a.mix.SFX == 0 {
+		return
+	}
+	a.voice = a.ctx.NewPlayerFromBytes(pcm)
+	a.voice.SetVolume(a.mix.SFX)
+	a.voice.Play()
+}
+
+// stinger ducking: how long and how far the music drops under a stinger
+const (
+	stingerDuckMS = 2200.0
+	duckGain      = 0.3
+)
+
+// PlayStinger plays a musical sting over the music, ducking the music under it
+func (a *Audio) PlayStinger(s Sfx) {
+	a.duckMS = stingerDuckMS
+	a.PlaySFX(s)
+}
+
+// PlayMusic makes t the track to fade in; the others fade out
+func (a *Audio) PlayMusic(t MusicTrack) {
+	a.current = t
+}
+
+// player returns the looping player for t, or nil while it is still being
+// synthesised (or if there is no audio device, in which case the game plays on
+// in silence)
+func (a *Audio) player(t MusicTrack) *audio.Player {
+	if a.music[t] != nil {
+		return a.music[t]
+	}
+	a.mu.Lock()
+	pcm := a.pcm[t]
+	a.mu.Unlock()
+	if pcm == nil {
+		return nil
+	}
+	loop := audio.NewInfiniteLoop(bytes.NewReader(pcm), int64(len(pcm)))
+	p, err := a.ctx.NewPlayer(loop)
+	if err != nil {
+		return nil
+	}
+	p.SetVolume(0)
+	a.music[t] = p
+	return p
+}
+
+// Update advances the crossfade by dt ms of real time. Silent tracks are
+// paused, so coming back to one resumes it where it left off.
+func (a *Audio) Update(dt float64) {
+	step := dt / musicFadeMS
+	duck := 1.0
+	if a.duckMS > 0 {
+		a.duckMS -= dt
+		duck = duckGain
+	}
+	a.updateAmbience(step)
+	if p := a.player(a.current); p != nil && !p.IsPlaying() {
+		p.Play()
+	}
+	for t, p := range a.music {
+		if p == nil {
+			continue
+		}
+		if MusicTrack(t) == a.current {
+			a.fade[t] = min(1, a.fade[t]+step)
+		} else {
+			a.fade[t] = max(0, a.fade[t]-step)
+		}
+		p.SetVolume(a.fade[t] * musicVolume * a.mix.Music * duck)
+		if a.fade[t] == 0 && p.IsPlaying() {
+			p.Pause()
+		}
+	}
+}
+
+// musicTrack picks the music for what is on screen
+func (g *Game) musicTrack() MusicTrack {
+	switch {
+	case g.gameOver, g.teacherState != teacherClosed, g.drill != nil, g.settingsActive:
+		return MusicMenu
+	case g.interLevelActive:
+		return MusicPause
+	case g.bossOnMap():
+		return MusicBoss
+	}
+	return MusicWave
+}
+
+func (g *Game) bossOnMap() bool {
+	for _, e := range g.enemies {
+		if e.Boss {
+			return true
+		}
+	}
+	return false
+}
+
+// updateAudio applies the volume settings and follows the game's state with
+// the music, every frame
+func (g *Game) updateAudio(dt float64) {
+	sound.SetMix(g.settings.mix())
+	sound.SetAmbience(g.mapAmbience)
+	sound.PlayMusic(g.musicTrack())
+	sound.Update(dt)
+}
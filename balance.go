@@ -0,0 +1,366 @@
+package datagame
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+//go:embed data/*.json
+var dataFS embed.FS
+
+// The game's balance lives in data files rather than code, so it can be tuned
+// without touching Go: data/towers.json has each tower type's starting stats
+// and data/waves.json how enemies and waves scale with the level. Both are
+// built into the game; -dev reloads edits to them while it runs (dev.go), and
+// a modder's copies in the data folder of the game's own folder replace them
+// (loadBalanceMods). Both can carry scripts (script.go): towers an on_hit
+// script, and waves a level_script and a spawn_script.
+
+// TowerStats are a tower type's stats before any upgrades. Types other than
+// the built-in ones are custom towers, which fire and look like their Base.
+type TowerStats struct {
+	Base          string  `json:"base"` // custom towers: "normal", "flame" or "slow"
+	Range         float64 `json:"range"`
+	Damage        float64 `json:"damage"`
+	Fire          float64 `json:"fire"`           // ms between shots or pulses
+	FlameDuration float64 `json:"flame_duration"` // flame: ms a hit keeps burning
+	PulseDuration float64 `json:"pulse_duration"` // slow: ms a pulse slows for
+	OnHit         string  `json:"on_hit"`         // script run as each shot lands; see towerHitAPI
+	Tech          string  `json:"tech"`           // the tech tree node that unlocks it, "" for none
+	onHit         *script
+}
+
+// WaveTuning is how enemies and waves scale with the level
+type WaveTuning struct {
+	// enemy HP: a base drawn from [EnemyHPMin, EnemyHPMax], scaled by
+	// 1 + (level-1)*EnemyHPPerLevel
+	EnemyHPMin         float64 `json:"enemy_hp_min"`
+	EnemyHPMax         float64 `json:"enemy_hp_max"`
+	EnemyHPPerLevel    float64 `json:"enemy_hp_per_level"`
+	EnemyArmorPerLevel float64 `json:"enemy_armor_per_level"`
+	// speed (px/sec): the base, plus up to EnemySpeedRand, plus EnemySpeedPerLevel a level
+	EnemySpeedBase     float64 `json:"enemy_speed_base"`
+	EnemySpeedRand     float64 `json:"enemy_speed_rand"`
+	EnemySpeedPerLevel float64 `json:"enemy_speed_per_level"`
+	// enemies spawned a level, and kills needed to clear it (inclusive ranges)
+	EnemiesPerLevelMin int `json:"enemies_per_level_min"`
+	EnemiesPerLevelMax int `json:"enemies_per_level_max"`
+	KillsToAdvanceMin  int `json:"kills_to_advance_min"`
+	KillsToAdvanceMax  int `json:"kills_to_advance_max"`
+	// spawn interval (ms) at level 1, how much it shrinks each level, and its floor
+	SpawnIntervalBase  float64 `json:"spawn_interval_base"`
+	SpawnIntervalDecay float64 `json:"spawn_interval_decay"`
+	SpawnIntervalMin   float64 `json:"spawn_interval_min"`
+	InterLevelPauseMS  float64 `json:"inter_level_pause_ms"`
+	// gold for a kill: the base, plus BountyPerLevel a level, times
+	// BossBounty for a boss. A horde's rank and file are worth hordeGold.
+	BountyBase     float64 `json:"bounty_base"`
+	BountyPerLevel float64 `json:"bounty_per_level"`
+	BossBounty     float64 `json:"boss_bounty"`
+	// interest on banked gold as each level starts: InterestPercent of it, at
+	// most InterestCap
+	InterestPercent float64 `json:"interest_percent"`
+	InterestCap     int     `json:"interest_cap"`
+	// scripts run as each level starts and as each enemy spawns; see
+	// levelScriptAPI and spawnScriptAPI
+	LevelScript string `json:"level_script"`
+	SpawnScript string `json:"spawn_script"`
+	levelScript *script
+	spawnScript *script
+}
+
+var (
+	towerStats = mustParse(parseTowerStats, "data/towers.json")
+	waveTuning = mustParse(parseWaveTuning, "data/waves.json")
+)
+
+// setTowerStats switches to new tower stats, and the build list to their types
+func setTowerStats(stats map[string]TowerStats) {
+	towerStats = stats
+	towerTypes = towerTypeList(stats)
+}
+
+// towerTypeList is the built-in towers, then any custom ones by name, less
+// those the run's tech tree hasn't unlocked
+func towerTypeList(stats map[string]TowerStats) []string {
+	types := slices.Clone(builtinTowers)
+	var custom []string
+	for typ, s := range stats {
+		if !slices.Contains(builtinTowers, typ) && runTech.has(s.Tech) {
+			custom = append(custom, typ)
+		}
+	}
+	slices.Sort(custom)
+	return append(types, custom...)
+}
+
+// towerKind is the built-in tower a type fires and looks like: itself, or a
+// custom tower's base
+func towerKind(typ string) string {
+	if s := towerStats[typ]; s.Base != "" {
+		return s.Base
+	}
+	return typ
+}
+
+// loadBalanceMods takes towers.json and waves.json from the data folder in
+// the game's own folder, where a modder has put them, in place of the
+// built-in ones
+func loadBalanceMods() error {
+	if data, err := storage.ReadFile("data/towers.json"); err == nil {
+		stats, err := parseTowerStats(data)
+		if err != nil {
+			return fmt.Errorf("data/towers.json: %w", err)
+		}
+		setTowerStats(stats)
+	}
+	if data, err := storage.ReadFile("data/waves.json"); err == nil {
+		w, err := parseWaveTuning(data)
+		if err != nil {
+			return fmt.Errorf("data/waves.json: %w", err)
+		}
+		waveTuning = w
+	}
+	return nil
+}
+
+// readData reads one of the built-in data files, or under -dev the last good
+// version loaded from disk
+func readData(fsys embed.FS, name string) ([]byte, error) {
+	if data, ok := devData[name]; ok {
+		return data, nil
+	}
+	return fsys.ReadFile(name)
+}
+
+// mustParse loads a built-in data file, which is known to be valid
+func mustParse[T any](parse func([]byte) (T, error), name string) T {
+	data, err := dataFS.ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+	v, err := parse(data)
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", name, err))
+	}
+	return v
+}
+
+// parseTowerStats reads towers.json, which must give every built-in type,
+// and compiles the on_hit scripts
+func parseTowerStats(data []byte) (map[string]TowerStats, error) {
+	var m map[string]TowerStats
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for _, typ := range builtinTowers {
+		if _, ok := m[typ]; !ok {
+			return nil, fmt.Errorf("no stats for the %s tower", typ)
+		}
+	}
+	for typ, s := range m {
+		builtin := slices.Contains(builtinTowers, typ)
+		switch {
+		case builtin && s.Base != "" && s.Base != typ:
+			return nil, fmt.Errorf("the %s tower is built in, so it can't have a base", typ)
+		case !builtin && !slices.Contains(builtinTowers, s.Base):
+			return nil, fmt.Errorf("the %s tower needs a base: normal, flame or slow", typ)
+		case s.Range <= 0 || s.Fire <= 0:
+			return nil, fmt.Errorf("the %s tower needs a range and fire above 0", typ)
+		case builtin && s.Tech != "":
+			return nil, fmt.Errorf("the %s tower is built in, so it can't need tech", typ)
+		case s.Tech != "" && techNode(s.Tech) == nil:
+			return nil, fmt.Errorf("the %s tower's tech %q isn't in the tech tree", typ, s.Tech)
+		}
+		var err error
+		if s.onHit, err = compileScript(s.OnHit, towerHitAPI); err != nil {
+			return nil, fmt.Errorf("the %s tower's on_hit: %w", typ, err)
+		}
+		m[typ] = s
+	}
+	return m, nil
+}
+
+// waveDefaults fills in keys a waves.json leaves out, so files written
+// before a key was added keep loading as they did
+var waveDefaults = WaveTuning{BountyBase: 20, BountyPerLevel: 4, BossBounty: 10, InterestPercent: 5, InterestCap: 100}
+
+// parseWaveTuning reads waves.json
+func parseWaveTuning(data []byte) (WaveTuning, error) {
+	w := waveDefaults
+	if err := json.Unmarshal(data, &w); err != nil {
+		return w, err
+	}
+	switch {
+	case w.EnemyHPMin <= 0 || w.EnemyHPMax < w.EnemyHPMin:
+		return w, errors.New("enemy HP needs 0 < min <= max")
+	case w.EnemiesPerLevelMin <= 0 || w.EnemiesPerLevelMax < w.EnemiesPerLevelMin:
+		return w, errors.New("enemies per level needs 0 < min <= max")
+	case w.KillsToAdvanceMin <= 0 || w.KillsToAdvanceMax < w.KillsToAdvanceMin:
+		return w, errors.New("kills to advance needs 0 < min <= max")
+	case w.SpawnIntervalMin <= 0 || w.SpawnIntervalBase < w.SpawnIntervalMin:
+		return w, errors.New("spawn interval needs 0 < min <= base")
+	case w.BountyBase <= 0 || w.BountyPerLevel < 0 || w.BossBounty < 1:
+		return w, errors.New("bounties need bounty_base > 0, bounty_per_level >= 0 and boss_bounty >= 1")
+	case w.InterestPercent < 0 || w.InterestCap < 0:
+		return w, errors.New("interest needs interest_percent >= 0 and interest_cap >= 0")
+	}
+	var err error
+	if w.levelScript, err = compileScript(w.LevelScript, levelScriptAPI); err != nil {
+		return w, fmt.Errorf("level_script: %w", err)
+	}
+	if w.spawnScript, err = compileScript(w.SpawnScript, spawnScriptAPI); err != nil {
+		return w, fmt.Errorf("spawn_script: %w", err)
+	}
+	return w, nil
+}
+
+// enemiesPerLevel rolls how many enemies a level spawns
+func (g *Game) enemiesPerLevel() int {
+	w := &waveTuning
+	return w.EnemiesPerLevelMin + g.rand.Intn(w.EnemiesPerLevelMax-w.EnemiesPerLevelMin+1)
+}
+
+// bounty is the gold an enemy spawning now is worth
+func (g *Game) bounty(boss bool) int {
+	w := &waveTuning
+	gold := w.BountyBase + float64(g.level-1)*w.BountyPerLevel
+	switch {
+	case boss:
+		gold *= w.BossBounty
+	case g.hordeLevel:
+		return hordeGold
+	}
+	return int(math.Round(gold))
+}
+
+// interestOn is the interest banking gold through a level earns
+func interestOn(gold int) int {
+	w := &waveTuning
+	return min(int(float64(max(0, gold))*w.InterestPercent/100), w.InterestCap)
+}
+
+// payInterest pays each player interest on the gold they saved through the
+// last level, and returns player 1's
+func (g *Game) payInterest() int {
+	if g.coop != nil {
+		g.coop.gold += interestOn(g.coop.gold)
+	}
+	n := interestOn(g.playerGold)
+	g.playerGold += n
+	return n
+}
+
+// killsToAdvance rolls how many kills clear a level
+func (g *Game) killsToAdvance() int {
+	w := &waveTuning
+	return w.KillsToAdvanceMin + g.rand.Intn(w.KillsToAdvanceMax-w.KillsToAdvanceMin+1)
+}
+
+// towerHit is what an on_hit script acts on: one of tw's shots landing on target
+type towerHit struct {
+	g      *Game
+	tw     *Tower
+	target *Enemy
+}
+
+// towerHitAPI is what a tower's on_hit script sees. It runs as each of the
+// tower's shots lands on a live enemy, before the damage is dealt.
+//
+//	damage (can be changed): what the shot deals, before armor
+//	level, upgrades, kills: the game level, and the tower's upgrades and kills
+//	hp, max_hp, armor, speed, boss, burning, slowed: the enemy hit
+//	burn(ms): set the enemy on fire, as a flame tower does
+//	slow(factor, ms): slow it to factor (0.1 to 1) of its speed
+//	splash(radius, damage): damage everything within radius of it
+//	gold(n): earn n gold, at most 100 a hit
+var towerHitAPI = &scriptAPI{
+	vars: []string{"damage", "level", "upgrades", "kills", "hp", "max_hp", "armor", "speed", "boss", "burning", "slowed"},
+	sets: []string{"damage"},
+	funcs: map[string]scriptFunc{
+		"burn": {1, func(r *scriptRun, a []float64) float64 {
+			h := r.ctx.(*towerHit)
+			h.target.BurnTime = math.Max(h.target.BurnTime, a[0])
+			h.target.BurnLevel, h.target.BurnSrc = h.g.level, h.tw
+			return 0
+		}},
+		"slow": {2, func(r *scriptRun, a []float64) float64 {
+			h := r.ctx.(*towerHit)
+			h.target.SlowTime = math.Max(h.target.SlowTime, a[1])
+			h.target.SlowFactor = math.Max(0.1, math.Min(1, a[0]))
+			return 0
+		}},
+		"splash": {2, func(r *scriptRun, a []float64) float64 {
+			h := r.ctx.(*towerHit)
+			h.g.applyDamageAt(h.target.Pos.X, h.target.Pos.Y, math.Max(0, a[1]), 0, math.Max(1, math.Min(200, a[0])), h.tw)
+			return 0
+		}},
+		"gold": {1, func(r *scriptRun, a []float64) float64 {
+			h := r.ctx.(*towerHit)
+			n := int(math.Max(0, math.Min(100, a[0])))
+			h.g.earn(h.tw, n)
+			h.g.wave.Gold += n
+			return 0
+		}},
+	},
+}
+
+// levelScriptAPI is what waves.json's level_script sees. It runs as each
+// level starts, after the level's rolls.
+//
+//	level: the level starting
+//	horde, boss: 1 on a horde level, or on a level with a boss
+//	enemies, kills (can be changed): how many enemies the level spawns, and
+//	the kills that clear it
+//	spawn_interval (can be changed): ms between spawns; later levels go on
+//	shrinking it from the new value
+var levelScriptAPI = &scriptAPI{
+	vars: []string{"level", "horde", "boss", "enemies", "kills", "spawn_interval"},
+	sets: []string{"enemies", "kills", "spawn_interval"},
+}
+
+// spawnScriptAPI is what waves.json's spawn_script sees. It runs as each
+// ordinary enemy (not a boss) spawns.
+//
+//	level, n: the level, and how many enemies it spawned before this one
+//	hp, armor, speed (can be changed): the enemy's stats, scaled for the level
+//	bounty (can be changed): the gold it is worth, from the level's bounty
+var spawnScriptAPI = &scriptAPI{
+	vars: []string{"level", "n", "hp", "armor", "speed", "bounty"},
+	sets: []string{"hp", "armor", "speed", "bounty"},
+}
+
+// onHit runs the firing tower's on_hit script as a shot lands, returning the
+// damage the shot deals
+func (g *Game) onHit(b *Bullet) float64 {
+	tw, e := b.Src, b.Target
+	if tw == nil || e == nil {
+		return b.Damage
+	}
+	s := towerStats[tw.Type].onHit
+	if s == nil {
+		return b.Damage
+	}
+	vars := []float64{b.Damage, float64(g.level), float64(tw.Upgrades), float64(tw.Kills),
+		e.HP, e.MaxHP, e.Armor, e.Speed, truth(e.Boss), truth(e.BurnTime > 0), truth(e.SlowTime > 0)}
+	s.run(&towerHit{g, tw, e}, g.rand, vars)
+	return math.Max(0, vars[0])
+}
+
+// runLevelScript runs waves.json's level_script for the level starting
+func (g *Game) runLevelScript() {
+	s := waveTuning.levelScript
+	if s == nil {
+		return
+	}
+	vars := []float64{float64(g.level), truth(g.hordeLevel), truth(g.isBossLevel()),
+		float64(g.enemiesToSpawn), float64(g.nextLevelThreshold), g.spawnInt}
+	s.run(nil, g.rand, vars)
+	g.enemiesToSpawn = max(1, int(vars[3]))
+	g.nextLevelThreshold = max(1, int(vars[4]))
+	g.spawnInt = math.Max(50, vars[5])
+}
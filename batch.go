@@ -0,0 +1,60 @@
+package datagame
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// batchMaxVertices keeps vertex indices within uint16; a fuller batch is
+// drawn and started again
+const batchMaxVertices = 1<<16 - 4
+
+// spriteBatch collects tinted rectangles cut from one source image and draws
+// them with a single DrawTriangles call, for scenes with too many sprites to
+// draw one by one. Its buffers are reused between frames.
+type spriteBatch struct {
+	dst, src *ebiten.Image
+	vs       []ebiten.Vertex
+	is       []uint16
+}
+
+// batchOp takes vertex colours as premultiplied, like the palette's
+var batchOp = ebiten.DrawTrianglesOptions{Filter: ebiten.FilterLinear, ColorScaleMode: ebiten.ColorScaleModePremultipliedAlpha}
+
+// begin starts a batch drawing from src onto dst
+func (b *spriteBatch) begin(dst, src *ebiten.Image) {
+	b.dst, b.src = dst, src
+	b.vs, b.is = b.vs[:0], b.is[:0]
+}
+
+// add queues the src rectangle r drawn over x,y,w,h (map px) tinted with col
+func (b *spriteBatch) add(r image.Rectangle, x, y, w, h float64, col color.RGBA) {
+	if w <= 0 || h <= 0 {
+		return
+	}
+	if len(b.vs) >= batchMaxVertices {
+		b.flush()
+	}
+	x0, y0 := float32(x*pixelScale), float32(y*pixelScale)
+	x1, y1 := float32((x+w)*pixelScale), float32((y+h)*pixelScale)
+	sx0, sy0, sx1, sy1 := float32(r.Min.X), float32(r.Min.Y), float32(r.Max.X), float32(r.Max.Y)
+	cr, cg, cb, ca := float32(col.R)/0xFF, float32(col.G)/0xFF, float32(col.B)/0xFF, float32(col.A)/0xFF
+	n := uint16(len(b.vs))
+	b.vs = append(b.vs,
+		ebiten.Vertex{DstX: x0, DstY: y0, SrcX: sx0, SrcY: sy0, ColorR: cr, ColorG: cg, ColorB: cb, ColorA: ca},
+		ebiten.Vertex{DstX: x1, DstY: y0, SrcX: sx1, SrcY: sy0, ColorR: cr, ColorG: cg, ColorB: cb, ColorA: ca},
+		ebiten.Vertex{DstX: x0, DstY: y1, SrcX: sx0, SrcY: sy1, ColorR: cr, ColorG: cg, ColorB: cb, ColorA: ca},
+		ebiten.Vertex{DstX: x1, DstY: y1, SrcX: sx1, SrcY: sy1, ColorR: cr, ColorG: cg, ColorB: cb, ColorA: ca},
+	)
+	b.is = append(b.is, n, n+1, n+2, n+1, n+3, n+2)
+}
+
+// flush draws everything queued so far
+func (b *spriteBatch) flush() {
+	if len(b.is) > 0 {
+		b.dst.DrawTriangles(b.vs, b.is, b.src, &batchOp)
+	}
+	b.vs, b.is = b.vs[:0], b.is[:0]
+}
@@ -0,0 +1,48 @@
+package datagame
+
+// --- boss waves ---
+const (
+	// every BossEveryLevels-th level opens with a boss
+	BossEveryLevels = 5
+	// boss HP as a multiple of a regular enemy's maximum HP at the same level
+	BossHPMultiplier = 12.0
+	// boss speed (px/sec) is fixed and slow so players have time to answer
+	BossSpeed = 12.0
+	// fraction of the boss's max shield removed by each correct answer
+	BossShieldPerAnswer = 0.25
+	// escape damage multiplier for a boss reaching the exit
+	BossEscapeMultiplier = 5.0
+)
+
+func (g *Game) isBossLevel() bool { return g.level%BossEveryLevels == 0 }
+
+// spawnBoss adds a shielded boss. While its shield holds the boss ignores all
+// tower and burn damage; only correct answers wear the shield down.
+func (g *Game) spawnBoss() {
+	w := &waveTuning
+	hp := w.EnemyHPMax * (1.0 + float64(g.level-1)*w.EnemyHPPerLevel) * BossHPMultiplier
+	armor := float64(g.level) * w.EnemyArmorPerLevel * 2
+	e := g.newEnemy(Enemy{HP: hp, MaxHP: hp, Armor: armor, Speed: BossSpeed, Boss: true, Shield: 1, MaxShield: 1, Bounty: g.bounty(true)})
+	e.Anim.Play(animBossWalk)
+	g.enemies = append(g.enemies, e)
+	sound.PlayStinger(SfxBoss)
+	g.levelMsg = trf("BOSS! Its shield only breaks with correct answers - press %s!", g.settings.Keys.Challenge)
+	g.levelMsgTimer = 5000
+}
+
+// stripBossShields is called for each correct answer while bosses are alive
+func (g *Game) stripBossShields() {
+	for _, e := range g.enemies {
+		if !e.Boss || e.Shield <= 0 {
+			continue
+		}
+		e.Shield -= e.MaxShield * BossShieldPerAnswer
+		if e.Shield <= 1e-9 {
+			e.Shield = 0
+			g.levelMsg = tr("Boss shield shattered! Towers can hurt it now")
+		} else {
+			g.levelMsg = trf("Boss shield down to %.0f%%", e.Shield/e.MaxShield*100)
+		}
+		g.levelMsgTimer = 3000
+	}
+}
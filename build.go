@@ -0,0 +1,89 @@
+package datagame
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// builtinTowers are the tower types the game has without mods
+var builtinTowers = []string{"normal", "flame", "slow"}
+
+// towerTypes are the towers a correct answer can build, in scroll-wheel order
+var towerTypes = towerTypeList(towerStats)
+
+// newTower returns a fresh tower of the given type at p, with its stats from
+// data/towers.json; unknown types are normal towers
+func newTower(typ string, p Vec) *Tower {
+	s, ok := towerStats[typ]
+	if !ok {
+		typ, s = "normal", towerStats["normal"]
+	}
+	return &Tower{X: p.X, Y: p.Y, Range: s.Range, Damage: s.Damage, Fire: s.Fire, Type: typ,
+		FlameDuration: s.FlameDuration, PulseDuration: s.PulseDuration}
+}
+
+// building reports whether the game is in build mode: a placement point is
+// set and no tower is selected, so a correct answer builds there
+func (g *Game) building() bool {
+	return g.selected < 0 && g.lastClick != (Vec{}) && g.drag == nil && g.photo == nil
+}
+
+// overBuildGhost reports whether a view position is over the ghost tower at
+// the placement point, where the wheel picks the tower type instead of zooming
+func (g *Game) overBuildGhost(x, y float64) bool {
+	if !g.building() || g.challengeActive || g.historyActive || g.overUI(x, y) {
+		return false
+	}
+	wx, wy := g.toWorld(x, y)
+	return math.Hypot(wx-g.lastClick.X, wy-g.lastClick.Y) < 2*towerRadius
+}
+
+// cycleBuildType steps through towerTypes with the wheel over the build ghost
+func (g *Game) cycleBuildType() {
+	if !g.overBuildGhost(cursor()) {
+		return
+	}
+	n := len(towerTypes)
+	if _, wy := ebiten.Wheel(); wy > 0 {
+		g.buildType = (g.buildType + n - 1) % n
+	} else if wy < 0 {
+		g.buildType = (g.buildType + 1) % n
+	}
+}
+
+// drawBuildGhost shows the tower a correct answer will build at the placement point
+func (g *Game) drawBuildGhost(screen *ebiten.Image) {
+	if !g.building() {
+		return
+	}
+	tw := newTower(towerTypes[g.buildType], g.lastClick)
+	col := pal.Good
+	if !g.canPlaceTower(g.lastClick, -1) {
+		col = pal.Bad
+	}
+	drawTowerGhost(screen, tw, tw.X, tw.Y, col)
+}
+
+// drawBuildCursor names the tower type next to the cursor while it is over the build ghost
+func (g *Game) drawBuildCursor(screen *ebiten.Image) {
+	x, y := cursor()
+	if !g.overBuildGhost(x, y) {
+		return
+	}
+	label := trf("Build: %s (scroll to change)", tr(towerTypes[g.buildType]))
+	w := textWidth(label) + 12
+	rect(screen, x+14, y+10, w, 20, fade(pal.TooltipFill, 0xE0))
+	drawText(screen, label, int(x)+20, int(y)+25, pal.Text)
+}
+
+// drawTowerGhost draws a see-through tower with its range at x,y in col, for
+// placing and moving towers
+func drawTowerGhost(screen *ebiten.Image, tw *Tower, x, y float64, col color.RGBA) {
+	disc(screen, x, y, tw.Range, fade(col, 0x20))
+	ring(screen, x, y, tw.Range, 2, fade(col, 0x60))
+	drawSprite(screen, SpriteTowerBase, 0, x, y, 0, towerRadius, fade(col, 0xA0))
+	turret, tint := turretArt(tw.Type)
+	drawSprite(screen, turret, 0, x, y, tw.Angle, towerRadius, fade(tint, 0xA0))
+}
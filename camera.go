@@ -0,0 +1,139 @@
+package datagame
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Camera limits and pan speed
+const (
+	CameraMaxZoom  = 3.0
+	CameraZoomStep = 1.1 // per wheel notch
+	CameraPanSpeed = 500.0
+)
+
+// Camera maps the map (world) onto the view: X, Y is the world point shown at
+// the centre of the view and Zoom is view pixels per world pixel. All
+// world<->screen conversions go through it.
+type Camera struct {
+	X, Y float64
+	Zoom float64
+	// middle-drag state
+	dragging     bool
+	dragX, dragY float64
+	// the finger on the screen went down on open map, so dragging it pans
+	touchPan bool
+}
+
+func newCamera() Camera {
+	return Camera{X: MapW / 2, Y: MapH / 2, Zoom: 1}
+}
+
+// GeoM is the world-to-view transform for the current view size
+func (c *Camera) GeoM(viewW, viewH int) ebiten.GeoM {
+	var m ebiten.GeoM
+	m.Translate(-c.X, -c.Y)
+	m.Scale(c.Zoom, c.Zoom)
+	m.Translate(math.Floor(float64(viewW)/2), math.Floor(float64(viewH)/2))
+	return m
+}
+
+// ToWorld converts a view position to world coordinates
+func (c *Camera) ToWorld(x, y float64, viewW, viewH int) (float64, float64) {
+	m := c.GeoM(viewW, viewH)
+	m.Invert()
+	return m.Apply(x, y)
+}
+
+// ToScreen converts a world position to view coordinates
+func (c *Camera) ToScreen(x, y float64, viewW, viewH int) (float64, float64) {
+	m := c.GeoM(viewW, viewH)
+	return m.Apply(x, y)
+}
+
+// Visible is the world area shown in the view
+func (c *Camera) Visible(viewW, viewH int) Rect {
+	x0, y0 := c.ToWorld(0, 0, viewW, viewH)
+	x1, y1 := c.ToWorld(float64(viewW), float64(viewH), viewW, viewH)
+	return Rect{x0, y0, x1 - x0, y1 - y0}
+}
+
+// minZoom lets the whole map fit in the view, but never magnifies
+func minZoom(viewW, viewH int) float64 {
+	return math.Min(1, math.Min(float64(viewW)/MapW, float64(viewH)/MapH))
+}
+
+// clamp keeps the zoom in range and the map on screen; an axis where the
+// whole map fits is centred instead
+func (c *Camera) clamp(viewW, viewH int) {
+	c.Zoom = math.Max(minZoom(viewW, viewH), math.Min(CameraMaxZoom, c.Zoom))
+	clampAxis := func(pos *float64, view, size float64) {
+		half := view / 2 / c.Zoom
+		if half*2 >= size {
+			*pos = size / 2
+			return
+		}
+		*pos = math.Max(half, math.Min(size-half, *pos))
+	}
+	clampAxis(&c.X, float64(viewW), MapW)
+	clampAxis(&c.Y, float64(viewH), MapH)
+}
+
+// zoomAt scales by f while keeping the world point under (sx, sy) fixed
+func (c *Camera) zoomAt(f, sx, sy float64, viewW, viewH int) {
+	wx, wy := c.ToWorld(sx, sy, viewW, viewH)
+	c.Zoom *= f
+	c.clamp(viewW, viewH)
+	nx, ny := c.ToWorld(sx, sy, viewW, viewH)
+	c.X += wx - nx
+	c.Y += wy - ny
+}
+
+// updateCamera handles wheel zoom, WASD and middle-drag panning, and Home to reset
+func (g *Game) updateCamera(dt float64) {
+	c := &g.camera
+	// the history list and the shop use the wheel to scroll, and the build
+	// ghost uses it to pick a tower type
+	if !g.historyActive && !(g.shopActive && g.shopRect().Contains(cursor())) && !g.overBuildGhost(cursor()) {
+		if _, wy := ebiten.Wheel(); wy != 0 {
+			sx, sy := cursor()
+			c.zoomAt(math.Pow(CameraZoomStep, wy), sx, sy, g.viewW, g.viewH)
+		}
+	}
+	step := CameraPanSpeed * dt / 1000 / c.Zoom
+	if ebiten.IsKeyPressed(ebiten.KeyA) {
+		c.X -= step
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyD) {
+		c.X += step
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyW) {
+		c.Y -= step
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyS) {
+		c.Y += step
+	}
+	x, y := cursor()
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonMiddle) {
+		if c.dragging {
+			c.X -= (x - c.dragX) / c.Zoom
+			c.Y -= (y - c.dragY) / c.Zoom
+		}
+		c.dragging = true
+		c.dragX, c.dragY = x, y
+	} else {
+		c.dragging = false
+	}
+	g.updateTouchCamera()
+	if inpututil.IsKeyJustPressed(ebiten.KeyHome) {
+		*c = newCamera()
+	}
+	c.clamp(g.viewW, g.viewH)
+}
+
+// toWorld converts a view position to map coordinates
+func (g *Game) toWorld(x, y float64) (float64, float64) {
+	return g.camera.ToWorld(x, y, g.viewW, g.viewH)
+}
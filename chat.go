@@ -0,0 +1,318 @@
+package datagame
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Chat and emotes for multiplayer. In a versus match Enter opens a chat line
+// to the opponent; in versus and co-op, holding E (or Y on player 2's gamepad)
+// opens a wheel of quick emotes, picked by moving the mouse (or stick) toward
+// one and letting go. Every message goes through filterChat on the way out and
+// on the way in, so a classroom never sees the words on chatBlocked.
+
+const (
+	chatMaxLen     = 80
+	chatLines      = 5       // messages shown at once
+	chatShowMS     = 10000.0 // how long a message stays up
+	chatRateMS     = 1000.0  // least time between this player's messages
+	chatW          = 360.0
+	emoteRadius    = 70.0 // wheel labels' distance from its centre (view px)
+	emoteDeadZone  = 20.0 // mouse travel (view px) before a slice is picked
+	emoteStickZone = 0.5
+)
+
+// emotes are the quick messages on the wheel, clockwise from the right. They
+// travel by id, so each side shows them in its own language.
+var emotes = []struct{ id, text string }{
+	{"nice", "Nice one!"},
+	{"thanks", "Thanks!"},
+	{"help", "Help!"},
+	{"oops", "Oops!"},
+	{"gl", "Good luck!"},
+	{"gg", "Well played!"},
+}
+
+// emoteText is the emote's text in the current language, "" for an unknown id
+func emoteText(id string) string {
+	for _, e := range emotes {
+		if e.id == id {
+			return tr(e.text)
+		}
+	}
+	return ""
+}
+
+type chatLine struct {
+	from, text string
+	ms         float64 // age
+}
+
+// chatBox is the shown messages, the chat line being typed and player 1's
+// emote wheel
+type chatBox struct {
+	lines  []chatLine
+	typing bool
+	input  []rune
+	sentMS float64 // since this player's last message, for chatRateMS
+	// the open emote wheel's centre (view px); wheel is false when it is closed
+	wheel       bool
+	wheelCenter Vec
+}
+
+// chatOpen reports whether the chat line can be used: only with a versus
+// opponent to read it
+func (g *Game) chatOpen() bool {
+	return g.versus != nil && g.versus.state == versusPlaying
+}
+
+// emotesOpen reports whether the emote wheel can be used: in versus or co-op
+func (g *Game) emotesOpen() bool {
+	return g.chatOpen() || g.coop != nil
+}
+
+// say adds a message to the box, filtered
+func (c *chatBox) say(from, text string) {
+	c.lines = append(c.lines, chatLine{from: from, text: filterChat(text)})
+	if len(c.lines) > chatLines {
+		c.lines = c.lines[len(c.lines)-chatLines:]
+	}
+}
+
+// updateChat ages the messages, opens the chat line on Enter and runs player
+// 1's emote wheel
+func (g *Game) updateChat(dt float64) {
+	c := &g.chat
+	c.sentMS += dt
+	for i := range c.lines {
+		c.lines[i].ms += dt
+	}
+	if g.chatOpen() && !g.challengeActive && inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		c.typing, c.input = true, c.input[:0]
+		return
+	}
+	if !g.emotesOpen() || g.challengeActive {
+		c.wheel = false
+		return
+	}
+	x, y := cursor()
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyE):
+		c.wheel, c.wheelCenter = true, Vec{x, y}
+	case c.wheel && inpututil.IsKeyJustReleased(ebiten.KeyE):
+		c.wheel = false
+		if i := emoteAt(x-c.wheelCenter.X, y-c.wheelCenter.Y, emoteDeadZone); i >= 0 {
+			g.sendEmote(tr("You"), i)
+		}
+	}
+}
+
+// updateChatLine takes the keyboard while a message is typed: Enter sends it,
+// Esc drops it
+func (g *Game) updateChatLine() {
+	c := &g.chat
+	g.inputChars = ebiten.AppendInputChars(g.inputChars[:0])
+	for _, r := range g.inputChars {
+		if len(c.input) < chatMaxLen && unicode.IsPrint(r) {
+			c.input = append(c.input, r)
+		}
+	}
+	if repeatingKey(ebiten.KeyBackspace) && len(c.input) > 0 {
+		c.input = c.input[:len(c.input)-1]
+	}
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		c.typing = false
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyKPEnter):
+		c.typing = false
+		text := strings.TrimSpace(string(c.input))
+		if text == "" || !g.chatOpen() {
+			return
+		}
+		if c.sentMS < chatRateMS {
+			c.say("", tr("Slow down: one message a second"))
+			return
+		}
+		c.sentMS = 0
+		text = filterChat(text)
+		c.say(tr("You"), text)
+		g.versus.send(versusMsg{Type: "chat", Text: text})
+	}
+}
+
+// sendEmote shows emote i from a local player and sends it to the opponent
+func (g *Game) sendEmote(from string, i int) {
+	c := &g.chat
+	if c.sentMS < chatRateMS {
+		return
+	}
+	c.sentMS = 0
+	c.say(from, tr(emotes[i].text))
+	if g.chatOpen() {
+		g.versus.send(versusMsg{Type: "emote", Text: emotes[i].id})
+	}
+}
+
+// emoteAt is the emote in direction (dx, dy) from the wheel's centre, or -1
+// inside the dead zone
+func emoteAt(dx, dy, deadZone float64) int {
+	if math.Hypot(dx, dy) < deadZone {
+		return -1
+	}
+	slice := 2 * math.Pi / float64(len(emotes))
+	a := math.Atan2(dy, dx)
+	return int(math.Round(a/slice)+float64(len(emotes))) % len(emotes)
+}
+
+// updateCoopEmote runs player 2's wheel: hold Y, tilt the left stick toward an
+// emote and let go. It reports whether the wheel has the pad this frame.
+func (g *Game) updateCoopEmote(p *coopPlayer) bool {
+	y := ebiten.StandardGamepadButtonRightTop
+	switch {
+	case inpututil.IsStandardGamepadButtonJustPressed(p.pad, y):
+		p.emoting = true
+	case p.emoting && inpututil.IsStandardGamepadButtonJustReleased(p.pad, y):
+		p.emoting = false
+		if i := p.emotePick(); i >= 0 {
+			g.sendEmote(tr("Player 2"), i)
+		}
+	}
+	return p.emoting
+}
+
+// emotePick is the emote player 2's left stick points at, or -1
+func (p *coopPlayer) emotePick() int {
+	dx := ebiten.StandardGamepadAxisValue(p.pad, ebiten.StandardGamepadAxisLeftStickHorizontal)
+	dy := ebiten.StandardGamepadAxisValue(p.pad, ebiten.StandardGamepadAxisLeftStickVertical)
+	return emoteAt(dx, dy, emoteStickZone)
+}
+
+// chatBlocked are the words filterChat masks, in English, Spanish and French,
+// lower case without accents
+var chatBlocked = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		fuck fucks fucked fucker fuckers fucking motherfucker shit shits shitty bullshit
+		bitch bitches bastard bastards asshole assholes ass arse arsehole dumbass jackass
+		dick dicks cock cocks cunt cunts piss pissed slut sluts whore whores damn crap
+		wanker twat bollocks prick pricks douche retard retarded fag fags faggot nigger nigga
+		mierda puta puto putas putos joder jodete cabron cabrones pendejo pendeja gilipollas
+		culo polla zorra
+		merde putain connard connards connasse salope salopes encule encules chier pute`) {
+		chatBlocked[foldChat(w)] = true
+	}
+}
+
+// chatLeet undoes the usual letter swaps used to sneak words past a filter
+var chatLeet = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s", "!", "i")
+
+// chatAccents folds accented letters to plain ones
+var chatAccents = strings.NewReplacer("á", "a", "à", "a", "â", "a", "ä", "a", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "î", "i", "ï", "i", "ó", "o", "ô", "o", "ö", "o", "ú", "u", "ù", "u", "û", "u", "ü", "u", "ñ", "n", "ç", "c")
+
+// foldChat reduces a word to what chatBlocked is keyed by: lower case,
+// unaccented, leetspeak undone and anything but letters dropped
+func foldChat(w string) string {
+	w = strings.TrimRight(w, ".,?!;:")
+	w = chatLeet.Replace(chatAccents.Replace(strings.ToLower(w)))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, w)
+}
+
+// squeeze collapses runs of a letter, so "fuuuck" reads as "fuck"
+func squeeze(w string) string {
+	var b strings.Builder
+	var last rune
+	for _, r := range w {
+		if r != last {
+			b.WriteRune(r)
+		}
+		last = r
+	}
+	return b.String()
+}
+
+// filterChat masks blocked words with asterisks. Words are matched whole, so
+// innocent words containing one ("class", "Scunthorpe") get through; stretched
+// spellings are caught once squeezed, from four letters up so "as" never reads
+// as "ass".
+func filterChat(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		f := foldChat(w)
+		if sq := squeeze(f); chatBlocked[f] || (len(sq) >= 4 && chatBlocked[sq]) {
+			words[i] = strings.Repeat("*", len([]rune(w)))
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// drawChat shows the recent messages and the chat line above the tower panel,
+// and the open emote wheels
+func (g *Game) drawChat(screen *ebiten.Image) {
+	c := &g.chat
+	shown := 0
+	for _, l := range c.lines {
+		if l.ms < chatShowMS {
+			shown++
+		}
+	}
+	if c.typing {
+		shown++
+	}
+	if shown > 0 {
+		r := g.towerPanelRect()
+		r.H = 12 + float64(shown*hudHintsLineH)
+		r.Y -= r.H + hudMargin
+		r.W = chatW
+		rect(screen, r.X, r.Y, r.W, r.H, fade(pal.Scrim, 0xA0))
+		x, y := int(r.X)+8, int(r.Y)+18
+		for _, l := range c.lines {
+			if l.ms >= chatShowMS {
+				continue
+			}
+			text := l.text
+			if l.from != "" {
+				text = l.from + ": " + text
+			}
+			drawText(screen, text, x, y, pal.Text)
+			y += hudHintsLineH
+		}
+		if c.typing {
+			drawText(screen, tr("Say: ")+string(c.input)+"_", x, y, pal.Warn)
+		}
+	}
+	if c.wheel {
+		x, y := cursor()
+		g.drawEmoteWheel(screen, c.wheelCenter, emoteAt(x-c.wheelCenter.X, y-c.wheelCenter.Y, emoteDeadZone))
+	}
+	if p := g.coop; p != nil && p.emoting {
+		x, y := g.camera.ToScreen(p.cursor.X, p.cursor.Y, g.viewW, g.viewH)
+		g.drawEmoteWheel(screen, Vec{x, y}, p.emotePick())
+	}
+}
+
+// drawEmoteWheel lays the emotes around centre, highlighting the picked one
+func (g *Game) drawEmoteWheel(screen *ebiten.Image, center Vec, pick int) {
+	disc(screen, center.X, center.Y, emoteRadius+30, fade(pal.Scrim, 0x90))
+	for i, e := range emotes {
+		a := 2 * math.Pi * float64(i) / float64(len(emotes))
+		text := tr(e.text)
+		x := center.X + emoteRadius*math.Cos(a) - textWidth(text)/2
+		y := center.Y + emoteRadius*math.Sin(a) + 5
+		col := pal.TextDim
+		if i == pick {
+			col = pal.Warn
+		}
+		drawText(screen, text, int(x), int(y), col)
+	}
+}
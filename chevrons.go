@@ -0,0 +1,57 @@
+package datagame
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// path direction chevrons
+const (
+	chevronGap   = 44.0 // px between chevrons along the path
+	chevronSize  = 7.0  // half-width of each chevron
+	chevronSpeed = 30.0 // px/sec they drift toward the exit
+)
+
+// drawChevrons marches ">" marks along the road toward the exit, so the
+// direction enemies will travel is clear before any spawn; they are held
+// still with reduced motion
+func (g *Game) drawChevrons(screen *ebiten.Image) {
+	offset := 0.0
+	if !g.reducedMotion() {
+		offset = math.Mod(g.fxMS/1000*chevronSpeed, chevronGap)
+	}
+	col := fade(pal.RoadEdge, 0x90)
+	// d is the distance along the path of the next chevron, carried across segments
+	d := offset
+	for i := 0; i < len(g.path)-1; i++ {
+		a, b := g.path[i], g.path[i+1]
+		segLen := dist(a, b)
+		if segLen == 0 {
+			continue
+		}
+		ux, uy := (b.X-a.X)/segLen, (b.Y-a.Y)/segLen
+		for ; d < segLen; d += chevronGap {
+			x, y := a.X+ux*d, a.Y+uy*d
+			if !g.onScreen(x, y, chevronSize) {
+				continue
+			}
+			// arms trail back from the tip on either side of the direction of travel
+			bx, by := x-ux*chevronSize, y-uy*chevronSize
+			line(screen, bx-uy*chevronSize, by+ux*chevronSize, x, y, 3, col)
+			line(screen, bx+uy*chevronSize, by-ux*chevronSize, x, y, 3, col)
+		}
+		d -= segLen
+	}
+}
+
+// segmentDist is the distance from p to the segment a-b
+func segmentDist(p, a, b Vec) float64 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	l2 := dx*dx + dy*dy
+	if l2 == 0 {
+		return dist(p, a)
+	}
+	t := math.Max(0, math.Min(1, ((p.X-a.X)*dx+(p.Y-a.Y)*dy)/l2))
+	return dist(p, Vec{a.X + t*dx, a.Y + t*dy})
+}
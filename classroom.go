@@ -0,0 +1,363 @@
+package datagame
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Classroom mode: the teacher runs the game with -classroom-host, which shows a
+// dashboard instead of a game, and each student runs it with -classroom
+// pointing at the teacher's machine. Students play their own runs as usual;
+// every few seconds their game reports its level, the level in progress,
+// accuracy and new question log entries to the dashboard as a line of JSON
+// over TCP, and the dashboard answers each report with how much of the log it
+// has, so the next one carries on from there.
+
+var (
+	classroomHost = flag.String("classroom-host", "", "show the teacher's classroom dashboard, listening for students on this address, e.g. :7800")
+	classroomJoin = flag.String("classroom", "", "report to the classroom dashboard at this address, e.g. 192.168.1.10:7800")
+)
+
+const (
+	classroomReportMS = 2000.0 // real time between a student's reports
+	classroomRetry    = 5 * time.Second
+	classroomLogMax   = 200 // most questions in each report; more wait for the next
+	dashboardRowH     = 22
+	dashboardRows     = 12 // students listed at once
+)
+
+// dashboardCols are the table's column offsets
+var dashboardCols = [...]int{0, 170, 230, 410, 540}
+
+// classroomReport is a student's state as sent to the dashboard. Log is this
+// run's question log from entry LogFrom on; Run tells runs apart, so the
+// dashboard can keep the logs of earlier ones.
+type classroomReport struct {
+	Name     string         `json:"name"`
+	Run      int64          `json:"run"`
+	Level    int            `json:"level"`
+	HP       float64        `json:"hp"`
+	Wave     WaveStats      `json:"wave"` // the level in progress
+	Answered int            `json:"answered"`
+	Correct  int            `json:"correct"`
+	GameOver bool           `json:"game_over"`
+	LogFrom  int            `json:"log_from"`
+	Log      []HistoryEntry `json:"log"`
+}
+
+// classroomAck is the dashboard's answer to a report: it has the first Have
+// entries of run Run's log
+type classroomAck struct {
+	Run  int64 `json:"run"`
+	Have int   `json:"have"`
+}
+
+// classroomFromFlags sets up whichever side of classroom mode the flags ask for
+func classroomFromFlags() (*classroomStudent, *classroomDashboard) {
+	switch {
+	case *classroomHost != "":
+		return nil, startDashboard(*classroomHost)
+	case *classroomJoin != "":
+		return startStudent(*classroomJoin), nil
+	}
+	return nil, nil
+}
+
+// --- student side ---
+
+// classroomStudent keeps a connection to the dashboard, redialling when it
+// drops; reports that can't be sent straight away are skipped, as the next
+// one carries on from the dashboard's last ack anyway
+type classroomStudent struct {
+	addr      string
+	out       chan classroomReport
+	connected atomic.Bool
+	// the dashboard's last ack, set by the connection goroutine
+	ackRun atomic.Int64
+	acked  atomic.Int64
+	// game goroutine only
+	reportMS  float64
+	wasOnline bool
+}
+
+func startStudent(addr string) *classroomStudent {
+	c := &classroomStudent{addr: addr, out: make(chan classroomReport, 1)}
+	go c.run()
+	return c
+}
+
+func (c *classroomStudent) run() {
+	for {
+		conn, err := net.DialTimeout("tcp", c.addr, classroomRetry)
+		if err != nil {
+			time.Sleep(classroomRetry)
+			continue
+		}
+		c.connected.Store(true)
+		// a dashboard started afresh has nothing, so send from the start
+		// until it says otherwise
+		c.ackRun.Store(0)
+		go func() {
+			dec := json.NewDecoder(conn)
+			for {
+				var a classroomAck
+				if dec.Decode(&a) != nil {
+					return
+				}
+				c.ackRun.Store(a.Run)
+				c.acked.Store(int64(a.Have))
+			}
+		}()
+		enc := json.NewEncoder(conn)
+		for r := range c.out {
+			if enc.Encode(r) != nil {
+				break
+			}
+		}
+		conn.Close()
+		c.connected.Store(false)
+		time.Sleep(classroomRetry)
+	}
+}
+
+// studentName is what the dashboard lists this student as: the player name
+// from settings, else the computer's name
+func (g *Game) studentName() string {
+	if g.settings.PlayerName != "" {
+		return g.settings.PlayerName
+	}
+	if host, err := os.Hostname(); err == nil {
+		return host
+	}
+	return "Student"
+}
+
+// updateStudent reports to the dashboard every classroomReportMS and says
+// when the connection comes and goes
+func (g *Game) updateStudent(dt float64) {
+	c := g.student
+	if c == nil {
+		return
+	}
+	c.reportMS -= dt
+	if c.reportMS > 0 {
+		return
+	}
+	c.reportMS = classroomReportMS
+	if online := c.connected.Load(); online != c.wasOnline {
+		c.wasOnline = online
+		g.levelMsg = tr("Lost the classroom connection; retrying")
+		if online {
+			g.levelMsg = tr("Connected to the classroom")
+		}
+		g.levelMsgTimer = 2000
+	}
+	r := classroomReport{Name: g.studentName(), Run: g.sessionStart.UnixNano(), Level: g.level, HP: g.playerHP,
+		Wave: g.wave, GameOver: g.gameOver}
+	for _, w := range g.waves {
+		r.Answered += w.Answered
+		r.Correct += w.Correct
+	}
+	if !g.gameOver {
+		// the level in progress isn't filed in g.waves until it ends
+		r.Answered += g.wave.Answered
+		r.Correct += g.wave.Correct
+	}
+	if c.ackRun.Load() == r.Run {
+		r.LogFrom = min(int(c.acked.Load()), len(g.history))
+	}
+	r.Log = g.history[r.LogFrom:min(len(g.history), r.LogFrom+classroomLogMax)]
+	select {
+	case c.out <- r:
+	default:
+	}
+}
+
+// --- teacher side ---
+
+// classroomSeat is one student as the dashboard knows them
+type classroomSeat struct {
+	last    classroomReport
+	earlier []HistoryEntry // question logs of their finished runs
+	runLog  []HistoryEntry // the run in play's, as far as reports have brought it
+	online  bool
+}
+
+// log is every question the student has answered this session
+func (s *classroomSeat) log() []HistoryEntry {
+	return append(s.earlier[:len(s.earlier):len(s.earlier)], s.runLog...)
+}
+
+// classroomDashboard collects student reports; mu guards seats and err, which
+// the connection goroutines fill in
+type classroomDashboard struct {
+	addr  string
+	mu    sync.Mutex
+	seats []*classroomSeat // in the order students joined
+	err   error
+	// game goroutine only
+	selected int
+	scroll   int
+}
+
+func startDashboard(addr string) *classroomDashboard {
+	d := &classroomDashboard{addr: addr}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		d.err = err
+		return d
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				d.mu.Lock()
+				d.err = err
+				d.mu.Unlock()
+				return
+			}
+			go d.serve(conn)
+		}
+	}()
+	return d
+}
+
+// serve reads one student's reports until they disconnect. A student who
+// reconnects under the same name takes their old seat back.
+func (d *classroomDashboard) serve(conn net.Conn) {
+	defer conn.Close()
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	var seat *classroomSeat
+	for {
+		var r classroomReport
+		if err := dec.Decode(&r); err != nil {
+			break
+		}
+		d.mu.Lock()
+		if seat == nil || seat.last.Name != r.Name {
+			seat = d.seat(r.Name)
+		}
+		if seat.last.Run != 0 && seat.last.Run != r.Run {
+			seat.earlier = append(seat.earlier, seat.runLog...)
+			seat.runLog = nil
+		}
+		// a report from past what we have (after a dashboard restart) is
+		// skipped; the ack sends the student back to where we are
+		if r.LogFrom <= len(seat.runLog) {
+			seat.runLog = append(seat.runLog[:r.LogFrom], r.Log...)
+		}
+		seat.last = r
+		seat.online = true
+		ack := classroomAck{Run: r.Run, Have: len(seat.runLog)}
+		d.mu.Unlock()
+		enc.Encode(ack)
+	}
+	if seat != nil {
+		d.mu.Lock()
+		seat.online = false
+		d.mu.Unlock()
+	}
+}
+
+// seat finds or adds the student called name; d.mu must be held
+func (d *classroomDashboard) seat(name string) *classroomSeat {
+	for _, s := range d.seats {
+		if s.last.Name == name {
+			return s
+		}
+	}
+	s := &classroomSeat{last: classroomReport{Name: name}}
+	d.seats = append(d.seats, s)
+	return s
+}
+
+// updateDashboard picks a student with Up/Down and scrolls their question log
+func (g *Game) updateDashboard() {
+	d := g.dashboard
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := len(d.seats)
+	if n == 0 {
+		return
+	}
+	prev := d.selected
+	if inpututil.IsKeyJustPressed(ebiten.KeyUp) {
+		d.selected--
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+		d.selected++
+	}
+	d.selected = max(0, min(d.selected, n-1))
+	log := d.seats[d.selected].log()
+	visible := historyRows(g.dashboardLogRect().H)
+	if d.selected != prev {
+		// a newly picked student's log opens at its newest entries
+		d.scroll = len(log) - visible
+	}
+	scrollLog(&d.scroll, len(log), visible)
+}
+
+// dashboardLogRect is where the selected student's question log goes, under the table
+func (g *Game) dashboardLogRect() Rect {
+	top := 60.0 + (dashboardRows+1)*dashboardRowH
+	return Rect{hudMargin, top, float64(g.viewW) - 2*hudMargin, float64(g.viewH) - top - hudMargin}
+}
+
+// drawDashboard lists every student's live state, with the selected
+// student's question log underneath
+func (g *Game) drawDashboard(screen *ebiten.Image) {
+	d := g.dashboard
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	x, y := int(hudMargin)+10, 30
+	drawTextSize(screen, trf("Classroom dashboard, listening on %s", d.addr), x, y, FontHeading, pal.Text)
+	if d.err != nil {
+		drawText(screen, tr("Couldn't listen for students: ")+d.err.Error(), x, y+24, pal.Bad)
+		return
+	}
+	if len(d.seats) == 0 {
+		drawText(screen, tr("Waiting for students to join..."), x, y+24, pal.TextDim)
+		return
+	}
+	y += 24
+	for i, h := range [...]string{"Student", "Level", "This level", "Accuracy", "Status"} {
+		drawText(screen, tr(h), x+dashboardCols[i], y, pal.TextDim)
+	}
+	// the table scrolls to keep the selected student in view
+	first := max(0, d.selected-dashboardRows+1)
+	for i, s := range d.seats[first:min(len(d.seats), first+dashboardRows)] {
+		r := s.last
+		yy := y + (i+1)*dashboardRowH
+		col := pal.Text
+		if first+i == d.selected {
+			rect(screen, hudMargin, float64(yy-15), float64(g.viewW)-2*hudMargin, dashboardRowH, fade(pal.Warn, 0x40))
+		}
+		status := tr("playing")
+		switch {
+		case !s.online:
+			status, col = tr("offline"), pal.TextDim
+		case r.GameOver:
+			status = tr("run over")
+		}
+		acc := "-"
+		if r.Answered > 0 {
+			acc = fmt.Sprintf("%.0f%% (%d)", 100*float64(r.Correct)/float64(r.Answered), r.Answered)
+		}
+		for i, v := range [...]string{r.Name, fmt.Sprint(r.Level), trf("%d kills, %d leaks", r.Wave.Kills, r.Wave.Leaks), acc, status} {
+			drawText(screen, v, x+dashboardCols[i], yy, col)
+		}
+	}
+	seat := d.seats[d.selected]
+	lr := g.dashboardLogRect()
+	drawLog(screen, seat.log(), d.scroll, int(lr.X), int(lr.Y), lr.W, lr.H, trf("%s's questions (Up/Down: pick a student)", seat.last.Name))
+}
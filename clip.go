@@ -0,0 +1,136 @@
+package datagame
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"slices"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Clips: the game keeps the last clipSeconds of play as small frames, and the
+// clip key (F9 by default) saves them as an animated GIF in the clips folder
+// (a download, in a browser), to share a good moment or show a bug. Frames are
+// grabbed clipFPS times a second at clipW pixels wide, so the recording costs
+// little; the GIF is encoded in the background.
+
+const (
+	clipSeconds = 10
+	clipFPS     = 10
+	clipW       = 320 // frame width in pixels; the height follows the window's shape
+)
+
+// clipRecorder holds the recent frames, oldest first from head once full. It
+// outlives runs, like touch, so a clip can show a restart.
+type clipRecorder struct {
+	small  *ebiten.Image // the screen scaled down, read back each frame
+	frames []clipFrame
+	head   int
+	last   time.Time
+	saving bool
+	done   chan clipResult
+}
+
+var clip clipRecorder
+
+type clipFrame struct {
+	img *image.RGBA
+	at  time.Time
+}
+
+type clipResult struct {
+	path string
+	err  error
+}
+
+// capture grabs a frame of screen when one is due
+func (c *clipRecorder) capture(screen *ebiten.Image) {
+	now := time.Now()
+	if now.Sub(c.last) < time.Second/clipFPS {
+		return
+	}
+	c.last = now
+	sb := screen.Bounds()
+	w, h := clipW, max(1, clipW*sb.Dy()/max(1, sb.Dx()))
+	if c.small == nil || c.small.Bounds().Dx() != w || c.small.Bounds().Dy() != h {
+		// a new window shape: frames of the old one can't go in the same GIF
+		c.small = ebiten.NewImage(w, h)
+		c.frames, c.head = nil, 0
+	}
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Scale(float64(w)/float64(sb.Dx()), float64(h)/float64(sb.Dy()))
+	c.small.Clear()
+	c.small.DrawImage(screen, op)
+	f := clipFrame{at: now}
+	if len(c.frames) < clipSeconds*clipFPS {
+		f.img = image.NewRGBA(image.Rect(0, 0, w, h))
+		c.frames = append(c.frames, f)
+	} else {
+		// reuse the oldest frame's pixels
+		f.img = c.frames[c.head].img
+		c.frames[c.head] = f
+		c.head = (c.head + 1) % len(c.frames)
+	}
+	c.small.ReadPixels(f.img.Pix)
+}
+
+// updateClip saves a clip when the clip key is pressed, and reports when it
+// has been written
+func (g *Game) updateClip() {
+	c := &clip
+	select {
+	case r := <-c.done:
+		c.saving = false
+		if r.err != nil {
+			g.levelMsg = trf("Couldn't save the clip: %v", r.err)
+		} else {
+			g.levelMsg = trf("Saved %s", r.path)
+		}
+		g.levelMsgTimer = 4000
+	default:
+	}
+	if !inpututil.IsKeyJustPressed(g.settings.Keys.Clip) || c.saving || len(c.frames) < 2 {
+		return
+	}
+	// hand the frames over in order and start a new recording, so the
+	// encoder has them to itself
+	frames := slices.Concat(c.frames[c.head:], c.frames[:c.head])
+	c.frames, c.head = nil, 0
+	c.saving = true
+	if c.done == nil {
+		c.done = make(chan clipResult, 1)
+	}
+	g.levelMsg = trf("Saving the last %d seconds as a GIF...", clipSeconds)
+	g.levelMsgTimer = 4000
+	go func() {
+		path, err := saveClip(frames)
+		clip.done <- clipResult{path, err}
+	}()
+}
+
+// saveClip encodes the frames as a looping GIF, each shown for as long as it
+// was on screen, and exports it
+func saveClip(frames []clipFrame) (string, error) {
+	anim := &gif.GIF{}
+	for i, f := range frames {
+		p := image.NewPaletted(f.img.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(p, p.Bounds(), f.img, image.Point{})
+		delay := 100 / clipFPS
+		if i+1 < len(frames) {
+			delay = min(max(2, int(frames[i+1].at.Sub(f.at)/(10*time.Millisecond))), 100)
+		}
+		anim.Image = append(anim.Image, p)
+		anim.Delay = append(anim.Delay, delay)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return "", err
+	}
+	return storage.Export(fmt.Sprintf("clips/clip-%s.gif", time.Now().Format("20060102-150405")), buf.Bytes())
+}
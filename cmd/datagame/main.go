@@ -0,0 +1,9 @@
+// Command datagame runs DataGame, the math tower defense, in a window or,
+// built with GOOS=js GOARCH=wasm, on a web page.
+package main
+
+import "datagame"
+
+func main() {
+	datagame.Run()
+}
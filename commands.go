@@ -0,0 +1,95 @@
+package datagame
+
+// Every player action that changes the battle (building, upgrading, selling
+// and moving towers, shop purchases, a right answer against a boss or taking
+// healing as its reward, starting the next wave early, speed,
+// pause and the mode toggles) is a Command, issued with g.do. On its own the
+// game applies a command straight away; under lockstep (see lockstep.go) it is
+// stamped with a future tick, sent to the other players, and applied by every
+// game on that tick, so their simulations stay identical. Commands name
+// towers by position rather than index, since another player's sale can
+// shift the indices before a command lands.
+
+// Command is one player action, in a form that can be sent over the network
+type Command struct {
+	Player int     `json:"player,omitempty"` // 0 for the host or a solo player
+	Kind   string  `json:"kind"`             // build, upgrade, sell, move, buy, shield, heal, item, start, speed, pause or mode
+	X      float64 `json:"x,omitempty"`      // where to build, or the tower acted on
+	Y      float64 `json:"y,omitempty"`
+	To     Vec     `json:"to,omitempty"`   // move: the new spot
+	Type   string  `json:"type,omitempty"` // build: tower type; buy: item label; item: its name; mode: "horde" or "math-gated"
+	N      int     `json:"n,omitempty"`    // buy: levels; speed: index into gameSpeeds
+}
+
+// do issues a command: applied now when playing alone, else queued for the
+// lockstep tick it will land on
+func (g *Game) do(c Command) {
+	if g.lockstep != nil && g.lockstep.started {
+		g.lockstep.issue(c)
+		return
+	}
+	g.apply(c)
+}
+
+// apply carries out a command. It re-checks gold and placement, which may have
+// changed between issuing the command and its tick coming round.
+func (g *Game) apply(c Command) {
+	switch c.Kind {
+	case "build":
+		// another player may have built there within the input delay
+		if !g.canPlaceTower(Vec{c.X, c.Y}, -1) {
+			return
+		}
+		tw := newTower(c.Type, Vec{c.X, c.Y})
+		tw.Owner = c.Player
+		g.towers = append(g.towers, tw)
+		g.recordBuild(tw)
+	case "upgrade":
+		if i := g.towerAt(c.X, c.Y); i >= 0 {
+			upgradeTower(g.towers[i], g.rand.Float64(), g.upgradeBonus)
+		}
+	case "sell":
+		g.sellTower(g.towerAt(c.X, c.Y))
+	case "move":
+		i := g.towerAt(c.X, c.Y)
+		if i < 0 {
+			return
+		}
+		tw := g.towers[i]
+		if cost := moveCost(tw); g.playerGold >= cost && g.canPlaceTower(c.To, i) {
+			g.playerGold -= cost
+			tw.X, tw.Y = c.To.X, c.To.Y
+		}
+	case "buy":
+		for range max(c.N, 1) {
+			it, ok := g.shopItem(c.Type)
+			if !ok || it.full || g.playerGold < it.cost {
+				return
+			}
+			g.playerGold -= it.cost
+			it.buy()
+		}
+	case "shield":
+		g.stripBossShields()
+	case "heal":
+		g.heal(RewardHealHP)
+	case "item":
+		g.applyItem(c)
+	case "start":
+		// the pause may have run out by the command's tick
+		if g.interLevelActive {
+			g.startWave()
+		}
+	case "speed":
+		g.speedIdx, g.paused = max(0, min(c.N, len(gameSpeeds)-1)), false
+	case "pause":
+		g.paused = !g.paused
+	case "mode":
+		switch c.Type {
+		case "horde":
+			g.toggleHorde()
+		case "math-gated":
+			g.toggleMathGated()
+		}
+	}
+}
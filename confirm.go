@@ -0,0 +1,98 @@
+package datagame
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// ConfirmCostThreshold is the shop price from which purchases ask for confirmation
+const ConfirmCostThreshold = 200
+
+// ConfirmDialog is a modal yes/no question; the game is paused while it is open
+type ConfirmDialog struct {
+	Title   string
+	Message string
+	OnYes   func()
+}
+
+// ask opens a confirmation dialog that runs onYes if the player accepts
+func (g *Game) ask(title, message string, onYes func()) {
+	g.confirm = &ConfirmDialog{Title: title, Message: message, OnYes: onYes}
+}
+
+func (g *Game) confirmRect() Rect {
+	w := max(360, textWidth(g.confirm.Message)+40)
+	return g.centered(w, 110)
+}
+
+// confirmButtons returns the Yes and No buttons along the bottom of the dialog
+func (g *Game) confirmButtons() []Button {
+	r := g.confirmRect()
+	y := r.Y + r.H - 38
+	return []Button{
+		{Rect: Rect{r.X + r.W/2 - 110, y, 100, 28}, Text: tr("Yes"), OnClick: g.acceptConfirm},
+		{Rect: Rect{r.X + r.W/2 + 10, y, 100, 28}, Text: tr("No"), OnClick: func() { g.confirm = nil }},
+	}
+}
+
+func (g *Game) acceptConfirm() {
+	d := g.confirm
+	g.confirm = nil
+	d.OnYes()
+}
+
+// updateConfirm handles the open dialog: Y/Enter accepts, N/Esc or right-click declines, or click a button
+func (g *Game) updateConfirm() {
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyY) || inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		g.acceptConfirm()
+	case inpututil.IsKeyJustPressed(ebiten.KeyN) || inpututil.IsKeyJustPressed(ebiten.KeyEscape) ||
+		cancelJustPressed():
+		g.confirm = nil
+	case pointerJustReleased():
+		x, y := cursor()
+		pressButton(g.confirmButtons(), x, y)
+	}
+}
+
+// confirmPurchase buys a shop item, asking first when it is expensive
+func (g *Game) confirmPurchase(it shopItem) {
+	if it.cost < ConfirmCostThreshold {
+		g.purchase(it.cost, Command{Kind: "buy", Type: it.label})
+		return
+	}
+	g.ask(tr("Confirm purchase"), trf("Buy %s for %d gold?", tr(it.label), it.cost), func() {
+		g.purchase(it.cost, Command{Kind: "buy", Type: it.label})
+	})
+}
+
+// confirmSell asks before selling the selected tower
+func (g *Game) confirmSell() {
+	if g.selected < 0 || g.selected >= len(g.towers) {
+		return
+	}
+	tw := g.towers[g.selected]
+	g.ask(tr("Sell tower"), trf("Sell this tower for %d gold?", towerSellValue(tw)), func() {
+		g.do(Command{Kind: "sell", X: tw.X, Y: tw.Y})
+	})
+}
+
+// confirmRestart asks before throwing away the current run
+func (g *Game) confirmRestart() {
+	g.ask(tr("Restart run"), tr("Start a new run? This run's progress will be lost."), func() {
+		g.startRun(g.nextRun())
+	})
+}
+
+func (g *Game) drawConfirm(screen *ebiten.Image) {
+	// dim everything behind the dialog
+	rect(screen, 0, 0, float64(g.viewW), float64(g.viewH), fade(pal.Scrim, 0x70))
+	r := g.confirmRect()
+	p := Panel{Rect: r, Title: g.confirm.Title}
+	p.Draw(screen)
+	x, y := p.Content()
+	drawText(screen, g.confirm.Message, x+12, y+4, pal.Text)
+	for _, b := range g.confirmButtons() {
+		b.Draw(screen)
+	}
+}
@@ -0,0 +1,412 @@
+package datagame
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"math/rand"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// The content folder: players and teachers add their own maps, wave sets and
+// question banks as JSON files in content/maps, content/waves and
+// content/questions in the game's own folder. The folder is read at startup,
+// and a file that doesn't load is listed in the content manager (I) with what
+// is wrong with it, instead of stopping the game. The manager plays a map,
+// puts a wave set or a question bank in use, and exports any item, the
+// game's own included, to the exports folder, to share it or to start a new
+// one from. Maps are the maps/*.json format, wave sets data/waves.json's and
+// question banks data/questions.json's.
+
+type contentKind int
+
+const (
+	contentMap contentKind = iota
+	contentWaves
+	contentBank
+)
+
+// contentDirs are the folders of each kind, by kind
+var contentDirs = []string{"content/maps", "content/waves", "content/questions"}
+
+var contentKindNames = []string{"Map", "Wave set", "Question bank"}
+
+// contentItem is a file of the content folder, or one of the game's own maps,
+// wave set or question bank
+type contentItem struct {
+	kind    contentKind
+	name    string // the file name without .json
+	builtIn bool
+	data    []byte
+	err     error // why it didn't load, nil when it did
+	mapDef  *MapDef
+	waves   WaveTuning
+	bank    *QuestionBank
+}
+
+// contentLib is what the last scanContent found, the built-in items first
+var contentLib []contentItem
+
+// builtinWaves is the wave tuning a wave set replaces, for when it is taken
+// out of use
+var builtinWaves WaveTuning
+
+// QuestionBank is a set of written questions, asked in challenges in place
+// of generated ones while it is in use
+type QuestionBank struct {
+	Name      string         `json:"name"`
+	Questions []BankQuestion `json:"questions"`
+}
+
+// BankQuestion is one question of a bank. Answers are whole or decimal
+// numbers, and may be money ("$4.50").
+type BankQuestion struct {
+	Text   string `json:"text"`
+	Answer string `json:"answer"`
+	Level  int    `json:"level"` // the first level it is asked at; 0 for from the start
+	ans    int    // Answer scaled by 10^places, as in Question
+	places int
+}
+
+// startContent reads the content folder and puts the wave set s picks in
+// use; it returns how many files didn't load
+func startContent(s *Settings) int {
+	builtinWaves = waveTuning
+	problems := scanContent()
+	useWaveSet(s.ContentWaves)
+	return problems
+}
+
+// scanContent lists the built-in items and reads the content folder into
+// contentLib, returning how many files didn't load
+func scanContent() int {
+	contentLib = nil
+	for _, name := range mapNames() {
+		data, _ := readData(mapFS, "maps/"+name+".json")
+		contentLib = append(contentLib, loadContent(contentMap, name, data))
+	}
+	data, _ := dataFS.ReadFile("data/waves.json")
+	contentLib = append(contentLib, loadContent(contentWaves, "default", data))
+	data, _ = dataFS.ReadFile("data/questions.json")
+	contentLib = append(contentLib, loadContent(contentBank, "example", data))
+	for i := range contentLib {
+		contentLib[i].builtIn = true
+	}
+	problems := 0
+	for kind, dir := range contentDirs {
+		for _, name := range contentFiles(contentKind(kind)) {
+			it := contentItem{kind: contentKind(kind), name: name}
+			if data, err := storage.ReadFile(dir + "/" + name + ".json"); err != nil {
+				it.err = err
+			} else {
+				it = loadContent(it.kind, name, data)
+			}
+			if it.err == nil && findContent(it.kind, name) != nil {
+				it.err = errors.New("one of the game's own has this name; rename the file")
+			}
+			if it.err != nil {
+				problems++
+			}
+			contentLib = append(contentLib, it)
+		}
+	}
+	return problems
+}
+
+// contentFiles lists the content folder's files of a kind by name, without
+// .json
+func contentFiles(kind contentKind) []string {
+	files, _ := storage.List(contentDirs[kind]) // no folder, no content
+	var names []string
+	for _, f := range files {
+		if name, ok := strings.CutSuffix(f, ".json"); ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// loadContent checks an item's data, keeping what it loads as
+func loadContent(kind contentKind, name string, data []byte) contentItem {
+	it := contentItem{kind: kind, name: name, data: data}
+	switch kind {
+	case contentMap:
+		if slices.Contains(mapNames(), name) {
+			it.mapDef, it.err = parseMap(name, data)
+		} else {
+			it.mapDef, it.err = parseContentMap(name, data)
+		}
+	case contentWaves:
+		it.waves, it.err = parseWaveTuning(data)
+	case contentBank:
+		it.bank, it.err = parseQuestionBank(data)
+	}
+	return it
+}
+
+// findContent finds an item by kind and name, nil for none
+func findContent(kind contentKind, name string) *contentItem {
+	i := slices.IndexFunc(contentLib, func(it contentItem) bool { return it.kind == kind && it.name == name })
+	if i < 0 {
+		return nil
+	}
+	return &contentLib[i]
+}
+
+// usableContent is findContent for items that loaded
+func usableContent(kind contentKind, name string) *contentItem {
+	if it := findContent(kind, name); it != nil && it.err == nil {
+		return it
+	}
+	return nil
+}
+
+// title is the item's own name for itself, or its file's
+func (it *contentItem) title() string {
+	switch {
+	case it.mapDef != nil && it.mapDef.Name != "":
+		return it.mapDef.Name
+	case it.bank != nil && it.bank.Name != "":
+		return it.bank.Name
+	}
+	return it.name
+}
+
+// useWaveSet puts the named wave set in use, or the built-in tuning for ""
+// or a set that is gone or broken
+func useWaveSet(name string) {
+	waveTuning = builtinWaves
+	if it := usableContent(contentWaves, name); it != nil && !it.builtIn {
+		waveTuning = it.waves
+	}
+}
+
+// activeBank is the question bank in use, nil for generated questions
+func (g *Game) activeBank() *QuestionBank {
+	if it := usableContent(contentBank, g.settings.ContentQuestions); it != nil {
+		return it.bank
+	}
+	return nil
+}
+
+// noteContent marks the run as played with the content folder's waves or
+// questions once either is in use, which keeps it off the boards and records
+func (g *Game) noteContent() {
+	if it := usableContent(contentWaves, g.settings.ContentWaves); (it != nil && !it.builtIn) || g.activeBank() != nil {
+		g.customContent = true
+	}
+}
+
+// parseQuestionBank reads and checks a question bank
+func parseQuestionBank(data []byte) (*QuestionBank, error) {
+	b := &QuestionBank{}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, err
+	}
+	if len(b.Questions) == 0 {
+		return nil, errors.New("the bank has no questions")
+	}
+	for i := range b.Questions {
+		q := &b.Questions[i]
+		answer := strings.TrimPrefix(strings.TrimSpace(q.Answer), "$")
+		_, frac, _ := strings.Cut(answer, ".")
+		q.places = len(frac)
+		var ok bool
+		q.ans, ok = parseScaled(answer, q.places)
+		switch {
+		case strings.TrimSpace(q.Text) == "":
+			return nil, fmt.Errorf("question %d has no text", i+1)
+		case !ok:
+			return nil, fmt.Errorf("question %d: the answer %q isn't a number", i+1, q.Answer)
+		}
+	}
+	return b, nil
+}
+
+// question picks one of the bank's questions open at level, or any of them
+// if none is open yet
+func (b *QuestionBank) question(r *rand.Rand, level int) *Question {
+	var open []*BankQuestion
+	for i := range b.Questions {
+		if b.Questions[i].Level <= level {
+			open = append(open, &b.Questions[i])
+		}
+	}
+	if len(open) == 0 {
+		for i := range b.Questions {
+			open = append(open, &b.Questions[i])
+		}
+	}
+	q := open[r.Intn(len(open))]
+	return &Question{Text: q.Text, Ans: q.ans, Places: q.places, Op: "bank"}
+}
+
+// ContentManager is the content overlay (I)
+type ContentManager struct {
+	row int
+	msg string
+}
+
+func (g *Game) openContent() {
+	g.content = &ContentManager{}
+}
+
+// updateContent handles the content overlay: Up/Down select, Enter uses the
+// item, E exports it, R reads the folder again and Esc or I close
+func (g *Game) updateContent() {
+	c := g.content
+	n := len(contentLib)
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape), inpututil.IsKeyJustPressed(ebiten.KeyI):
+		g.content = nil
+	case inpututil.IsKeyJustPressed(ebiten.KeyUp):
+		c.row = (c.row + n - 1) % n
+	case inpututil.IsKeyJustPressed(ebiten.KeyDown):
+		c.row = (c.row + 1) % n
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter), inpututil.IsKeyJustPressed(ebiten.KeyNumpadEnter):
+		g.useContent(&contentLib[c.row])
+	case inpututil.IsKeyJustPressed(ebiten.KeyE):
+		it := &contentLib[c.row]
+		if it.data == nil {
+			c.msg = tr("There is nothing to export")
+		} else if where, err := storage.Export(fmt.Sprintf("exports/%s/%s.json", path.Base(contentDirs[it.kind]), it.name), it.data); err != nil {
+			c.msg = trf("Couldn't export: %v", err)
+		} else {
+			c.msg = trf("Saved %s", where)
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyR):
+		problems := scanContent()
+		if g.lockstep == nil {
+			// networked co-op keeps the host's waves
+			useWaveSet(g.settings.ContentWaves)
+		}
+		g.noteContent()
+		c.row = min(c.row, len(contentLib)-1)
+		c.msg = trf("%d items, %d with problems", len(contentLib), problems)
+	}
+}
+
+// useContent plays a map, or puts a wave set or question bank in use (or
+// back out of use, when it already is)
+func (g *Game) useContent(it *contentItem) {
+	c := g.content
+	if it.err != nil {
+		c.msg = tr("Fix the file, then press R to read it again")
+		return
+	}
+	switch it.kind {
+	case contentMap:
+		if g.lockstepLocked() {
+			c.msg = g.levelMsg
+			return
+		}
+		name := it.name
+		g.ask(tr("Play this map?"), trf("This run ends and a new one starts on %s.", it.title()), func() {
+			// like -map, the map stays for the rest of the session
+			*launchMap = name
+			g.startRun(g.nextRun())
+		})
+	case contentWaves:
+		if g.lockstepLocked() {
+			c.msg = g.levelMsg
+			return
+		}
+		name := it.name
+		if it.builtIn || g.settings.ContentWaves == name {
+			name = ""
+		}
+		g.settings.ContentWaves = name
+		g.settings.Save()
+		useWaveSet(name)
+		g.spawnInt = max(g.spawnInt, waveTuning.SpawnIntervalMin)
+		set := tr("the built-in waves")
+		if name != "" {
+			set = it.title()
+		}
+		c.msg = trf("Waves from %s, from the next enemy", set)
+		g.noteContent()
+	case contentBank:
+		name := it.name
+		if g.settings.ContentQuestions == name {
+			name = ""
+		}
+		g.settings.ContentQuestions = name
+		g.settings.Save()
+		if g.activeBank() != nil {
+			c.msg = trf("Challenges ask questions from %s", it.title())
+		} else {
+			c.msg = tr("Challenges ask generated questions")
+		}
+		g.noteContent()
+	}
+}
+
+// inUse reports whether the item is the map being played, or the wave set or
+// question bank in use
+func (g *Game) inUse(it *contentItem) bool {
+	switch it.kind {
+	case contentMap:
+		return g.mapDef.File == it.name
+	case contentWaves:
+		active := usableContent(contentWaves, g.settings.ContentWaves)
+		return active == it || (active == nil && it.builtIn)
+	}
+	return usableContent(contentBank, g.settings.ContentQuestions) == it
+}
+
+const contentRows = 14 // items shown at once
+
+func (g *Game) drawContent(screen *ebiten.Image) {
+	c := g.content
+	w, h := 680.0, 120.0+contentRows*22
+	r := g.centered(w, h)
+	x0, y0 := int(r.X), int(r.Y)
+	rect(screen, r.X, r.Y, w, h, fade(pal.Scrim, 0xE0))
+	drawText(screen, tr("Content (I or Esc to close)"), x0+10, y0+20, pal.Text)
+	first := min(max(0, c.row-contentRows/2), max(0, len(contentLib)-contentRows))
+	for i := first; i < min(len(contentLib), first+contentRows); i++ {
+		it := &contentLib[i]
+		col := color.Color(pal.Text)
+		prefix := "  "
+		if i == c.row {
+			col = pal.Warn
+			prefix = "> "
+		}
+		label := prefix + tr(contentKindNames[it.kind]) + ": " + it.title()
+		switch {
+		case it.err != nil:
+			label += tr(" - didn't load")
+			if i != c.row {
+				col = pal.Bad
+			}
+		case g.inUse(it):
+			label += tr(" - in use")
+		}
+		if rec := g.records[it.name]; it.kind == contentMap && rec != nil {
+			label += " - " + trf("best %d, %d/3 stars", rec.Best, rec.Stars)
+		}
+		if it.builtIn {
+			label += tr(" (built in)")
+		}
+		drawText(screen, label, x0+10, y0+46+(i-first)*22, col)
+	}
+	// what is wrong with the selected item, or where it lives
+	it := &contentLib[c.row]
+	detail, col := contentDirs[it.kind]+"/"+it.name+".json", pal.TextDim
+	if it.builtIn {
+		detail = tr("Part of the game; export it to make your own")
+	}
+	if it.err != nil {
+		detail, col = it.err.Error(), pal.Bad
+	}
+	drawText(screen, detail, x0+10, y0+int(h)-54, col)
+	drawText(screen, c.msg, x0+10, y0+int(h)-32, pal.Warn)
+	drawText(screen, tr("Up/Down select, Enter play or use, E export, R read the folder again"), x0+10, y0+int(h)-10, pal.TextDim)
+}
@@ -0,0 +1,352 @@
+package datagame
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Local co-op: pressing Start on a gamepad joins a second player, who plays
+// beside the mouse-and-keyboard player on the same map. Player 2 steers a
+// cursor with the stick or D-pad, builds and upgrades their own towers with
+// their own questions, and earns their own gold from what their towers kill.
+// Questions are multiple choice on the face buttons, so no keyboard is needed.
+
+const (
+	coopCursorSpeed = 320.0 // map px per second at full stick
+	coopDeadZone    = 0.25
+	coopMsgMS       = 2000.0
+	coopPanelW      = 380.0
+)
+
+// coopAnswerButtons pick the answer choices, in the order they are listed
+var coopAnswerButtons = [4]ebiten.StandardGamepadButton{
+	ebiten.StandardGamepadButtonRightBottom, // A
+	ebiten.StandardGamepadButtonRightRight,  // B
+	ebiten.StandardGamepadButtonRightLeft,   // X
+	ebiten.StandardGamepadButtonRightTop,    // Y
+}
+
+var coopAnswerLabels = [4]string{"A", "B", "X", "Y"}
+
+// coopPlayer is player 2's state; Tower.Owner is 1 for their towers
+type coopPlayer struct {
+	pad       ebiten.GamepadID
+	cursor    Vec // map position
+	gold      int
+	buildType int // index into towerTypes
+	reviews   ReviewQueue
+	// the open question, its answers by coopAnswerButtons (scaled like
+	// Question.Ans) and what a right answer does
+	question *Question
+	choices  [4]int
+	reward   func()
+	elapsed  float64 // ms the question has been open
+	msg      string
+	msgMS    float64
+	emoting  bool // Y is held with the emote wheel open
+}
+
+// updateCoop lets a gamepad join or leave as player 2 and runs player 2's
+// input. It runs while player 1 has a question open, so both can answer at once.
+func (g *Game) updateCoop(dt float64) {
+	if g.coop == nil {
+		// networked co-op already has its player 2
+		if g.lockstep != nil {
+			return
+		}
+		g.gamepads = ebiten.AppendGamepadIDs(g.gamepads[:0])
+		for _, id := range g.gamepads {
+			if ebiten.IsStandardGamepadLayoutAvailable(id) &&
+				inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonCenterRight) {
+				g.coop = &coopPlayer{pad: id, cursor: Vec{MapW / 2, MapH / 2}}
+				g.levelMsg = tr("Player 2 joined")
+				g.levelMsgTimer = 2000
+				return
+			}
+		}
+		return
+	}
+	p := g.coop
+	if inpututil.IsGamepadJustDisconnected(p.pad) ||
+		inpututil.IsStandardGamepadButtonJustPressed(p.pad, ebiten.StandardGamepadButtonCenterRight) {
+		g.leaveCoop()
+		return
+	}
+	if p.msgMS > 0 {
+		p.msgMS -= dt
+	}
+	if p.question != nil {
+		p.elapsed += dt
+		g.updateCoopAnswer(p)
+		return
+	}
+	if g.updateCoopEmote(p) {
+		return
+	}
+	p.moveCursor(dt)
+	pressed := func(b ebiten.StandardGamepadButton) bool {
+		return inpututil.IsStandardGamepadButtonJustPressed(p.pad, b)
+	}
+	switch {
+	case pressed(ebiten.StandardGamepadButtonFrontTopLeft):
+		p.buildType = (p.buildType + len(towerTypes) - 1) % len(towerTypes)
+	case pressed(ebiten.StandardGamepadButtonFrontTopRight):
+		p.buildType = (p.buildType + 1) % len(towerTypes)
+	case pressed(ebiten.StandardGamepadButtonRightBottom):
+		g.coopBuildOrUpgrade(p)
+	case pressed(ebiten.StandardGamepadButtonRightLeft):
+		g.coopBuyUpgrade(p)
+	}
+}
+
+// leaveCoop drops player 2; their towers and gold go to player 1
+func (g *Game) leaveCoop() {
+	for _, tw := range g.towers {
+		tw.Owner = 0
+	}
+	g.playerGold += g.coop.gold
+	g.coop = nil
+	g.levelMsg = tr("Player 2 left; their towers and gold go to player 1")
+	g.levelMsgTimer = 3000
+}
+
+// moveCursor steers player 2's cursor with the left stick or the D-pad
+func (p *coopPlayer) moveCursor(dt float64) {
+	dx := ebiten.StandardGamepadAxisValue(p.pad, ebiten.StandardGamepadAxisLeftStickHorizontal)
+	dy := ebiten.StandardGamepadAxisValue(p.pad, ebiten.StandardGamepadAxisLeftStickVertical)
+	if math.Hypot(dx, dy) < coopDeadZone {
+		dx, dy = 0, 0
+	}
+	held := func(b ebiten.StandardGamepadButton) bool { return ebiten.IsStandardGamepadButtonPressed(p.pad, b) }
+	if held(ebiten.StandardGamepadButtonLeftLeft) {
+		dx = -1
+	}
+	if held(ebiten.StandardGamepadButtonLeftRight) {
+		dx = 1
+	}
+	if held(ebiten.StandardGamepadButtonLeftTop) {
+		dy = -1
+	}
+	if held(ebiten.StandardGamepadButtonLeftBottom) {
+		dy = 1
+	}
+	step := coopCursorSpeed * dt / 1000
+	p.cursor.X = max(0, min(MapW, p.cursor.X+dx*step))
+	p.cursor.Y = max(0, min(MapH, p.cursor.Y+dy*step))
+}
+
+// coopTower is the index of player 2's tower under their cursor, or -1
+func (g *Game) coopTower(p *coopPlayer) int {
+	i := g.towerAt(p.cursor.X, p.cursor.Y)
+	if i >= 0 && g.towers[i].Owner != 1 {
+		return -1
+	}
+	return i
+}
+
+// coopBuildOrUpgrade asks player 2 a question that, answered right, upgrades
+// their tower under the cursor or builds one there
+func (g *Game) coopBuildOrUpgrade(p *coopPlayer) {
+	if i := g.towerAt(p.cursor.X, p.cursor.Y); i >= 0 {
+		tw := g.towers[i]
+		if tw.Owner != 1 {
+			p.say(tr("That's player 1's tower"))
+			return
+		}
+		g.coopAsk(p, g.level, func() { g.do(Command{Player: 1, Kind: "upgrade", X: tw.X, Y: tw.Y}) })
+		return
+	}
+	if !g.canPlaceTower(p.cursor, -1) {
+		p.say(tr("Can't build here"))
+		return
+	}
+	at, typ := p.cursor, towerTypes[p.buildType]
+	g.coopAsk(p, g.level, func() {
+		// player 1 may have built there in the meantime
+		if !g.canPlaceTower(at, -1) {
+			p.say(tr("Can't build here"))
+			return
+		}
+		g.do(Command{Player: 1, Kind: "build", Type: typ, X: at.X, Y: at.Y})
+	})
+}
+
+// coopUpgradeCost is what player 2 pays to upgrade a tower with gold
+func coopUpgradeCost(tw *Tower) int { return 40 * (1 + tw.Upgrades) }
+
+// coopBuyUpgrade spends player 2's gold on upgrading their tower under the
+// cursor; in math-gated mode a question comes first, as for player 1's shop
+func (g *Game) coopBuyUpgrade(p *coopPlayer) {
+	i := g.coopTower(p)
+	if i < 0 {
+		p.say(tr("Point at one of your towers to upgrade it"))
+		return
+	}
+	tw := g.towers[i]
+	cost := coopUpgradeCost(tw)
+	if p.gold < cost {
+		p.say(trf("Upgrading costs %d gold", cost))
+		return
+	}
+	buy := func() {
+		if p.gold >= cost {
+			p.gold -= cost
+			g.do(Command{Player: 1, Kind: "upgrade", X: tw.X, Y: tw.Y})
+		}
+	}
+	if g.mathGated {
+		g.coopAsk(p, purchaseDifficulty(cost), buy)
+		return
+	}
+	buy()
+}
+
+// coopAsk opens a question for player 2 from their own review queue
+func (g *Game) coopAsk(p *coopPlayer, level int, onCorrect func()) {
+	p.question = g.questionFrom(&p.reviews, level)
+	p.choices = answerChoices(g.qrand, p.question)
+	p.reward = onCorrect
+	p.elapsed = 0
+}
+
+// updateCoopAnswer takes player 2's pick; Back closes the question unanswered
+func (g *Game) updateCoopAnswer(p *coopPlayer) {
+	if inpututil.IsStandardGamepadButtonJustPressed(p.pad, ebiten.StandardGamepadButtonCenterLeft) {
+		p.question = nil
+		return
+	}
+	for i, b := range coopAnswerButtons {
+		if !inpututil.IsStandardGamepadButtonJustPressed(p.pad, b) {
+			continue
+		}
+		q := p.question
+		correct := p.choices[i] == q.Ans
+		p.reviews.Answered(q, correct, g.level)
+		g.answerCue(correct)
+		g.scoreAnswer(correct, p.elapsed)
+		g.waveAnswered++
+		g.wave.Answered++
+		p.question = nil
+		if !correct {
+			p.say(tr("Not quite: ") + q.Text + " = " + q.AnswerText())
+			return
+		}
+		g.wave.Correct++
+		g.do(Command{Player: 1, Kind: "shield"})
+		g.versusSend()
+		p.reward()
+		return
+	}
+}
+
+func (p *coopPlayer) say(msg string) {
+	p.msg, p.msgMS = msg, coopMsgMS
+}
+
+// answerChoices mixes q's answer with three near misses (off by 1, 2, 5 or 10
+// in the last place), in random order. Near misses only go negative when the
+// answer is.
+func answerChoices(r *rand.Rand, q *Question) [4]int {
+	offsets := []int{1, -1, 2, -2, 5, -5, 10, -10}
+	r.Shuffle(len(offsets), func(i, j int) { offsets[i], offsets[j] = offsets[j], offsets[i] })
+	var c [4]int
+	right := r.Intn(len(c))
+	c[right] = q.Ans
+	n := 0
+	for _, d := range offsets {
+		if n == len(c)-1 {
+			break
+		}
+		v := q.Ans + d
+		if v < 0 && q.Ans >= 0 {
+			continue
+		}
+		if n >= right {
+			c[n+1] = v
+		} else {
+			c[n] = v
+		}
+		n++
+	}
+	return c
+}
+
+// choiceText formats a scaled answer choice the way q's answer is shown
+func choiceText(q *Question, v int) string {
+	alt := *q
+	alt.Ans = v
+	return alt.AnswerText()
+}
+
+// earn pays gold for something src did: to player 2 for their towers, else player 1
+func (g *Game) earn(src *Tower, gold int) {
+	if g.coop != nil && src != nil && src.Owner == 1 {
+		g.coop.gold += gold
+		return
+	}
+	g.playerGold += gold
+}
+
+// drawCoopCursor draws player 2's cursor, over a ghost of the tower they would
+// build when it is on open ground
+func (g *Game) drawCoopCursor(screen *ebiten.Image) {
+	p := g.coop
+	if p == nil {
+		return
+	}
+	x, y := p.cursor.X, p.cursor.Y
+	if p.question == nil && g.towerAt(x, y) < 0 {
+		col := pal.Good
+		if !g.canPlaceTower(p.cursor, -1) {
+			col = pal.Bad
+		}
+		drawTowerGhost(screen, newTower(towerTypes[p.buildType], p.cursor), x, y, col)
+	}
+	ring(screen, x, y, 8, 2, pal.Warn)
+	line(screen, x-14, y, x-6, y, 2, pal.Warn)
+	line(screen, x+6, y, x+14, y, 2, pal.Warn)
+	line(screen, x, y-14, x, y-6, 2, pal.Warn)
+	line(screen, x, y+6, x, y+14, 2, pal.Warn)
+}
+
+// drawCoopPanel shows player 2's gold and controls, or their open question
+// with its answers by button, above the speed buttons in the bottom right
+func (g *Game) drawCoopPanel(screen *ebiten.Image) {
+	p := g.coop
+	if p == nil {
+		return
+	}
+	lines := 3
+	if p.question != nil {
+		lines = 4
+	}
+	r := g.place(AnchorBottomRight, coopPanelW, 36+float64(lines*hudHintsLineH), hudMargin)
+	r.Y -= speedBtnH + hudMargin
+	h := &g.hud
+	panel := Panel{Rect: r, Title: h.coopTitle.get(float64(p.gold), 0, 0, func() string { return trf("Player 2: %d gold", p.gold) })}
+	panel.Draw(screen)
+	x, y := panel.Content()
+	if q := p.question; q != nil {
+		drawText(screen, q.Text, x, y, pal.Text)
+		for i, v := range p.choices {
+			choice := h.coopChoices[i].get(float64(v), float64(q.Places), b2f(q.Op == "money"), func() string {
+				return fmt.Sprintf("%s: %s", coopAnswerLabels[i], choiceText(q, v))
+			})
+			drawText(screen, choice, x+(i%2)*170, y+(1+i/2)*hudHintsLineH, pal.Text)
+		}
+		drawText(screen, tr("Back: skip the question"), x, y+3*hudHintsLineH, pal.TextDim)
+		return
+	}
+	build := h.coopBuild.get(float64(p.buildType), 0, 0, func() string {
+		return trf("A: build %s or upgrade   LB/RB: tower type", tr(towerTypes[p.buildType]))
+	})
+	drawText(screen, build, x, y, pal.Text)
+	drawText(screen, tr("X: upgrade for gold   Y: emotes   Start: leave"), x, y+hudHintsLineH, pal.Text)
+	if p.msgMS > 0 {
+		drawText(screen, p.msg, x, y+2*hudHintsLineH, pal.Warn)
+	}
+}
@@ -0,0 +1,104 @@
+package datagame
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// coverageCell is the heatmap resolution in map pixels
+const coverageCell = 10
+
+// coverage caches the heatmap image; it is rebuilt only when towers move, change range or are added/sold,
+// or the theme changes
+type coverage struct {
+	img *ebiten.Image
+	sig []float64 // X, Y, Range of every tower at the last rebuild
+	pal *Palette  // theme the cells were shaded with
+	// this frame's signature, compared with sig; reused between frames
+	now []float64
+	op  ebiten.DrawImageOptions
+}
+
+// appendTowerSignature appends the X, Y and Range of every tower to sig
+func appendTowerSignature(sig []float64, towers []*Tower) []float64 {
+	for _, tw := range towers {
+		sig = append(sig, tw.X, tw.Y, tw.Range)
+	}
+	return sig
+}
+
+// rebuild counts covering towers at each cell centre and writes one pixel per cell
+func (c *coverage) rebuild(towers []*Tower) {
+	cols, rows := MapW/coverageCell, MapH/coverageCell
+	if c.img == nil {
+		c.img = ebiten.NewImage(cols, rows)
+	}
+	pix := make([]byte, cols*rows*4)
+	for cy := 0; cy < rows; cy++ {
+		for cx := 0; cx < cols; cx++ {
+			x := float64(cx*coverageCell) + coverageCell/2
+			y := float64(cy*coverageCell) + coverageCell/2
+			n := 0
+			for _, tw := range towers {
+				if math.Hypot(tw.X-x, tw.Y-y) <= tw.Range {
+					n++
+				}
+			}
+			// palette colours are already premultiplied, as WritePixels expects
+			col := pal.Coverage[min(n, len(pal.Coverage)-1)]
+			i := (cy*cols + cx) * 4
+			pix[i] = col.R
+			pix[i+1] = col.G
+			pix[i+2] = col.B
+			pix[i+3] = col.A
+		}
+	}
+	c.img.WritePixels(pix)
+	c.sig = appendTowerSignature(c.sig[:0], towers)
+	c.pal = pal
+}
+
+// drawCoverage overlays the tower coverage heatmap on the map (toggled with V)
+func (g *Game) drawCoverage(screen *ebiten.Image) {
+	c := &g.coverage
+	c.now = appendTowerSignature(c.now[:0], g.towers)
+	if c.img == nil || c.pal != pal || !slices.Equal(c.now, c.sig) {
+		c.rebuild(g.towers)
+	}
+	op := &c.op
+	op.GeoM.Reset()
+	op.GeoM.Scale(coverageCell*pixelScale, coverageCell*pixelScale)
+	screen.DrawImage(g.coverage.img, op)
+}
+
+// showRange reports whether tower i's range circle should be drawn: only when selected or hovered
+func (g *Game) showRange(i int) bool {
+	if i == g.selected {
+		return true
+	}
+	return !g.challengeActive && g.towerAt(g.toWorld(cursor())) == i
+}
+
+// drawCoverageLegend explains the heatmap colours along the bottom edge
+func (g *Game) drawCoverageLegend(screen *ebiten.Image) {
+	r := g.place(AnchorBottom, 300, 30, hudMargin)
+	Panel{Rect: r}.Draw(screen)
+	drawText(screen, tr("Towers in range:"), int(r.X)+8, int(r.Y)+20, pal.Text)
+	x := r.X + 8 + textWidth(tr("Towers in range:")) + 10
+	for n := 1; n < len(pal.Coverage); n++ {
+		// undo the premultiplied fade so the swatch shows the full colour
+		c := pal.Coverage[n]
+		c.R, c.G, c.B = uint8(uint32(c.R)*0xFF/uint32(c.A)), uint8(uint32(c.G)*0xFF/uint32(c.A)), uint8(uint32(c.B)*0xFF/uint32(c.A))
+		c.A = 0xFF
+		rect(screen, x, r.Y+9, 12, 12, c)
+		label := fmt.Sprint(n)
+		if n == len(pal.Coverage)-1 {
+			label += "+"
+		}
+		drawText(screen, label, int(x)+16, int(r.Y)+20, pal.Text)
+		x += 40
+	}
+}
@@ -0,0 +1,172 @@
+package datagame
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Crash recovery: a panic in the game is caught, written up in a crash log in
+// the crashes folder (downloaded, in a browser) with the stack trace and the
+// state of the run, and the window shows a friendly screen saying where the
+// log went instead of vanishing. From there Enter starts a new game and Esc
+// quits.
+
+// crashGuard runs the game, catching its panics
+type crashGuard struct {
+	game  ebiten.Game
+	crash *crashReport // non-nil once the game has crashed
+}
+
+// crashReport is what the crash screen says about the crash
+type crashReport struct {
+	path string // where the log went, "" if it couldn't be written
+	err  error  // why it couldn't
+}
+
+// crashDetailer is a game that can describe its state for a crash log
+type crashDetailer interface {
+	crashDetails() string
+}
+
+func (c *crashGuard) Update() error {
+	if c.crash != nil {
+		return c.updateCrash()
+	}
+	defer c.recover()
+	return c.game.Update()
+}
+
+func (c *crashGuard) Draw(screen *ebiten.Image) {
+	if c.crash != nil {
+		drawCrash(screen, c.crash)
+		return
+	}
+	defer c.recover()
+	c.game.Draw(screen)
+}
+
+func (c *crashGuard) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return c.game.Layout(outsideWidth, outsideHeight)
+}
+
+// recover catches a panic in the game, logs it and switches to the crash screen
+func (c *crashGuard) recover() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	path, err := writeCrashLog(r, debug.Stack(), c.game)
+	c.crash = &crashReport{path: path, err: err}
+}
+
+// updateCrash waits on the crash screen: Enter (or a tap) starts a new game
+// where the game allows it, Esc quits
+func (c *crashGuard) updateCrash() error {
+	touch.update()
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		return ebiten.Termination
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyKPEnter) || pointerJustReleased():
+		g, ok := c.game.(*Game)
+		if !ok {
+			return ebiten.Termination
+		}
+		g.startRun(g.nextRun())
+		c.crash = nil
+	}
+	return nil
+}
+
+// crashExit, deferred in Run, catches a panic outside the game loop (starting
+// up, or in the loop itself), logs it and exits
+func crashExit() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if path, err := writeCrashLog(r, debug.Stack(), nil); err == nil {
+		fmt.Fprintln(os.Stderr, "crash report saved to", path)
+	}
+	os.Exit(1)
+}
+
+// writeCrashLog writes up a panic: what it was, where the game was and the
+// stack. The log is also printed, for whoever started the game from a terminal.
+func writeCrashLog(r any, stack []byte, game ebiten.Game) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "DataGame crash report\n\n")
+	fmt.Fprintf(&b, "time:   %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "system: %s/%s, %s\n", runtime.GOOS, runtime.GOARCH, runtime.Version())
+	fmt.Fprintf(&b, "panic:  %v\n", r)
+	if d, ok := game.(crashDetailer); ok {
+		b.WriteString(safeCrashDetails(d))
+	}
+	fmt.Fprintf(&b, "\n%s", stack)
+	fmt.Fprint(os.Stderr, b.String())
+	return storage.Export(fmt.Sprintf("crashes/crash-%s.txt", time.Now().Format("20060102-150405")), []byte(b.String()))
+}
+
+// safeCrashDetails asks for the game's state, which after a panic may be too
+// broken to describe
+func safeCrashDetails(d crashDetailer) (s string) {
+	defer func() {
+		if r := recover(); r != nil {
+			s = fmt.Sprintf("state:  unavailable (%v)\n", r)
+		}
+	}()
+	return d.crashDetails()
+}
+
+// crashDetails describes the run for a crash log
+func (g *Game) crashDetails() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "seed:   %d\n", g.seed)
+	fmt.Fprintf(&b, "level:  %d (%s), %.0f s of game time\n", g.level, g.runMode(), g.runMS/1000)
+	fmt.Fprintf(&b, "player: %.0f HP, %d lives, %d gold\n", g.playerHP, g.playerLives, g.playerGold)
+	fmt.Fprintf(&b, "counts: %d enemies, %d towers, %d bullets\n", len(g.enemies), len(g.towers), len(g.bullets))
+	if g.daily != nil {
+		fmt.Fprintf(&b, "daily:  %s %v\n", g.daily.Date, g.daily.Modifiers)
+	}
+	var links []string
+	for _, l := range []struct {
+		name string
+		on   bool
+	}{{"versus", g.versus != nil}, {"co-op", g.lockstep != nil}, {"local co-op", g.coop != nil},
+		{"classroom", g.student != nil}, {"spectators", g.spectators != nil}, {"ghost", g.ghost != nil}} {
+		if l.on {
+			links = append(links, l.name)
+		}
+	}
+	if len(links) > 0 {
+		fmt.Fprintf(&b, "with:   %s\n", strings.Join(links, ", "))
+	}
+	return b.String()
+}
+
+// drawCrash is the screen shown after a crash, in words a child can pass on
+func drawCrash(screen *ebiten.Image, c *crashReport) {
+	screen.Fill(pal.PanelFill)
+	x, y := 40, 80
+	drawTextSize(screen, tr("Oops! The game ran into a problem"), x, y, FontHeading, pal.Bad)
+	y += 40
+	drawText(screen, tr("Sorry, something went wrong and the game had to stop."), x, y, pal.Text)
+	y += 30
+	if c.err != nil {
+		drawText(screen, tr("The crash report couldn't be saved: ")+c.err.Error(), x, y, pal.Warn)
+	} else {
+		drawText(screen, tr("A crash report was saved to:"), x, y, pal.Text)
+		y += 22
+		drawText(screen, c.path, x, y, pal.Warn)
+		y += 30
+		drawText(screen, tr("Please show this screen to a teacher or a grown-up, so they can send the report in."), x, y, pal.Text)
+	}
+	y += 40
+	drawText(screen, tr("Enter: start a new game   Esc: quit"), x, y, pal.TextDim)
+}
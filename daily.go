@@ -0,0 +1,177 @@
+package datagame
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Daily challenge (J): a run everyone plays the same way on the same day, from
+// one seed and a set of modifiers. With a score server set, today's challenge
+// comes from it, so every player worldwide gets the same one:
+//
+//	GET {server}/daily    {"date": "2026-10-17", "seed": 1234, "modifiers": ["fast", "tough"]}
+//
+// Without one, or when it can't be reached, the seed and modifiers are derived
+// from the UTC date, which gives every offline player the same run too.
+
+// dailyModifiers are the modifiers a daily run can have, in the order they are listed
+var dailyModifiers = []string{"fast", "tough", "fragile", "math-gated", "horde"}
+
+// dailyModifierNames describe each modifier, by dailyModifiers index
+var dailyModifierNames = []string{"fast enemies", "tough enemies", "half HP", "math-gated", "horde"}
+
+const (
+	dailyModifierCount = 2 // modifiers on a date-derived daily run
+	dailySpeedScale    = 1.3
+	dailyHPScale       = 1.5
+)
+
+// dailyChallenge is one day's run
+type dailyChallenge struct {
+	Date      string   `json:"date"` // UTC, YYYY-MM-DD
+	Seed      int64    `json:"seed"`
+	Modifiers []string `json:"modifiers"`
+	offline   bool     // derived from the date rather than served
+}
+
+// offlineDaily derives the challenge for the UTC date of t
+func offlineDaily(t time.Time) dailyChallenge {
+	date := t.UTC().Format(time.DateOnly)
+	h := fnv.New64a()
+	h.Write([]byte(date))
+	seed := int64(h.Sum64())
+	d := dailyChallenge{Date: date, Seed: seed, offline: true}
+	r := rand.New(rand.NewSource(seed))
+	for _, i := range r.Perm(len(dailyModifiers))[:dailyModifierCount] {
+		d.Modifiers = append(d.Modifiers, dailyModifiers[i])
+	}
+	return d
+}
+
+func (c *scoreClient) daily() (dailyChallenge, error) {
+	var d dailyChallenge
+	resp, err := c.http.Get(c.base + "/daily")
+	if err != nil {
+		return d, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return d, fmt.Errorf("score server: %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return d, err
+	}
+	if d.Date == "" {
+		return d, errors.New("score server: no date in the daily challenge")
+	}
+	return d, nil
+}
+
+// newDailyGame starts the run for d, with its modifiers on
+func newDailyGame(d dailyChallenge) *Game {
+	g := newGameSeeded(d.Seed)
+	g.daily = &d
+	g.mathGated = g.modifier("math-gated")
+	g.horde = g.modifier("horde")
+	// a horde daily is hordes from the first level, not the next
+	g.startHordeLevel()
+	if g.modifier("fragile") {
+		g.playerHP = PlayerMaxHP / 2
+	}
+	return g
+}
+
+// modifier reports whether this run is a daily run with modifier m on
+func (g *Game) modifier(m string) bool {
+	if g.daily == nil {
+		return false
+	}
+	for _, dm := range g.daily.Modifiers {
+		if dm == m {
+			return true
+		}
+	}
+	return false
+}
+
+// description lists the run's known modifiers for the player
+func (d *dailyChallenge) description() string {
+	var names []string
+	for i, m := range dailyModifiers {
+		for _, dm := range d.Modifiers {
+			if dm == m {
+				names = append(names, tr(dailyModifierNames[i]))
+			}
+		}
+	}
+	if len(names) == 0 {
+		return tr("no modifiers")
+	}
+	return strings.Join(names, ", ")
+}
+
+// confirmDaily asks before throwing away the current run for today's challenge
+func (g *Game) confirmDaily() {
+	if g.dailyFetch != nil {
+		return
+	}
+	g.ask(tr("Daily challenge"), tr("Start today's daily challenge? This run's progress will be lost."), g.fetchDaily)
+}
+
+// fetchDaily asks the score server for today's challenge in the background,
+// falling back to the date-derived one; updateDailyFetch starts it when ready
+func (g *Game) fetchDaily() {
+	c := g.scoreClient()
+	if c == nil {
+		g.startDaily(offlineDaily(time.Now()))
+		return
+	}
+	fetch := make(chan dailyChallenge, 1)
+	g.dailyFetch = fetch
+	g.levelMsg = tr("Fetching today's challenge...")
+	g.levelMsgTimer = scoreTimeout.Seconds() * 1000
+	go func() {
+		d, err := c.daily()
+		if err != nil {
+			d = offlineDaily(time.Now())
+		}
+		fetch <- d
+	}()
+}
+
+// updateDailyFetch starts the daily run once the score server has answered
+func (g *Game) updateDailyFetch() {
+	if g.dailyFetch == nil {
+		return
+	}
+	select {
+	case d := <-g.dailyFetch:
+		g.startDaily(d)
+	default:
+	}
+}
+
+func (g *Game) startDaily(d dailyChallenge) {
+	g.startRun(newDailyGame(d))
+	g.levelMsg = trf("Daily challenge for %s: %s", d.Date, d.description())
+	if d.offline {
+		g.levelMsg = trf("Daily challenge for %s (offline): %s", d.Date, d.description())
+	}
+	g.levelMsgTimer = 5000
+}
+
+// dailyLocked stops a daily run's modifiers being switched off by hand; it
+// reports true, and says so, when the run is a daily one
+func (g *Game) dailyLocked() bool {
+	if g.daily == nil {
+		return false
+	}
+	g.levelMsg = tr("The daily challenge's modes can't be changed")
+	g.levelMsgTimer = 2000
+	return true
+}
@@ -0,0 +1,152 @@
+package datagame
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// death effect timings (ms)
+const (
+	deathMS    = 350.0 // corpse shrink and fade
+	coinFlyMS  = 650.0 // from the kill to the gold counter
+	coinStagMS = 70.0  // between coins of one kill
+	maxCoins   = 6     // per kill
+	popupMS    = 900.0 // a "+N gold" rising from the kill
+	popupRise  = 24.0  // px it rises
+)
+
+// Corpse is a dead enemy's sprite left behind to shrink, spin and fade out
+type Corpse struct {
+	X, Y, R float64
+	Sprite  Sprite
+	Frame   int
+	Col     color.RGBA
+	Age     float64 // ms
+}
+
+// Coin flies in view coordinates from where an enemy died to the gold counter,
+// so it stays on course while the camera moves
+type Coin struct {
+	X, Y float64 // start
+	Age  float64 // ms, negative while waiting its turn
+}
+
+// GoldPopup shows the gold a kill was worth where it happened
+type GoldPopup struct {
+	X, Y float64 // map position
+	Gold int
+	Age  float64 // ms
+}
+
+// killEnemy starts the death effects for e: its corpse, gibs, and a "+N gold"
+// popup and coins for its bounty. A horde's rank and file only burst into gibs.
+func (g *Game) killEnemy(e *Enemy, gold int) {
+	p := e.Pos
+	if g.hordeLevel && !e.Boss {
+		g.emitDeath(e, p.X, p.Y)
+		return
+	}
+	c := Corpse{X: p.X, Y: p.Y, R: enemyRadius(e), Sprite: SpriteEnemy, Frame: e.Anim.Frame(), Col: pal.Enemy}
+	if e.Boss {
+		c.Sprite, c.Col = SpriteBoss, pal.Boss
+	}
+	g.corpses = append(g.corpses, c)
+	g.emitDeath(e, p.X, p.Y)
+	if gold > 0 {
+		g.popups = append(g.popups, GoldPopup{X: p.X, Y: p.Y - c.R, Gold: gold})
+	}
+	if g.reducedMotion() {
+		return
+	}
+
+	sx, sy := g.camera.ToScreen(p.X, p.Y, g.viewW, g.viewH)
+	for i := range min(1+gold/20, maxCoins) {
+		g.coins = append(g.coins, Coin{X: sx, Y: sy, Age: -coinStagMS * float64(i)})
+	}
+}
+
+// updateDeaths ages corpses and coins, compacting finished ones in place
+func (g *Game) updateDeaths(dt float64) {
+	corpses := g.corpses[:0]
+	for _, c := range g.corpses {
+		c.Age += dt
+		if c.Age < deathMS {
+			corpses = append(corpses, c)
+		}
+	}
+	g.corpses = corpses
+	coins := g.coins[:0]
+	for _, c := range g.coins {
+		c.Age += dt
+		if c.Age < coinFlyMS {
+			coins = append(coins, c)
+		}
+	}
+	g.coins = coins
+	popups := g.popups[:0]
+	for _, p := range g.popups {
+		p.Age += dt
+		if p.Age < popupMS {
+			popups = append(popups, p)
+		}
+	}
+	g.popups = popups
+}
+
+// drawCorpses draws dying enemies puffing up briefly, then shrinking away; with
+// reduced motion they only fade
+func (g *Game) drawCorpses(screen *ebiten.Image) {
+	for _, c := range g.corpses {
+		if !g.onScreen(c.X, c.Y, c.R*1.3) {
+			continue
+		}
+		t := c.Age / deathMS
+		if g.reducedMotion() {
+			drawSprite(screen, c.Sprite, c.Frame, c.X, c.Y, 0, c.R, fade(c.Col, uint8(0xFF*(1-t))))
+			continue
+		}
+		scale := 1 + 0.3*math.Sin(math.Min(t*3, 1)*math.Pi/2) - 1.3*t*t
+		if scale <= 0 {
+			continue
+		}
+		drawSprite(screen, c.Sprite, c.Frame, c.X, c.Y, t*math.Pi/2, c.R*scale, fade(c.Col, uint8(0xFF*(1-t))))
+	}
+}
+
+// drawGoldPopups draws each kill's "+N gold" rising and fading; with reduced
+// motion it only fades
+func (g *Game) drawGoldPopups(screen *ebiten.Image) {
+	for _, p := range g.popups {
+		if !g.onScreen(p.X, p.Y, 40) {
+			continue
+		}
+		t := p.Age / popupMS
+		y := p.Y
+		if !g.reducedMotion() {
+			y -= popupRise * math.Sin(t*math.Pi/2)
+		}
+		s := trf("+%d gold", p.Gold)
+		a := uint8(0xFF * math.Min(1, 3*(1-t)))
+		drawText(screen, s, int(p.X-textWidth(s)/2), int(y), fade(pal.Gold, a))
+	}
+}
+
+// drawCoins draws the coins arcing up and over to the gold counter
+func (g *Game) drawCoins(screen *ebiten.Image) {
+	tx, ty := g.goldCounterPos()
+	for _, c := range g.coins {
+		if c.Age < 0 {
+			continue
+		}
+		// ease in along a curve that first lifts the coin off the kill
+		t := c.Age / coinFlyMS
+		t = t * t
+		cx, cy := c.X, math.Min(c.Y, ty)-80
+		x := (1-t)*(1-t)*c.X + 2*(1-t)*t*cx + t*t*tx
+		y := (1-t)*(1-t)*c.Y + 2*(1-t)*t*cy + t*t*ty
+		disc(screen, x, y, 5, pal.GoldDark)
+		disc(screen, x, y, 3.5, pal.Gold)
+	}
+}
@@ -0,0 +1,32 @@
+package datagame
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// drawDebug shows frame rates, entity counts and the run's seed (F3), for bug reports
+func (g *Game) drawDebug(screen *ebiten.Image) {
+	lines := []string{
+		fmt.Sprintf("FPS %.1f  TPS %.1f", ebiten.ActualFPS(), ebiten.ActualTPS()),
+		fmt.Sprintf("enemies %d  bullets %d  towers %d", len(g.enemies), len(g.bullets), len(g.towers)),
+		fmt.Sprintf("level %d  speed %gx  paused %v", g.level, gameSpeeds[g.speedIdx], g.paused),
+		fmt.Sprintf("seed %d", g.seed),
+		fmt.Sprintf("view %dx%d  zoom %.2f", g.viewW, g.viewH, g.camera.Zoom),
+	}
+	if l := g.lockstep; l != nil && l.started {
+		lines = append(lines, fmt.Sprintf("lockstep tick %d  delay %d  desync %d", l.tick, lockstepDelay, l.desync))
+	}
+	w := 0.0
+	for _, l := range lines {
+		w = max(w, textWidth(l))
+	}
+	r := g.place(AnchorBottomLeft, w+16, float64(len(lines)*16+10), hudMargin)
+	// sit above the tower panel
+	r.Y -= hudTowerH + hudMargin
+	rect(screen, r.X, r.Y, r.W, r.H, fade(pal.Scrim, 0xC0))
+	for i, l := range lines {
+		drawText(screen, l, int(r.X)+8, int(r.Y)+18+i*16, pal.Debug)
+	}
+}
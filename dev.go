@@ -0,0 +1,187 @@
+package datagame
+
+import (
+	"embed"
+	"flag"
+	"math"
+	"os"
+	"slices"
+)
+
+// Dev mode (-dev) is for balancing: the game watches its data files and
+// reloads them into the running game when they change, so a new tower range
+// or spawn rate can be tried without a restart. The built-in data (the maps,
+// data/towers.json and data/waves.json) is read from the working directory,
+// normally the source checkout, in place of the copy built into the game;
+// settings.json and teacher.json are the player's own, from the game's
+// folder. A file that doesn't load is reported on screen and the last good
+// version stays in use. Reloading changes the simulation, so it isn't meant
+// for networked games.
+
+var devMode = flag.Bool("dev", false, "watch the map, tower, wave and settings files and reload them into the running game when they change")
+
+const devPollMS = 500.0 // ms between looks at the watched files
+
+// devFile is a watched file
+type devFile struct {
+	name    string
+	builtIn *embed.FS // where the game's own copy is, nil for the player's files
+	reload  func(g *Game, data []byte) error
+}
+
+// devWatcher is what -dev has seen of the watched files. Like touch, it
+// outlives runs.
+type devWatcher struct {
+	sinceMS float64
+	seen    map[string][]byte // last contents, by name
+}
+
+var dev devWatcher
+
+// devData holds the data files -dev has loaded from disk, by name, for
+// readData to prefer to the built-in copies
+var devData = map[string][]byte{}
+
+// devFiles lists the watched files
+func devFiles() []devFile {
+	files := []devFile{
+		{"data/towers.json", &dataFS, (*Game).reloadTowers},
+		{"data/waves.json", &dataFS, (*Game).reloadWaves},
+		{"settings.json", nil, (*Game).reloadSettings},
+		{"teacher.json", nil, (*Game).reloadTeacher},
+	}
+	for _, name := range mapNames() {
+		reload := func(g *Game, data []byte) error { return g.reloadMap(name, data) }
+		files = append(files, devFile{"maps/" + name + ".json", &mapFS, reload})
+	}
+	return files
+}
+
+// updateDev looks for changed files every devPollMS and reloads them
+func (g *Game) updateDev(dt float64) {
+	if !*devMode {
+		return
+	}
+	d := &dev
+	if d.sinceMS += dt; d.sinceMS < devPollMS {
+		return
+	}
+	d.sinceMS = 0
+	if d.seen == nil {
+		d.seen = map[string][]byte{}
+	}
+	for _, f := range devFiles() {
+		var data []byte
+		var err error
+		if f.builtIn != nil {
+			data, err = os.ReadFile(f.name)
+		} else {
+			data, err = storage.ReadFile(f.name)
+		}
+		if err != nil {
+			continue // nothing to watch
+		}
+		seen, ok := d.seen[f.name]
+		if !ok {
+			// the game started on the built-in copy, or the player's file as it is now
+			seen = data
+			if f.builtIn != nil {
+				seen, _ = readData(*f.builtIn, f.name)
+			}
+		}
+		d.seen[f.name] = data
+		if string(seen) == string(data) {
+			continue
+		}
+		if err := f.reload(g, data); err != nil {
+			g.levelMsg = trf("Couldn't reload %s: %v", f.name, err)
+			g.levelMsgTimer = 5000
+			continue
+		}
+		if f.builtIn != nil {
+			devData[f.name] = data
+		}
+		g.levelMsg = trf("Reloaded %s", f.name)
+		g.levelMsgTimer = 2000
+	}
+}
+
+// reloadTowers takes new tower stats, moving the towers already built by the
+// change to their type's stats so their upgrades are kept
+func (g *Game) reloadTowers(data []byte) error {
+	stats, err := parseTowerStats(data)
+	if err != nil {
+		return err
+	}
+	for _, tw := range g.towers {
+		old, now := towerStats[tw.Type], stats[tw.Type]
+		if now.Range == 0 {
+			continue // a custom type taken out; its towers stay as they are
+		}
+		tw.Range = math.Max(1, tw.Range+now.Range-old.Range)
+		tw.Damage = math.Max(0, tw.Damage+now.Damage-old.Damage)
+		tw.Fire = math.Max(1, tw.Fire+now.Fire-old.Fire)
+		tw.FlameDuration += now.FlameDuration - old.FlameDuration
+		tw.PulseDuration += now.PulseDuration - old.PulseDuration
+	}
+	setTowerStats(stats)
+	g.buildType = min(g.buildType, len(towerTypes)-1)
+	if g.coop != nil {
+		g.coop.buildType = min(g.coop.buildType, len(towerTypes)-1)
+	}
+	return nil
+}
+
+// reloadWaves takes new wave tuning, which applies from the next enemy spawned
+// and the next level's rolls
+func (g *Game) reloadWaves(data []byte) error {
+	w, err := parseWaveTuning(data)
+	if err != nil {
+		return err
+	}
+	// a wave set from the content folder stays in use over it
+	builtinWaves = w
+	useWaveSet(g.settings.ContentWaves)
+	g.spawnInt = max(g.spawnInt, waveTuning.SpawnIntervalMin)
+	return nil
+}
+
+// reloadMap takes a new version of a map, redrawing it if it is the one being
+// played. The road moves too while it still follows the map's own path, which
+// it does until the first new level rolls a path of its own.
+func (g *Game) reloadMap(name string, data []byte) error {
+	m, err := parseMap(name, data)
+	if err != nil {
+		return err
+	}
+	if g.mapDef.File != name {
+		return nil
+	}
+	if slices.Equal(g.path, g.mapDef.Waypoints()) {
+		g.setPath(m.Waypoints())
+	}
+	g.mapDef = m
+	g.mapAmbience = m.Ambience()
+	g.background.pal = nil // redraw
+	return nil
+}
+
+// reloadSettings rereads settings.json, edited by hand or by another copy of
+// the game
+func (g *Game) reloadSettings([]byte) error {
+	g.settings = loadSettings()
+	launchSettings(g.settings)
+	setLanguage(g.settings.Language)
+	setTheme(g.settings.Theme)
+	applyTickRate(g.settings)
+	if g.lockstep == nil {
+		useWaveSet(g.settings.ContentWaves)
+	}
+	return nil
+}
+
+// reloadTeacher rereads teacher.json
+func (g *Game) reloadTeacher([]byte) error {
+	g.teacher = loadTeacherConfig()
+	return nil
+}
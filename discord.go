@@ -0,0 +1,181 @@
+package datagame
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+)
+
+// Discord status: with "Discord status" on in settings, the game shows what
+// it is playing (the mode, the level and how far the wave has got) on the
+// player's Discord profile, through the local Discord app's IPC socket. It is
+// off by default, and needs the game's Discord application ID, set when the
+// game is built:
+//
+//	go build -ldflags "-X datagame.discordAppID=<id>" ./cmd/datagame
+//
+// Everything happens on a goroutine that gives up quietly: with Discord
+// closed, not installed, or no network, the game plays exactly the same, and
+// tries again every discordEveryMS.
+
+// discordAppID is the game's Discord application, "" for none
+var discordAppID = ""
+
+const discordEveryMS = 15000.0 // between updates; Discord allows 5 every 20s
+
+// discordActivity is what the profile shows
+type discordActivity struct {
+	Details    string `json:"details"`
+	State      string `json:"state"`
+	Timestamps struct {
+		Start int64 `json:"start"` // unix seconds; Discord counts up from it
+	} `json:"timestamps"`
+}
+
+// discordLink sends activities to Discord. A nil activity clears the status.
+type discordLink struct {
+	on      bool
+	sinceMS float64
+	out     chan *discordActivity
+}
+
+var discord discordLink
+
+// updateDiscord sends the status every discordEveryMS while it is on, and
+// clears it when it is turned off
+func (g *Game) updateDiscord(dt float64) {
+	d := &discord
+	on := g.settings.DiscordStatus && discordAppID != ""
+	if !on {
+		if d.on {
+			d.on = false
+			d.send(nil)
+		}
+		return
+	}
+	if d.out == nil {
+		d.out = make(chan *discordActivity, 1)
+		go d.run()
+	}
+	if d.sinceMS += dt; d.on && d.sinceMS < discordEveryMS {
+		return
+	}
+	d.on, d.sinceMS = true, 0
+	d.send(g.discordActivity())
+}
+
+// send hands a to the goroutine, replacing any activity it hasn't got to yet
+func (d *discordLink) send(a *discordActivity) {
+	if d.out == nil {
+		return
+	}
+	select {
+	case <-d.out:
+	default:
+	}
+	d.out <- a
+}
+
+// discordActivity describes the run for the profile
+func (g *Game) discordActivity() *discordActivity {
+	a := &discordActivity{}
+	mode := tr(scoreModeNames[slices.Index(scoreModes, g.runMode())])
+	switch {
+	case g.versus != nil:
+		mode = tr("Versus")
+	case g.lockstep != nil, g.coop != nil:
+		mode = tr("Co-op")
+	}
+	a.Details = fmt.Sprintf("%s - %s", mode, trf("Level %d", g.level))
+	switch {
+	case g.gameOver:
+		a.State = tr("Game over")
+	case g.paused:
+		a.State = tr("Paused")
+	default:
+		a.State = trf("Wave: %d/%d kills", g.killCount, g.nextLevelThreshold)
+	}
+	a.Timestamps.Start = g.sessionStart.Unix()
+	return a
+}
+
+// run connects to Discord when there is something to send, and drops the
+// connection on any error, to try again with the next activity
+func (d *discordLink) run() {
+	var conn io.ReadWriteCloser
+	for a := range d.out {
+		if conn == nil && a == nil {
+			continue
+		}
+		if conn == nil {
+			var err error
+			if conn, err = discordConnect(); err != nil {
+				conn = nil
+				continue
+			}
+		}
+		args := map[string]any{"pid": os.Getpid(), "activity": a}
+		err := discordWrite(conn, 1, map[string]any{"cmd": "SET_ACTIVITY", "args": args, "nonce": fmt.Sprint(time.Now().UnixNano())})
+		if err == nil {
+			// read Discord's reply before writing again, so the socket never
+			// fills; a pending read would block writes on a Windows pipe
+			_, _, err = discordRead(conn)
+		}
+		if err != nil || a == nil {
+			conn.Close()
+			conn = nil
+		}
+	}
+}
+
+// discordConnect opens the IPC connection and says hello
+func discordConnect() (io.ReadWriteCloser, error) {
+	conn, err := discordDial()
+	if err != nil {
+		return nil, err
+	}
+	if err := discordWrite(conn, 0, map[string]any{"v": 1, "client_id": discordAppID}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// Discord answers with READY, or an error and a hang-up
+	if op, _, err := discordRead(conn); err != nil || op != 1 {
+		conn.Close()
+		return nil, errors.New("discord: handshake refused")
+	}
+	return conn, nil
+}
+
+// discordWrite sends one frame: the opcode and the payload's length, little
+// endian, then the JSON payload
+func discordWrite(w io.Writer, op uint32, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	frame := make([]byte, 8, 8+len(data))
+	binary.LittleEndian.PutUint32(frame[0:], op)
+	binary.LittleEndian.PutUint32(frame[4:], uint32(len(data)))
+	_, err = w.Write(append(frame, data...))
+	return err
+}
+
+// discordRead reads one frame
+func discordRead(r io.Reader) (uint32, []byte, error) {
+	var head [8]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.LittleEndian.Uint32(head[4:])
+	if n > 1<<20 {
+		return 0, nil, errors.New("discord: frame too big")
+	}
+	data := make([]byte, n)
+	_, err := io.ReadFull(r, data)
+	return binary.LittleEndian.Uint32(head[:4]), data, err
+}
@@ -0,0 +1,11 @@
+package datagame
+
+import (
+	"errors"
+	"io"
+)
+
+// discordDial fails in a browser, which can't reach the Discord app
+func discordDial() (io.ReadWriteCloser, error) {
+	return nil, errors.New("discord: not available in a browser")
+}
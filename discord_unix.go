@@ -0,0 +1,33 @@
+//go:build !windows && !js
+
+package datagame
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// discordDial finds the Discord app's socket, discord-ipc-0 to -9 in the
+// runtime or temp directory
+func discordDial() (io.ReadWriteCloser, error) {
+	dir := os.TempDir()
+	for _, env := range []string{"XDG_RUNTIME_DIR", "TMPDIR", "TMP", "TEMP"} {
+		if v := os.Getenv(env); v != "" {
+			dir = v
+			break
+		}
+	}
+	var err error
+	for i := range 10 {
+		var conn net.Conn
+		conn, err = net.DialTimeout("unix", filepath.Join(dir, "discord-ipc-"+strconv.Itoa(i)), time.Second)
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
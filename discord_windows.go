@@ -0,0 +1,20 @@
+package datagame
+
+import (
+	"io"
+	"os"
+	"strconv"
+)
+
+// discordDial opens the Discord app's named pipe, discord-ipc-0 to -9
+func discordDial() (io.ReadWriteCloser, error) {
+	var err error
+	for i := range 10 {
+		var f *os.File
+		f, err = os.OpenFile(`\\.\pipe\discord-ipc-`+strconv.Itoa(i), os.O_RDWR, 0)
+		if err == nil {
+			return f, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,127 @@
+package datagame
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// DrillDurationMS is the length of one practice round
+const DrillDurationMS = 60000.0
+
+// Drill is a timed practice round: rapid-fire questions, no tower defense
+type Drill struct {
+	Level      int     // question difficulty passed to genQuestion
+	TimeLeft   float64 // ms
+	Asked      int
+	Correct    int
+	Streak     int
+	BestStreak int
+	TotalMS    float64 // summed response time of answered questions
+	Done       bool
+	lastResult string // feedback for the previous answer
+}
+
+// startDrill begins a practice round at the current game level
+func (g *Game) startDrill() {
+	g.closeChallenge()
+	g.shopActive = false
+	g.reportActive = false
+	g.drill = &Drill{Level: g.level, TimeLeft: DrillDurationMS}
+	g.nextDrillQuestion()
+}
+
+func (g *Game) nextDrillQuestion() {
+	level := g.questionLevel(g.drill.Level)
+	g.question = g.focusFacts(g.filterTopic(genQuestion(g.qrand, level), level))
+	g.inputBuf = ""
+	g.challengeElapsed = 0
+	g.readQuestion()
+}
+
+func (g *Game) updateDrill(dt float64) {
+	d := g.drill
+	if d.Done {
+		// summary screen: Enter restarts, Escape returns to the game
+		if inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyKPEnter) {
+			g.drill = &Drill{Level: d.Level, TimeLeft: DrillDurationMS}
+			g.nextDrillQuestion()
+		} else if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			g.drill = nil
+			g.question = nil
+			g.inputBuf = ""
+		}
+		return
+	}
+	// Up/Down adjust difficulty between questions
+	if inpututil.IsKeyJustPressed(ebiten.KeyUp) {
+		d.Level++
+		g.nextDrillQuestion()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyDown) && d.Level > 1 {
+		d.Level--
+		g.nextDrillQuestion()
+	}
+	d.TimeLeft -= dt
+	g.challengeElapsed += dt
+	submitted, cancelled := g.readAnswerInput()
+	if cancelled || d.TimeLeft <= 0 {
+		d.TimeLeft = math.Max(0, d.TimeLeft)
+		d.Done = true
+		return
+	}
+	if submitted && g.inputBuf != "" {
+		d.Asked++
+		d.TotalMS += g.challengeElapsed
+		if g.checkAnswer() {
+			d.Correct++
+			d.Streak++
+			if d.Streak > d.BestStreak {
+				d.BestStreak = d.Streak
+			}
+			d.lastResult = tr("Correct!")
+		} else {
+			d.Streak = 0
+			d.lastResult = fmt.Sprintf("%s = %s", g.question.Text, g.question.AnswerText())
+		}
+		g.nextDrillQuestion()
+	}
+}
+
+func (g *Game) drawDrill(screen *ebiten.Image) {
+	d := g.drill
+	r := g.drillRect()
+	x0, y0 := int(r.X), int(r.Y)
+	rect(screen, r.X, r.Y, r.W, r.H, fade(pal.Scrim, 0xC0))
+	if d.Done {
+		drawText(screen, tr("Practice complete!"), x0+20, y0+30, pal.Text)
+		acc := 0.0
+		avg := 0.0
+		if d.Asked > 0 {
+			acc = float64(d.Correct) / float64(d.Asked) * 100
+			avg = d.TotalMS / float64(d.Asked) / 1000.0
+		}
+		drawText(screen, trf("Answered: %d   Correct: %d   Accuracy: %.0f%%", d.Asked, d.Correct, acc), x0+20, y0+70, pal.Text)
+		drawText(screen, trf("Best streak: %d   Avg time: %.1fs", d.BestStreak, avg), x0+20, y0+100, pal.Text)
+		drawText(screen, trf("Difficulty: %d", d.Level), x0+20, y0+130, pal.Text)
+		drawText(screen, tr("Enter to go again, Esc to return to the game"), x0+20, y0+200, pal.Text)
+		return
+	}
+	drawText(screen, trf("PRACTICE  Time: %.0fs  Difficulty: %d (Up/Down)", math.Ceil(d.TimeLeft/1000.0), d.Level), x0+20, y0+30, pal.Text)
+	drawText(screen, trf("Score: %d/%d   Streak: %d   Best: %d", d.Correct, d.Asked, d.Streak, d.BestStreak), x0+20, y0+55, pal.Text)
+	// timer bar
+	rect(screen, float64(x0+20), float64(y0+70), r.W-40, 6, pal.TrackLight)
+	if bw := (r.W - 40) * d.TimeLeft / DrillDurationMS; bw >= 1 {
+		rect(screen, float64(x0+20), float64(y0+70), bw, 6, pal.Good)
+	}
+	drawText(screen, tr("Solve:"), x0+20, y0+110, pal.Text)
+	drawText(screen, g.question.Text, x0+20, y0+135, pal.Text)
+	drawText(screen, tr("Answer: ")+g.inputBuf, x0+20, y0+165, pal.Text)
+	if d.lastResult != "" {
+		drawText(screen, d.lastResult, x0+20, y0+195, pal.Warn)
+	}
+	drawText(screen, tr("Enter to submit, Esc to finish"), x0+20, y0+225, pal.Text)
+	g.drawKeypad(screen)
+}
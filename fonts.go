@@ -0,0 +1,67 @@
+package datagame
+
+import (
+	"bytes"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// FontSize selects one of the text size tiers
+type FontSize int
+
+const (
+	FontHUD     FontSize = iota // body text, HUD and menus
+	FontHeading                 // panel and overlay titles
+	FontBig                     // countdowns and end-of-run banners
+)
+
+// fontPx is the pixel size of each tier; FontHUD matches the old 7x13 bitmap
+// font closely enough that fixed layouts keep lining up
+var fontPx = [...]float64{13, 18, 44}
+
+// faces holds one face per tier, built from the bundled Go fonts in init
+var faces [len(fontPx)]*text.GoTextFace
+
+func init() {
+	regular, err := text.NewGoTextFaceSource(bytes.NewReader(goregular.TTF))
+	if err != nil {
+		panic(err)
+	}
+	bold, err := text.NewGoTextFaceSource(bytes.NewReader(gobold.TTF))
+	if err != nil {
+		panic(err)
+	}
+	faces[FontHUD] = &text.GoTextFace{Source: regular, Size: fontPx[FontHUD]}
+	faces[FontHeading] = &text.GoTextFace{Source: bold, Size: fontPx[FontHeading]}
+	faces[FontBig] = &text.GoTextFace{Source: bold, Size: fontPx[FontBig]}
+}
+
+// drawText draws s in the HUD size with its baseline at y
+func drawText(img *ebiten.Image, s string, x, y int, col color.Color) {
+	drawTextSize(img, s, x, y, FontHUD, col)
+}
+
+// textOp is reused by drawTextSize so drawing text doesn't allocate options
+var textOp text.DrawOptions
+
+// drawTextSize draws s in the given size tier with its baseline at y
+func drawTextSize(img *ebiten.Image, s string, x, y int, size FontSize, col color.Color) {
+	f := faces[size]
+	op := &textOp
+	op.GeoM.Reset()
+	op.ColorScale.Reset()
+	op.GeoM.Translate(float64(x)*pixelScale, float64(y)*pixelScale-f.Metrics().HAscent)
+	op.ColorScale.ScaleWithColor(col)
+	text.Draw(img, s, f, op)
+}
+
+// textWidth measures s in the HUD size
+func textWidth(s string) float64 { return textWidthSize(s, FontHUD) }
+
+func textWidthSize(s string, size FontSize) float64 {
+	return text.Advance(s, faces[size]) / pixelScale
+}
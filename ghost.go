@@ -0,0 +1,209 @@
+package datagame
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Ghost racing: every finished run saves a replay of where and when towers
+// were built and when each level was reached. Starting the game with -ghost
+// and another player's replay (a file, or a URL to download it from) plays the
+// same seed, and daily modifiers if any, with their towers appearing as
+// translucent ghosts at the game time they built them, and their progress
+// shown next to yours.
+
+var ghostFrom = flag.String("ghost", "", "race the replay in this file or at this URL, on the same seed")
+
+const (
+	replayVersion = 1
+	ghostAlpha    = 0x60
+)
+
+// Replay is a finished run as saved for ghost racing. Times are game time in
+// ms since the run started, so speed changes and pauses don't skew them.
+type Replay struct {
+	Version int             `json:"version"`
+	Name    string          `json:"name"`
+	Date    time.Time       `json:"date"`
+	Seed    int64           `json:"seed"`
+	Daily   *dailyChallenge `json:"daily,omitempty"`
+	Towers  []ReplayTower   `json:"towers"` // in build order
+	Levels  []float64       `json:"levels"` // when each level from 2 on was reached
+	Level   int             `json:"level"`  // level the run ended on
+	Score   int             `json:"score"`
+	EndMS   float64         `json:"end_ms"`
+}
+
+// ReplayTower is one tower built during a run
+type ReplayTower struct {
+	MS    float64 `json:"ms"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Type  string  `json:"type"`
+	Owner int     `json:"owner,omitempty"`
+}
+
+// recordBuild adds a newly built tower to this run's replay
+func (g *Game) recordBuild(tw *Tower) {
+	g.replay.Towers = append(g.replay.Towers, ReplayTower{MS: g.runMS, X: tw.X, Y: tw.Y, Type: tw.Type, Owner: tw.Owner})
+}
+
+// saveReplay files the finished run in the replays folder, named by date and
+// seed, and returns its name
+func (g *Game) saveReplay() (string, error) {
+	r := g.replay
+	r.Version, r.Date, r.Seed, r.Daily = replayVersion, time.Now().UTC(), g.seed, g.daily
+	r.Name = g.settings.PlayerName
+	if r.Name == "" {
+		r.Name = "Player"
+	}
+	r.Level, r.Score, r.EndMS = g.level, g.runScore(), g.runMS
+	name := fmt.Sprintf("replays/run-%s-seed%d.json", time.Now().Format("20060102-150405"), g.seed)
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return name, storage.WriteFile(name, data, 0o644)
+}
+
+// loadReplay reads a replay from a file, or downloads it from an http(s) URL
+func loadReplay(from string) (*Replay, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(from, "http://") || strings.HasPrefix(from, "https://") {
+		data, err = downloadReplay(from)
+	} else {
+		data, err = os.ReadFile(from)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &Replay{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	if r.Version != replayVersion {
+		return nil, errors.New(tr("the replay is from a different version of the game"))
+	}
+	return r, nil
+}
+
+func downloadReplay(url string) ([]byte, error) {
+	c := &http.Client{Timeout: scoreTimeout}
+	resp, err := c.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// newGhostGame starts a run on the replay's seed, and its daily modifiers if
+// it was a daily run, racing its ghost
+func newGhostGame(r *Replay) *Game {
+	var g *Game
+	if r.Daily != nil {
+		g = newDailyGame(*r.Daily)
+	} else {
+		g = newGameSeeded(r.Seed)
+	}
+	g.ghost = r
+	return g
+}
+
+// nextRun is the run a restart starts: the same ghost race again while one is
+// loaded, else a fresh run
+func (g *Game) nextRun() *Game {
+	if g.ghost != nil {
+		return newGhostGame(g.ghost)
+	}
+	return NewGame()
+}
+
+// startGhostRace loads the -ghost replay and starts racing it
+func (g *Game) startGhostRace(from string) {
+	r, err := loadReplay(from)
+	if err != nil {
+		g.levelMsg = tr("Couldn't load the ghost: ") + err.Error()
+		g.levelMsgTimer = 5000
+		return
+	}
+	g.startRun(newGhostGame(r))
+	g.levelMsg = trf("Racing %s's ghost: they reached level %d", r.Name, r.Level)
+	g.levelMsgTimer = 4000
+}
+
+// ghostLevel is the level the ghost had reached by this point of the run
+func (g *Game) ghostLevel() int {
+	level := 1
+	for _, ms := range g.ghost.Levels {
+		if ms > g.runMS {
+			break
+		}
+		level++
+	}
+	return level
+}
+
+// drawGhostTowers draws the ghost's towers built so far, see-through
+func (g *Game) drawGhostTowers(screen *ebiten.Image) {
+	if g.ghost == nil {
+		return
+	}
+	for _, t := range g.ghost.Towers {
+		if t.MS > g.runMS {
+			break
+		}
+		if !g.onScreen(t.X, t.Y, 14) {
+			continue
+		}
+		turret, tint := turretArt(t.Type)
+		drawSprite(screen, SpriteTowerBase, 0, t.X, t.Y, 0, 14, fade(pal.Tower, ghostAlpha))
+		drawSprite(screen, turret, 0, t.X, t.Y, 0, 14, fade(tint, ghostAlpha))
+	}
+}
+
+// drawGhost compares the ghost's progress with yours, under the wave panel
+func (g *Game) drawGhost(screen *ebiten.Image) {
+	r := g.ghost
+	if r == nil {
+		return
+	}
+	pr := g.wavePanelRect()
+	pr.Y += pr.H + hudMargin
+	pr.H = 44
+	p := Panel{Rect: pr, Title: g.hud.ghostTitle.get(0, 0, 0, func() string { return trf("%s's ghost", r.Name) })}
+	p.Draw(screen)
+	x, y := p.Content()
+	ended := g.runMS >= r.EndMS
+	level, col := g.ghostLevel(), pal.Text
+	text := g.hud.ghostLevel.get(float64(level), float64(g.level), b2f(ended), func() string {
+		if ended {
+			return trf("Their run ended on level %d", r.Level)
+		}
+		return trf("Level %d (you: %d)", level, g.level)
+	})
+	if ended {
+		drawText(screen, text, x, y, pal.TextDim)
+		return
+	}
+	switch {
+	case level > g.level:
+		col = pal.Bad
+	case level < g.level:
+		col = pal.Good
+	}
+	drawText(screen, text, x, y, col)
+}
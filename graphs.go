@@ -0,0 +1,105 @@
+package datagame
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// runGoldSampleMS is how often gold is recorded for the end-screen graph (game time)
+const runGoldSampleMS = 5000.0
+
+// runGraphH is the height of the graph strip on the end screen
+const runGraphH = 130.0
+
+// sampleGold records the player's gold every runGoldSampleMS of simulated time
+func (g *Game) sampleGold(sim float64) {
+	g.goldSampleMS += sim
+	if len(g.goldHistory) == 0 || g.goldSampleMS >= runGoldSampleMS {
+		g.goldSampleMS = 0
+		g.goldHistory = append(g.goldHistory, g.playerGold)
+	}
+}
+
+// drawRunGraphs draws three line charts side by side in r: gold over time,
+// leaks per level and answer accuracy per level
+func (g *Game) drawRunGraphs(screen *ebiten.Image, r Rect) {
+	gold := make([]float64, len(g.goldHistory))
+	for i, v := range g.goldHistory {
+		gold[i] = float64(v)
+	}
+	leaks := make([]float64, len(g.waves))
+	acc := make([]float64, len(g.waves))
+	for i, w := range g.waves {
+		leaks[i] = float64(w.Leaks)
+		// levels without answers leave a gap in the accuracy line
+		acc[i] = math.NaN()
+		if w.Answered > 0 {
+			acc[i] = 100 * float64(w.Correct) / float64(w.Answered)
+		}
+	}
+	w := (r.W - 20) / 3
+	lineChart(screen, Rect{r.X, r.Y, w, r.H}, tr("Gold over time"), gold, "%.0f", pal.Gold)
+	lineChart(screen, Rect{r.X + w + 10, r.Y, w, r.H}, tr("Leaks per level"), leaks, "%.0f", pal.Bad)
+	lineChart(screen, Rect{r.X + 2*(w+10), r.Y, w, r.H}, tr("Accuracy per level"), acc, "%.0f%%", pal.Good)
+}
+
+// lineChart plots values left to right in a titled box, scaled from 0 to the
+// largest value; NaN values break the line
+func lineChart(screen *ebiten.Image, r Rect, title string, values []float64, format string, col color.RGBA) {
+	p := Panel{Rect: r}
+	p.Draw(screen)
+	drawText(screen, title, int(r.X)+8, int(r.Y)+16, pal.PanelTitle)
+	plot := Rect{r.X + 8, r.Y + 26, r.W - 16, r.H - 34}
+	top := 0.0
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			top = math.Max(top, v)
+		}
+	}
+	if top == 0 && !hasValue(values) {
+		drawText(screen, tr("No data"), int(plot.X), int(plot.Y+plot.H/2), pal.TextDim)
+		return
+	}
+	if top == 0 {
+		top = 1
+	}
+	// axes, with the largest value labelled at the top
+	line(screen, plot.X, plot.Y, plot.X, plot.Y+plot.H, 1, pal.TextDim)
+	line(screen, plot.X, plot.Y+plot.H, plot.X+plot.W, plot.Y+plot.H, 1, pal.TextDim)
+	label := fmt.Sprintf(format, top)
+	drawText(screen, label, int(plot.X+plot.W-textWidth(label)), int(plot.Y)+10, pal.TextDim)
+
+	pt := func(i int) (float64, float64) {
+		x := plot.X
+		if len(values) > 1 {
+			x += plot.W * float64(i) / float64(len(values)-1)
+		}
+		return x, plot.Y + plot.H - plot.H*values[i]/top
+	}
+	for i, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		x, y := pt(i)
+		if i > 0 && !math.IsNaN(values[i-1]) {
+			px, py := pt(i - 1)
+			line(screen, px, py, x, y, 2, col)
+		}
+		// mark points while there are few enough to tell apart
+		if len(values) <= 30 {
+			disc(screen, x, y, 2.5, col)
+		}
+	}
+}
+
+func hasValue(values []float64) bool {
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			return true
+		}
+	}
+	return false
+}
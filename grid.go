@@ -0,0 +1,71 @@
+package datagame
+
+import "math"
+
+// gridCell is the side of a spatial grid cell in map px; about half a typical
+// tower range, so a range query touches a handful of cells
+const gridCell = 64
+
+const (
+	gridCols = MapW/gridCell + 1
+	gridRows = MapH/gridCell + 1
+)
+
+// enemyGrid buckets enemies by their cached position (Enemy.Pos) so range
+// queries only look at nearby enemies. It is rebuilt every tick after the
+// enemies move; the cell slices are reused, so rebuilding doesn't allocate
+// once they have grown.
+type enemyGrid struct {
+	cells [gridCols * gridRows][]*Enemy
+}
+
+// cellOf returns the column and row holding a map position, clamped to the grid
+func cellOf(x, y float64) (int, int) {
+	return clampInt(int(x)/gridCell, 0, gridCols-1), clampInt(int(y)/gridCell, 0, gridRows-1)
+}
+
+// rebuild empties the grid and files each enemy under its position
+func (gr *enemyGrid) rebuild(enemies []*Enemy) {
+	for i := range gr.cells {
+		gr.cells[i] = gr.cells[i][:0]
+	}
+	for _, e := range enemies {
+		c, r := cellOf(e.Pos.X, e.Pos.Y)
+		gr.cells[r*gridCols+c] = append(gr.cells[r*gridCols+c], e)
+	}
+}
+
+// Near appends to dst the enemies within radius of x,y
+func (gr *enemyGrid) Near(dst []*Enemy, x, y, radius float64) []*Enemy {
+	c0, r0 := cellOf(x-radius, y-radius)
+	c1, r1 := cellOf(x+radius, y+radius)
+	for r := r0; r <= r1; r++ {
+		for c := c0; c <= c1; c++ {
+			for _, e := range gr.cells[r*gridCols+c] {
+				if math.Hypot(e.Pos.X-x, e.Pos.Y-y) <= radius {
+					dst = append(dst, e)
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// Nearest returns the enemy closest to x,y within radius and its distance,
+// or nil
+func (gr *enemyGrid) Nearest(x, y, radius float64) (*Enemy, float64) {
+	var best *Enemy
+	bestD := math.Inf(1)
+	c0, r0 := cellOf(x-radius, y-radius)
+	c1, r1 := cellOf(x+radius, y+radius)
+	for r := r0; r <= r1; r++ {
+		for c := c0; c <= c1; c++ {
+			for _, e := range gr.cells[r*gridCols+c] {
+				if d := math.Hypot(e.Pos.X-x, e.Pos.Y-y); d <= radius && d < bestD {
+					best, bestD = e, d
+				}
+			}
+		}
+	}
+	return best, bestD
+}
@@ -0,0 +1,51 @@
+package datagame
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// pixelScale is how many screen pixels make up one view pixel. Layout sizes the
+// screen in device pixels, so on high-DPI displays (and in windows larger than
+// the view) text and shapes are drawn at full resolution instead of being
+// scaled up from the view. Game code works in view pixels throughout; the
+// drawing helpers, cursor() and the offscreen images convert.
+var pixelScale = 1.0
+
+// setPixelScale changes the view-to-screen scale, resizing the fonts to match
+func setPixelScale(s float64) {
+	if s == pixelScale {
+		return
+	}
+	pixelScale = s
+	for i, f := range faces {
+		f.Size = fontPx[i] * s
+	}
+}
+
+// deviceScale is the monitor's device scale factor (2 on most retina displays)
+func deviceScale() float64 {
+	if m := ebiten.Monitor(); m != nil {
+		return m.DeviceScaleFactor()
+	}
+	return 1
+}
+
+// scaledSize is a w x h view-pixel area in screen pixels, for offscreen images
+func scaledSize(w, h float64) (int, int) {
+	return int(math.Ceil(w * pixelScale)), int(math.Ceil(h * pixelScale))
+}
+
+// scaledImage returns img if it already has the screen-pixel size for a w x h
+// view area, or a new image that does
+func scaledImage(img *ebiten.Image, w, h float64) *ebiten.Image {
+	sw, sh := scaledSize(w, h)
+	if img != nil && img.Bounds().Dx() == sw && img.Bounds().Dy() == sh {
+		return img
+	}
+	if img != nil {
+		img.Deallocate()
+	}
+	return ebiten.NewImage(sw, sh)
+}
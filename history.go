@@ -0,0 +1,148 @@
+package datagame
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// HistoryEntry is one answered question in the current run
+type HistoryEntry struct {
+	Level   int     `json:"level"`
+	Text    string  `json:"text"`
+	Given   string  `json:"given"`
+	Answer  string  `json:"answer"`
+	Correct bool    `json:"correct"`
+	MS      float64 `json:"ms"`
+}
+
+const historyLineH = 18
+
+// recordHistory appends the current question and input to the run log
+func (g *Game) recordHistory(correct bool) {
+	g.history = append(g.history, HistoryEntry{
+		Level:   g.level,
+		Text:    g.question.Text,
+		Given:   g.inputBuf,
+		Answer:  g.question.AnswerText(),
+		Correct: correct,
+		MS:      g.challengeElapsed,
+	})
+}
+
+// scrollHistory moves the log view with the mouse wheel or PageUp/PageDown
+func (g *Game) scrollHistory(visible int) {
+	scrollLog(&g.historyScroll, len(g.history), visible)
+}
+
+// scrollLog moves *scroll through a log of n lines with the mouse wheel or
+// PageUp/PageDown, keeping visible lines on screen
+func scrollLog(scroll *int, n, visible int) {
+	_, wy := ebiten.Wheel()
+	if wy > 0 || inpututil.IsKeyJustPressed(ebiten.KeyPageUp) {
+		*scroll -= 3
+	}
+	if wy < 0 || inpututil.IsKeyJustPressed(ebiten.KeyPageDown) {
+		*scroll += 3
+	}
+	*scroll = max(0, min(*scroll, n-visible))
+}
+
+// historyRows is how many log lines fit in a box of height h
+func historyRows(h float64) int { return int(h-50) / historyLineH }
+
+// drawHistory renders the run log inside the given box, newest last
+func (g *Game) drawHistory(screen *ebiten.Image, x0, y0 int, w, h float64, title string) {
+	drawLog(screen, g.history, g.historyScroll, x0, y0, w, h, title)
+}
+
+// drawLog renders a question log from line scroll inside the given box
+func drawLog(screen *ebiten.Image, log []HistoryEntry, scroll, x0, y0 int, w, h float64, title string) {
+	rect(screen, float64(x0), float64(y0), w, h, fade(pal.Scrim, 0xD0))
+	correct := 0
+	for _, e := range log {
+		if e.Correct {
+			correct++
+		}
+	}
+	drawText(screen, trf("%s - %d/%d correct", title, correct, len(log)), x0+10, y0+20, pal.Text)
+	if len(log) == 0 {
+		drawText(screen, tr("No questions answered yet."), x0+10, y0+44, pal.Text)
+		return
+	}
+	rows := historyRows(h)
+	for i := 0; i < rows && scroll+i < len(log); i++ {
+		e := log[scroll+i]
+		col := pal.Good
+		mark := "ok "
+		if !e.Correct {
+			col = pal.Bad
+			mark = "X  "
+		}
+		line := fmt.Sprintf("%sL%-2d %-28s you: %-8s", mark, e.Level, e.Text, e.Given)
+		if !e.Correct {
+			line += " ans: " + e.Answer
+		}
+		drawText(screen, line, x0+10, y0+44+i*historyLineH, col)
+		drawText(screen, fmt.Sprintf("%5.1fs", e.MS/1000.0), x0+int(w)-60, y0+44+i*historyLineH, col)
+	}
+	if len(log) > rows {
+		drawText(screen, tr("Scroll: mouse wheel / PgUp / PgDn"), x0+10, y0+int(h)-8, pal.TextDim)
+	}
+}
+
+// updateGameOver handles the end-of-run screen: scroll the log, Enter starts a new run
+func (g *Game) updateGameOver() {
+	if g.techScreen != nil {
+		g.updateTech()
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		g.techScreen = &TechScreen{}
+		return
+	}
+	g.scrollHistory(historyRows(gameOverLogH))
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyKPEnter) {
+		// a finished versus match hangs up; the new run is solo
+		if g.versus != nil {
+			g.versus.close()
+		}
+		if g.lockstep != nil {
+			g.lockstep.close()
+		}
+		g.startRun(g.nextRun())
+	}
+}
+
+// heights of the log box in the overlay and on the game-over screen
+const (
+	historyLogH  = 440.0
+	gameOverLogH = 240.0
+)
+
+// drawGameOver shows the end-of-run summary, including the question log
+func (g *Game) drawGameOver(screen *ebiten.Image) {
+	w := 700.0
+	x0 := (g.viewW - int(w)) / 2
+	rect(screen, float64(x0), 40, w, 110, fade(pal.Scrim, 0xE0))
+	col := pal.Bad
+	if g.versus != nil && g.versus.state == versusWon {
+		col = pal.Good
+	}
+	drawTextSize(screen, tr(g.endReason), x0+10, 68, FontHeading, col)
+	result := trf("Reached level %d with %d gold, scoring %d", g.level, g.playerGold, g.runScore()) + " - " + trf("%d/3 stars", g.runStars)
+	if g.newBest {
+		result += " - " + tr("a new best on this map!")
+	}
+	drawText(screen, result, x0+10, 90, pal.Text)
+	if sent := g.online.sentStatus(); sent != "" {
+		drawText(screen, sent, x0+10, 112, pal.TextDim)
+	}
+	drawText(screen, trf("Press Enter to start a new run, Tab for the tech tree (+%d tech points this run)", g.techEarned), x0+10, 134, pal.Text)
+	g.drawRunGraphs(screen, Rect{float64(x0), 160, w, runGraphH})
+	g.drawHistory(screen, x0, 170+runGraphH, w, gameOverLogH, tr("Question log"))
+	if g.techScreen != nil {
+		g.drawTech(screen)
+	}
+}
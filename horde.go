@@ -0,0 +1,143 @@
+package datagame
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// horde mode (H): stress waves of thousands of weak enemies
+const (
+	hordeEnemiesPerLevel = 2000
+	hordePack            = 50   // enemies spawned together each spawn interval
+	hordeHPScale         = 0.1  // of a normal enemy's HP
+	hordeLeakScale       = 0.05 // of a normal enemy's escape damage
+	hordeGold            = 1    // per kill
+)
+
+// crowdAt is how many enemies on screen switch drawing to the batched path:
+// one draw call for all bodies and one for all health bars, without shaders,
+// status rings or markers
+const crowdAt = 300
+
+// toggleHorde switches horde mode, which starts with the next level
+func (g *Game) toggleHorde() {
+	g.horde = !g.horde
+	if g.horde {
+		g.levelMsg = trf("Horde mode ON from the next level: %d weak enemies per level", hordeEnemiesPerLevel)
+	} else {
+		g.levelMsg = tr("Horde mode OFF from the next level")
+	}
+	g.levelMsgTimer = 3000
+}
+
+// toggleMathGated switches math-gated mode, where every build and purchase
+// needs a correct answer
+func (g *Game) toggleMathGated() {
+	g.mathGated = !g.mathGated
+	if g.mathGated {
+		g.levelMsg = tr("Math-gated mode ON: every build and purchase needs a correct answer")
+	} else {
+		g.levelMsg = tr("Math-gated mode OFF")
+	}
+	g.levelMsgTimer = 3000
+}
+
+// startHordeLevel replaces a new level's spawn targets with a horde's
+func (g *Game) startHordeLevel() {
+	g.hordeLevel = g.horde
+	if g.hordeLevel {
+		g.enemiesToSpawn = hordeEnemiesPerLevel
+		// the level ends when the horde is gone, not after a few kills
+		g.nextLevelThreshold = hordeEnemiesPerLevel
+	}
+}
+
+// spawnPack is how many enemies to spawn this spawn interval
+func (g *Game) spawnPack() int {
+	if !g.hordeLevel {
+		return 1
+	}
+	return min(hordePack, g.enemiesToSpawn-g.enemiesSpawned)
+}
+
+// newEnemy returns e in an Enemy from the pool of removed ones, so horde
+// waves don't allocate an enemy per spawn
+func (g *Game) newEnemy(e Enemy) *Enemy {
+	g.enemySerial++
+	e.Serial = g.enemySerial
+	n := len(g.enemyPool)
+	if n == 0 {
+		return &e
+	}
+	p := g.enemyPool[n-1]
+	g.enemyPool = g.enemyPool[:n-1]
+	*p = e
+	return p
+}
+
+// freeEnemy returns a removed enemy to the pool; nothing may still refer to it
+func (g *Game) freeEnemy(e *Enemy) {
+	e.Serial = 0 // bullets still homing in on it lose track
+	g.enemyPool = append(g.enemyPool, e)
+}
+
+// compactEnemies finishes a compaction pass over g.enemies that kept live, a
+// prefix of it: the slots past it are cleared so the slice doesn't hold on to
+// pooled enemies
+func (g *Game) compactEnemies(live []*Enemy) []*Enemy {
+	clear(g.enemies[len(live):])
+	return live
+}
+
+// crowded reports whether this frame has enough enemies to draw them batched
+func (g *Game) crowded() bool {
+	return len(g.drawnEnemies) >= crowdAt
+}
+
+// drawCrowd draws every enemy body in one batch, tinted by status
+func (g *Game) drawCrowd(screen *ebiten.Image) {
+	b := &g.batch
+	b.begin(screen, spriteSheet)
+	for _, d := range g.drawnEnemies {
+		e := d.e
+		col := pal.Enemy
+		switch {
+		case e.Boss:
+			col = pal.Boss
+		case e.SlowTime > 0, e.FreezeTime > 0:
+			col = pal.EnemySlow
+		case e.BurnTime > 0:
+			col = pal.EnemyBurning
+		}
+		sprite := SpriteEnemy
+		if e.Boss {
+			sprite = SpriteBoss
+		}
+		s := spriteCell / 2 * enemyRadius(e) / spriteRadius
+		b.add(spriteCellRect(sprite, e.Anim.Frame()), d.p.X-s, d.p.Y-s, 2*s, 2*s, col)
+	}
+	b.flush()
+}
+
+// drawCrowdBars draws a thin health bar over each damaged enemy in one batch
+func (g *Game) drawCrowdBars(screen *ebiten.Image) {
+	const barW, barH = 16.0, 2.0
+	b := &g.batch
+	b.begin(screen, whitePixel)
+	src := whitePixel.Bounds()
+	for _, d := range g.drawnEnemies {
+		e := d.e
+		if e.HP >= e.MaxHP || e.Boss {
+			continue
+		}
+		x, y := d.p.X-barW/2, d.p.Y-enemyRadius(e)-5
+		b.add(src, x, y, barW, barH, pal.BarBack)
+		b.add(src, x, y, barW*max(0, e.HP/e.MaxHP), barH, pal.Good)
+	}
+	b.flush()
+	// bosses keep their full bars, shield included
+	for _, d := range g.drawnEnemies {
+		if d.e.Boss {
+			drawEnemyBars(screen, d.e, d.p)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package datagame
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// HUD panel sizes; positions come from the layout anchors
+const (
+	hudMargin     = 10.0
+	hudStatsW     = 150.0
+	hudStatsH     = 84.0
+	hudWaveW      = 220.0
+	hudWaveH      = 84.0
+	hudTowerW     = 250.0
+	hudTowerH     = 110.0
+	hudHintsW     = 260.0
+	hudHintsLineH = 18
+)
+
+func (g *Game) statsPanelRect() Rect { return g.place(AnchorTopRight, hudStatsW, hudStatsH, hudMargin) }
+func (g *Game) wavePanelRect() Rect  { return g.place(AnchorTop, hudWaveW, hudWaveH, hudMargin) }
+func (g *Game) towerPanelRect() Rect {
+	return g.place(AnchorBottomLeft, hudTowerW, hudTowerH, hudMargin)
+}
+
+// drawHUD draws the always-visible panels: resources, wave progress, selection and hints
+func (g *Game) drawHUD(screen *ebiten.Image) {
+	g.drawStatsPanel(screen)
+	g.drawWavePanel(screen)
+	g.drawTowerPanel(screen)
+	g.drawHintsPanel(screen)
+	if g.showCoverage {
+		g.drawCoverageLegend(screen)
+	}
+	if g.showDamage {
+		g.drawDamageBoard(screen)
+	}
+}
+
+// hpFlashDuration is how long the health bar flashes after an enemy escapes (ms)
+const hpFlashDuration = 600.0
+
+func (g *Game) drawStatsPanel(screen *ebiten.Image) {
+	p := Panel{Rect: g.statsPanelRect()}
+	p.Draw(screen)
+	x, y := p.Content()
+	bx := float64(x + 20)
+	bw := p.W - 36
+
+	// health bar, red when low or flashing after an escape
+	drawIcon(screen, IconHP, float64(x), float64(y-11))
+	hpCol := pal.Good
+	if g.playerHP <= PlayerMaxHP/4 {
+		hpCol = pal.Danger
+	}
+	// blinks, or stays lit with reduced motion
+	if g.hpFlashMS > 0 && (g.reducedMotion() || int(g.hpFlashMS/100)%2 == 0) {
+		hpCol = pal.Flash
+		rect(screen, bx-2, float64(y-13), bw+4, 18, fade(pal.Flash, 0x60))
+	}
+	ProgressBar(screen, Rect{bx, float64(y - 11), bw, 14}, g.playerHP/PlayerMaxHP, hpCol)
+	hp := g.hud.hp.get(math.Round(g.playerHP), 0, 0, func() string { return fmt.Sprintf("%.0f / %.0f", g.playerHP, PlayerMaxHP) })
+	drawText(screen, hp, int(bx)+4, y, pal.Text)
+	lives := g.hud.lives.get(float64(g.playerLives), 0, 0, func() string { return fmt.Sprintf("x%d", g.playerLives) })
+	drawText(screen, lives, int(bx+bw-textWidth(lives)-3), y, pal.Text)
+
+	// armor as a shield segment: how much of each escape's damage it blocks
+	drawIcon(screen, IconArmor, float64(x), float64(y+11))
+	ProgressBar(screen, Rect{bx, float64(y + 13), bw, 10}, g.playerArmor/PlayerEscapeBaseDamage, pal.Armor)
+	armor := g.hud.armor.get(math.Round(g.playerArmor), 0, 0, func() string { return fmt.Sprintf("%.0f", g.playerArmor) })
+	drawText(screen, armor, int(bx+bw-textWidth(armor)-3), y+22, pal.TextInverse)
+
+	gold := g.hud.gold.get(float64(g.playerGold), 0, 0, func() string { return trf("Gold: %d", g.playerGold) })
+	Label{Icon: IconGold, Text: gold}.Draw(screen, x, y+44)
+}
+
+// goldCounterPos is the view position of the gold icon, where kill coins fly to
+func (g *Game) goldCounterPos() (float64, float64) {
+	x, y := Panel{Rect: g.statsPanelRect()}.Content()
+	return float64(x) + 7, float64(y + 44 - 4)
+}
+
+// remainingEnemies counts enemies still to spawn plus those on the map
+func (g *Game) remainingEnemies() int {
+	remaining := g.enemiesToSpawn - g.enemiesSpawned
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining + len(g.enemies)
+}
+
+func (g *Game) drawWavePanel(screen *ebiten.Image) {
+	boss := g.isBossLevel()
+	title := g.hud.level.get(float64(g.level), b2f(boss), 0, func() string {
+		if boss {
+			return trf("Level %d", g.level) + "  " + tr("BOSS")
+		}
+		return trf("Level %d", g.level)
+	})
+	p := Panel{Rect: g.wavePanelRect(), Title: title}
+	p.Draw(screen)
+	x, y := p.Content()
+	remaining := g.remainingEnemies()
+	left := g.hud.remaining.get(float64(remaining), 0, 0, func() string { return trf("Remaining: %d", remaining) })
+	Label{Icon: IconEnemy, Text: left}.Draw(screen, x, y)
+	if g.enemiesToSpawn > 0 {
+		bar := Rect{p.X + 130, float64(y) - 9, p.W - 138, 8}
+		done := 1 - float64(remaining)/float64(g.enemiesToSpawn)
+		ProgressBar(screen, bar, done, pal.Good)
+	}
+	score := g.hud.score.get(float64(g.score), 0, 0, func() string { return trf("Score: %d", g.score) })
+	drawText(screen, score, x, y+20, pal.Text)
+	if g.combo > 0 {
+		combo := g.hud.combo.get(float64(g.combo), 0, 0, func() string { return trf("Combo x%.1f", g.comboMult()) })
+		drawText(screen, combo, int(p.X+p.W-textWidth(combo))-8, y+20, pal.Warn)
+	}
+	// transient level message sits just under the wave panel
+	if g.levelMsgTimer > 0 && g.levelMsg != "" {
+		w := textWidth(g.levelMsg) + 16
+		m := Panel{Rect: Rect{(float64(g.viewW) - w) / 2, p.Y + p.H + 4, w, 22}}
+		m.Draw(screen)
+		drawText(screen, g.levelMsg, int(m.X)+8, int(m.Y)+15, pal.Text)
+	}
+}
+
+func (g *Game) drawTowerPanel(screen *ebiten.Image) {
+	r := g.towerPanelRect()
+	if g.selected < 0 || g.selected >= len(g.towers) {
+		p := Panel{Rect: Rect{r.X, r.Y + r.H - 76, r.W, 76}, Title: tr("Placement point")}
+		p.Draw(screen)
+		x, y := p.Content()
+		place := g.hud.place.get(math.Round(g.lastClick.X), math.Round(g.lastClick.Y), float64(g.settings.Keys.Challenge), func() string {
+			return trf("%.0f, %.0f (click then press %s)", g.lastClick.X, g.lastClick.Y, g.settings.Keys.Challenge)
+		})
+		drawText(screen, place, x, y, pal.Text)
+		next := g.hud.nextTower.get(float64(g.buildType), 0, 0, func() string { return trf("Next tower: %s", tr(towerTypes[g.buildType])) })
+		drawText(screen, next, x, y+18, pal.TextDim)
+		return
+	}
+	tw := g.towers[g.selected]
+	h := &g.hud
+	title := h.towerTitle.get(float64(slices.Index(towerTypes, tw.Type)), 0, 0, func() string { return trf("Selected tower: %s", tr(tw.Type)) })
+	p := Panel{Rect: r, Title: title}
+	p.Draw(screen)
+	x, y := p.Content()
+	Label{Icon: IconTower, Text: h.damage.get(math.Round(tw.Damage), 0, 0, func() string { return trf("Damage: %.0f", tw.Damage) })}.Draw(screen, x, y)
+	drawText(screen, h.rng.get(math.Round(tw.Range), 0, 0, func() string { return trf("Range: %.0f", tw.Range) }), x+20, y+18, pal.Text)
+	drawText(screen, h.fire.get(math.Round(tw.Fire), 0, 0, func() string { return trf("Fire every %.0f ms", tw.Fire) }), x+20, y+36, pal.Text)
+	drawText(screen, h.upgrades.get(float64(tw.Upgrades), 0, 0, func() string { return trf("Upgrades: %d", tw.Upgrades) }), x+20, y+54, pal.Text)
+	if b, ok := g.sellButton(); ok {
+		b.Draw(screen)
+	}
+}
+
+// sellButton sits in the tower panel while a tower is selected
+func (g *Game) sellButton() (Button, bool) {
+	if g.selected < 0 || g.selected >= len(g.towers) || g.challengeActive {
+		return Button{}, false
+	}
+	r := g.towerPanelRect()
+	value := towerSellValue(g.towers[g.selected])
+	text := g.hud.sell.get(float64(value), 0, 0, func() string { return trf("Sell %d", value) })
+	return Button{Rect: Rect{r.X + r.W - 90, r.Y + r.H - 34, 80, 24}, Text: text, OnClick: g.confirmSell}, true
+}
+
+func (g *Game) drawHintsPanel(screen *ebiten.Image) {
+	// keyboard hints are no use on a phone, and a narrow view has no room
+	// for them beside the wave panel
+	if g.narrow() {
+		return
+	}
+	keys := g.settings.Keys
+	all := [...]Label{
+		{Text: g.hud.keys.get(float64(keys.Challenge), float64(keys.Shop), 0, func() string {
+			return trf("%s: math challenge   %s: shop", keys.Challenge, keys.Shop)
+		})},
+		{Text: tr("Click: select tower / set placement")},
+		{Color: pal.Warn},
+	}
+	lines := all[:2]
+	if g.mathGated {
+		d := purchaseDifficulty(g.buildCost())
+		all[2].Text = g.hud.gated.get(float64(d), 0, 0, func() string { return trf("MATH-GATED (G): build difficulty %d", d) })
+		lines = all[:3]
+	}
+	w := hudHintsW
+	for _, l := range lines {
+		if lw := textWidth(l.Text) + 16; lw > w {
+			w = lw
+		}
+	}
+	p := Panel{Rect: g.place(AnchorTopLeft, w, float64(len(lines)*hudHintsLineH+10), hudMargin)}
+	p.Draw(screen)
+	x, y := int(p.X)+8, int(p.Y)+18
+	for i, l := range lines {
+		l.Draw(screen, x, y+i*hudHintsLineH)
+	}
+}
@@ -0,0 +1,97 @@
+package datagame
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Translations are JSON objects mapping the English source string (or format
+// string) to its translation, plus a "_name" entry with the language's display
+// name. English needs no file: any missing key falls back to the source text.
+// Extra or overriding files can be dropped into datagame/lang/ in the config dir.
+
+//go:embed lang/*.json
+var langFS embed.FS
+
+var (
+	languages = map[string]map[string]string{} // code -> table
+	curLang   map[string]string
+	// langGen counts language switches, so cached text knows to re-translate
+	langGen int
+)
+
+func init() {
+	languages["en"] = map[string]string{"_name": "English"}
+	entries, _ := langFS.ReadDir("lang")
+	for _, e := range entries {
+		data, err := langFS.ReadFile("lang/" + e.Name())
+		if err == nil {
+			addLanguage(strings.TrimSuffix(e.Name(), ".json"), data)
+		}
+	}
+	// user-provided translation files
+	files, _ := storage.List("lang")
+	for _, f := range files {
+		if !strings.HasSuffix(f, ".json") {
+			continue
+		}
+		if data, err := storage.ReadFile("lang/" + f); err == nil {
+			addLanguage(strings.TrimSuffix(f, ".json"), data)
+		}
+	}
+}
+
+// addLanguage merges a translation file into the table for code
+func addLanguage(code string, data []byte) {
+	var table map[string]string
+	if err := json.Unmarshal(data, &table); err != nil {
+		return
+	}
+	if languages[code] == nil {
+		languages[code] = map[string]string{}
+	}
+	for k, v := range table {
+		languages[code][k] = v
+	}
+}
+
+// setLanguage selects the active translation table; unknown codes mean English
+func setLanguage(code string) {
+	curLang = languages[code]
+	langGen++
+}
+
+// languageCodes lists the available languages, English first
+func languageCodes() []string {
+	codes := make([]string, 0, len(languages))
+	for c := range languages {
+		if c != "en" {
+			codes = append(codes, c)
+		}
+	}
+	sort.Strings(codes)
+	return append([]string{"en"}, codes...)
+}
+
+func languageName(code string) string {
+	if n := languages[code]["_name"]; n != "" {
+		return n
+	}
+	return code
+}
+
+// tr translates a UI string, falling back to the English source
+func tr(s string) string {
+	if t, ok := curLang[s]; ok && t != "" {
+		return t
+	}
+	return s
+}
+
+// trf translates a format string and applies args
+func trf(format string, args ...any) string {
+	return fmt.Sprintf(tr(format), args...)
+}
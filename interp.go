@@ -0,0 +1,56 @@
+package datagame
+
+import (
+	"math"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// tick rates: the usual one, and the low one for slow machines (Settings.LowTickRate)
+const (
+	normalTPS = 60
+	lowTPS    = 30
+)
+
+// applyTickRate sets Ebiten's tick rate from the settings. Each Update advances
+// the game by the length of a tick, so game speed is the same at either rate,
+// and Draw blends between ticks so motion stays smooth at the low one.
+func applyTickRate(s *Settings) {
+	tps := normalTPS
+	if s.LowTickRate {
+		tps = lowTPS
+	}
+	ebiten.SetTPS(tps)
+}
+
+// tickMS is how much time one Update covers, in ms
+func tickMS() float64 { return 1000 / float64(ebiten.TPS()) }
+
+// ticksFor is how many Updates last about ms, at least one
+func ticksFor(ms float64) int { return max(1, int(math.Round(ms/tickMS()))) }
+
+// markTick snapshots enemy progress at the start of an Update, so Draw can
+// blend from the previous tick to the current one on displays faster than TPS
+func (g *Game) markTick() {
+	for _, e := range g.enemies {
+		e.PrevDist = e.Dist
+	}
+	g.tickAt = time.Now()
+}
+
+// tickAlpha is how far the current frame is between the last tick and the next (0-1)
+func (g *Game) tickAlpha() float64 {
+	tick := time.Second / time.Duration(ebiten.TPS())
+	if g.watch != nil {
+		// a spectator's enemies move once per snapshot, not once per tick
+		tick = spectateInterval
+	}
+	a := float64(time.Since(g.tickAt)) / float64(tick)
+	return max(0, min(1, a))
+}
+
+// renderPos is the enemy's position interpolated between its last two ticks
+func (g *Game) renderPos(e *Enemy, alpha float64) Vec {
+	return g.posAlongPath(e.PrevDist + (e.Dist-e.PrevDist)*alpha)
+}
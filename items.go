@@ -0,0 +1,143 @@
+package datagame
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Items are one-use consumables bought in the shop's Consumables tab and used
+// from the hotbar along the bottom of the screen, with 1, 2 and 3 or a click:
+// an airstrike blasts a spot picked with the next click, a freeze bomb stops
+// every enemy for a few seconds, and a gold rush doubles bounties for a while.
+// Using one is a Command, so networked games use it on the same tick.
+
+// itemKind indexes Game.items and itemDefs
+type itemKind int
+
+const (
+	itemAirstrike itemKind = iota
+	itemFreeze
+	itemGoldRush
+)
+
+const (
+	maxItems         = 9 // of each kind held
+	airstrikeRadius  = 70.0
+	airstrikeDamage  = 150.0 // at level 1
+	airstrikePerLvl  = 25.0  // more damage a level
+	airstrikePierce  = 5.0   // armor it ignores
+	freezeMS         = 3000.0
+	goldRushMS       = 15000.0
+	goldRushMultiple = 2
+	itemBtnW         = 130.0
+	itemBtnH         = 28.0
+	itemBtnGap       = 8.0
+)
+
+// itemDef describes an item kind for the shop and the hotbar
+type itemDef struct {
+	name string // also the shop label and the Command type
+	desc string
+	icon Icon
+	cost int // base price, rising with each one held
+	key  ebiten.Key
+}
+
+var itemDefs = []itemDef{
+	itemAirstrike: {"Airstrike", "Blasts enemies around a spot you click", IconBlast, 60, ebiten.KeyDigit1},
+	itemFreeze:    {"Freeze bomb", "Stops every enemy for 3 seconds", IconSlow, 50, ebiten.KeyDigit2},
+	itemGoldRush:  {"Gold rush", "Kills pay double for 15 seconds", IconGold, 80, ebiten.KeyDigit3},
+}
+
+// itemShopItems are the items' lines in the shop
+func (g *Game) itemShopItems() []shopItem {
+	var items []shopItem
+	for k, d := range itemDefs {
+		items = append(items, shopItem{tab: shopTabConsumables, icon: d.icon, label: d.name, desc: d.desc,
+			level: g.items[k], base: d.cost, full: g.items[k] >= maxItems, room: maxItems - g.items[k], buy: func() { g.items[k]++ }})
+	}
+	return items
+}
+
+// useItem readies an item from the hotbar: the airstrike waits for a spot,
+// the others go off at once
+func (g *Game) useItem(k itemKind) {
+	if g.items[k] == 0 {
+		return
+	}
+	if k == itemAirstrike {
+		g.aiming = !g.aiming
+		return
+	}
+	g.do(Command{Kind: "item", Type: itemDefs[k].name})
+}
+
+// updateItems handles the hotbar keys
+func (g *Game) updateItems() {
+	for k, d := range itemDefs {
+		if inpututil.IsKeyJustPressed(d.key) {
+			g.useItem(itemKind(k))
+		}
+	}
+}
+
+// applyItem uses up one item, for Command "item"
+func (g *Game) applyItem(c Command) {
+	k := -1
+	for i, d := range itemDefs {
+		if d.name == c.Type {
+			k = i
+		}
+	}
+	if k < 0 || g.items[k] == 0 {
+		return
+	}
+	g.items[k]--
+	switch itemKind(k) {
+	case itemAirstrike:
+		dmg := airstrikeDamage + airstrikePerLvl*float64(g.level-1)
+		g.applyDamageAt(c.X, c.Y, dmg, airstrikePierce, airstrikeRadius, nil)
+		g.emitImpact(c.X, c.Y)
+		g.impactCue(c.X, c.Y)
+	case itemFreeze:
+		for _, e := range g.enemies {
+			e.FreezeTime = freezeMS
+		}
+	case itemGoldRush:
+		g.goldRushMS = goldRushMS
+	}
+}
+
+// itemButtons are the hotbar, one button per kind showing its key and how
+// many are held
+func (g *Game) itemButtons() []Button {
+	n := float64(len(itemDefs))
+	row := g.place(AnchorBottom, n*itemBtnW+(n-1)*itemBtnGap, itemBtnH, 10)
+	if g.showCoverage {
+		// above the heatmap legend
+		row.Y -= 30 + itemBtnGap
+	}
+	var btns []Button
+	for k, d := range itemDefs {
+		text := trf("%d %s x%d", k+1, tr(d.name), g.items[k])
+		if itemKind(k) == itemGoldRush && g.goldRushMS > 0 {
+			text = trf("%d %s %.0fs", k+1, tr(d.name), math.Ceil(g.goldRushMS/1000))
+		}
+		btns = append(btns, Button{Rect: Rect{row.X + float64(k)*(itemBtnW+itemBtnGap), row.Y, itemBtnW, itemBtnH},
+			Text: text, Disabled: g.items[k] == 0, Active: itemKind(k) == itemAirstrike && g.aiming,
+			OnClick: func() { g.useItem(itemKind(k)) }})
+	}
+	return btns
+}
+
+// drawAirstrikeAim marks where an airstrike would land, under the cursor
+func (g *Game) drawAirstrikeAim(screen *ebiten.Image) {
+	if !g.aiming {
+		return
+	}
+	x, y := g.toWorld(cursor())
+	disc(screen, x, y, airstrikeRadius, fade(pal.Danger, 0x30))
+	ring(screen, x, y, airstrikeRadius, 2, pal.Danger)
+}
@@ -0,0 +1,127 @@
+package datagame
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// KeyBindings are the rebindable hotkeys, saved in settings.json by key name
+type KeyBindings struct {
+	Challenge ebiten.Key `json:"challenge"`
+	Shop      ebiten.Key `json:"shop"`
+	Pause     ebiten.Key `json:"pause"`
+	Speed     ebiten.Key `json:"speed"`
+	Photo     ebiten.Key `json:"photo"`
+	Clip      ebiten.Key `json:"clip"`
+}
+
+func defaultKeyBindings() KeyBindings {
+	return KeyBindings{Challenge: ebiten.KeyC, Shop: ebiten.KeyB, Pause: ebiten.KeySpace, Speed: ebiten.KeyF, Photo: ebiten.KeyF12, Clip: ebiten.KeyF9}
+}
+
+// keyAction is one row of the controls page
+type keyAction struct {
+	name string
+	key  func(*KeyBindings) *ebiten.Key
+}
+
+var keyActions = []keyAction{
+	{"Open math challenge", func(k *KeyBindings) *ebiten.Key { return &k.Challenge }},
+	{"Open shop", func(k *KeyBindings) *ebiten.Key { return &k.Shop }},
+	{"Pause / resume", func(k *KeyBindings) *ebiten.Key { return &k.Pause }},
+	{"Change game speed", func(k *KeyBindings) *ebiten.Key { return &k.Speed }},
+	{"Photo mode", func(k *KeyBindings) *ebiten.Key { return &k.Photo }},
+	{"Save a clip of the last 10 seconds", func(k *KeyBindings) *ebiten.Key { return &k.Clip }},
+}
+
+// reservedKey reports keys with fixed meanings (menus, answer typing) that can't be bound
+func reservedKey(k ebiten.Key) bool {
+	switch {
+	case k >= ebiten.KeyDigit0 && k <= ebiten.KeyDigit9, k >= ebiten.KeyNumpad0 && k <= ebiten.KeyNumpad9:
+		return true
+	}
+	switch k {
+	case ebiten.KeyEscape, ebiten.KeyEnter, ebiten.KeyNumpadEnter, ebiten.KeyBackspace, ebiten.KeyTab,
+		ebiten.KeyUp, ebiten.KeyDown, ebiten.KeyLeft, ebiten.KeyRight, ebiten.KeyPageUp, ebiten.KeyPageDown,
+		ebiten.KeyMinus, ebiten.KeyNumpadSubtract, ebiten.KeyPeriod, ebiten.KeyNumpadDecimal, ebiten.KeyComma,
+		ebiten.KeyDelete, ebiten.KeyF3, ebiten.KeyF11,
+		ebiten.KeyT, ebiten.KeyI, ebiten.KeyP, ebiten.KeyO, ebiten.KeyL, ebiten.KeyR, ebiten.KeyG, ebiten.KeyX, ebiten.KeyN, ebiten.KeyV, ebiten.KeyK, ebiten.KeyM, ebiten.KeyH, ebiten.KeyU, ebiten.KeyJ, ebiten.KeyE,
+		ebiten.KeyW, ebiten.KeyA, ebiten.KeyS, ebiten.KeyD, ebiten.KeyHome:
+		return true
+	}
+	return false
+}
+
+// updateControls handles the controls page: Up/Down select, Enter starts rebinding
+func (g *Game) updateControls() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyUp) {
+		g.settingsRow = (g.settingsRow + len(keyActions) - 1) % len(keyActions)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+		g.settingsRow = (g.settingsRow + 1) % len(keyActions)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		g.rebinding = true
+		g.bindMsg = ""
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) {
+		g.settings.Keys = defaultKeyBindings()
+		g.settings.Save()
+		g.bindMsg = tr("Default keys restored")
+	}
+}
+
+// captureBinding waits for the next key press and assigns it to the selected action.
+// A key already bound to another action is swapped with the old one.
+func (g *Game) captureBinding() {
+	for _, k := range inpututil.AppendJustPressedKeys(nil) {
+		if k == ebiten.KeyEscape {
+			g.rebinding = false
+			return
+		}
+		if reservedKey(k) {
+			g.bindMsg = trf("%s is reserved, try another key", k)
+			continue
+		}
+		keys := &g.settings.Keys
+		target := keyActions[g.settingsRow].key(keys)
+		for _, a := range keyActions {
+			if other := a.key(keys); other != target && *other == k {
+				*other = *target
+			}
+		}
+		*target = k
+		g.settings.Save()
+		g.rebinding = false
+		g.bindMsg = ""
+		return
+	}
+}
+
+func (g *Game) drawControls(screen *ebiten.Image, x0, y0 int, h float64) {
+	for i, a := range keyActions {
+		col := color.Color(pal.Text)
+		prefix := "  "
+		if i == g.settingsRow {
+			col = pal.Warn
+			prefix = "> "
+		}
+		key := fmt.Sprint(*a.key(&g.settings.Keys))
+		if g.rebinding && i == g.settingsRow {
+			key = tr("press a key...")
+		}
+		drawText(screen, prefix+tr(a.name), x0+10, y0+50+i*24, col)
+		drawText(screen, key, x0+300, y0+50+i*24, col)
+	}
+	if g.bindMsg != "" {
+		drawText(screen, g.bindMsg, x0+10, y0+50+len(keyActions)*24, pal.Warn)
+	}
+	hint := tr("Enter rebind, Backspace reset all, Tab options")
+	if g.rebinding {
+		hint = tr("Press the new key, Esc to cancel")
+	}
+	drawText(screen, hint, x0+10, y0+int(h)-12, pal.TextDim)
+}
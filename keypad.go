@@ -0,0 +1,109 @@
+package datagame
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// On-screen keypad for mouse and touch answer entry, shown under the challenge
+// and drill panels. Keys are laid out on a 4x4 grid of cells.
+const (
+	keypadKeyW = 44.0
+	keypadKeyH = 32.0
+	keypadGap  = 6.0
+	keypadW    = 4*keypadKeyW + 3*keypadGap
+)
+
+type keypadKey struct {
+	label    string
+	col, row int
+	span     int // cells wide
+}
+
+var keypadKeys = []keypadKey{
+	{"7", 0, 0, 1}, {"8", 1, 0, 1}, {"9", 2, 0, 1}, {"<-", 3, 0, 1},
+	{"4", 0, 1, 1}, {"5", 1, 1, 1}, {"6", 2, 1, 1}, {"-", 3, 1, 1},
+	{"1", 0, 2, 1}, {"2", 1, 2, 1}, {"3", 2, 2, 1}, {".", 3, 2, 1},
+	{"Esc", 0, 3, 1}, {"0", 1, 3, 1}, {"Enter", 2, 3, 2},
+}
+
+// keypadOrigin places the keypad under whichever answer panel is showing
+func (g *Game) keypadOrigin() (float64, float64) {
+	x := (float64(g.viewW) - keypadW) / 2
+	if g.drill != nil {
+		r := g.drillRect()
+		return x, r.Y + r.H + 10
+	}
+	r := g.challengeRect()
+	return x, r.Y + r.H + 10
+}
+
+func (g *Game) keypadBounds(k keypadKey) (x, y, w, h float64) {
+	ox, oy := g.keypadOrigin()
+	x = ox + float64(k.col)*(keypadKeyW+keypadGap)
+	y = oy + float64(k.row)*(keypadKeyH+keypadGap)
+	w = float64(k.span)*keypadKeyW + float64(k.span-1)*keypadGap
+	return x, y, w, keypadKeyH
+}
+
+func (g *Game) keypadKeyAt(x, y float64) (keypadKey, bool) {
+	for _, k := range keypadKeys {
+		kx, ky, kw, kh := g.keypadBounds(k)
+		if x >= kx && x <= kx+kw && y >= ky && y <= ky+kh {
+			return k, true
+		}
+	}
+	return keypadKey{}, false
+}
+
+// pointerReleases returns the positions of mouse clicks and touches released this tick
+func pointerReleases() []Vec {
+	var out []Vec
+	if inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) {
+		x, y := cursor()
+		out = append(out, Vec{x, y})
+	}
+	for _, id := range inpututil.AppendJustReleasedTouchIDs(nil) {
+		x, y := inpututil.TouchPositionInPreviousTick(id)
+		out = append(out, Vec{float64(x) / pixelScale, float64(y) / pixelScale})
+	}
+	return out
+}
+
+// readKeypad applies keypad presses to inputBuf, mirroring the keyboard rules
+func (g *Game) readKeypad() (submitted, cancelled bool) {
+	for _, p := range pointerReleases() {
+		k, ok := g.keypadKeyAt(p.X, p.Y)
+		if !ok {
+			continue
+		}
+		switch k.label {
+		case "<-":
+			g.backspaceAnswer()
+		case "Enter":
+			submitted = true
+		case "Esc":
+			cancelled = true
+		default:
+			g.typeAnswerRune(rune(k.label[0]))
+		}
+	}
+	return submitted, cancelled
+}
+
+func (g *Game) drawKeypad(screen *ebiten.Image) {
+	hover, hovering := g.keypadKeyAt(cursor())
+	pressed := pointerDown()
+	for _, k := range keypadKeys {
+		x, y, w, h := g.keypadBounds(k)
+		col := fade(pal.Key, 0xE0)
+		if hovering && hover == k {
+			col = fade(pal.KeyHover, 0xE0)
+			if pressed {
+				col = fade(pal.KeyPressed, 0xE0)
+			}
+		}
+		rect(screen, x, y, w, h, col)
+		drawText(screen, k.label, int(x+(w-textWidth(k.label))/2), int(y+h/2)+4, pal.Text)
+	}
+}
@@ -0,0 +1,113 @@
+package datagame
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// Launch flags set up a particular run straight away, so a tester can replay
+// a scenario or a teacher can start every machine in a class the same way:
+//
+//	datagame -seed 42 -level 8 -difficulty 3 -fullscreen -mute
+//
+// -seed, -map and -level apply to every fresh run this session (restarts
+// included); -difficulty and -mute override the saved settings for this
+// session without changing them.
+
+var (
+	launchSeed       = flag.Int64("seed", 0, "play this seed, so runs go the same way every time (0 picks a new one each run)")
+	launchLevel      = flag.Int("level", 1, "start runs at this level")
+	launchMap        = flag.String("map", defaultMap, "play on this map")
+	launchDifficulty = flag.String("difficulty", "", "lock questions to a grade band: auto, 1-2, 3, 4, 5 or 6")
+	launchFullscreen = flag.Bool("fullscreen", false, "start fullscreen")
+	launchWindowed   = flag.Bool("windowed", false, "start in a window (the default)")
+	launchMute       = flag.Bool("mute", false, "start with the sound off")
+)
+
+// difficultyNames name the GradeBands for -difficulty, by index
+var difficultyNames = []string{"auto", "1-2", "3", "4", "5", "6"}
+
+// checkLaunchFlags reports a launch flag the game can't honour
+func checkLaunchFlags() error {
+	if *launchLevel < 1 {
+		return errors.New("-level must be 1 or more")
+	}
+	if _, err := loadMap(*launchMap); errors.Is(err, fs.ErrNotExist) {
+		maps := append(mapNames(), contentFiles(contentMap)...)
+		return fmt.Errorf("-map: there is no map %q; the maps are %s", *launchMap, strings.Join(maps, ", "))
+	} else if err != nil {
+		return fmt.Errorf("-map: %w", err)
+	}
+	if *launchDifficulty != "" && !slices.Contains(difficultyNames, *launchDifficulty) {
+		return fmt.Errorf("-difficulty must be one of %s", strings.Join(difficultyNames, ", "))
+	}
+	if *launchFullscreen && *launchWindowed {
+		return errors.New("-fullscreen and -windowed can't both be given")
+	}
+	return nil
+}
+
+// launchSettings applies -difficulty and -mute over the saved settings,
+// keeping the saved values for Save to write back
+func launchSettings(s *Settings) {
+	if i := slices.Index(difficultyNames, *launchDifficulty); i >= 0 {
+		band := s.GradeBand
+		s.savedBand, s.GradeBand = &band, i
+	}
+	if *launchMute {
+		muted := s.Muted
+		s.savedMuted, s.Muted = &muted, true
+	}
+}
+
+// unlaunched is s as it should be saved: a setting a launch flag changed goes
+// back to its saved value, unless the player has changed it since
+func (s Settings) unlaunched() Settings {
+	if s.savedBand != nil && s.GradeBand == slices.Index(difficultyNames, *launchDifficulty) {
+		s.GradeBand = *s.savedBand
+	}
+	if s.savedMuted != nil && s.Muted {
+		s.Muted = *s.savedMuted
+	}
+	return s
+}
+
+// launch moves a fresh run to the map and level the flags ask for
+func (g *Game) launch() {
+	if *launchMap != defaultMap {
+		m, err := loadMap(*launchMap)
+		if err != nil {
+			// a content folder map that has gone or broken since it was picked
+			*launchMap = defaultMap
+			g.levelMsg = tr("Map not loaded: ") + err.Error()
+			g.levelMsgTimer = 5000
+			return
+		}
+		g.useMap(m)
+	}
+	g.skipTo(*launchLevel)
+}
+
+// useMap moves a fresh run to m
+func (g *Game) useMap(m *MapDef) {
+	g.mapDef = m
+	g.setPath(g.mapDef.Waypoints())
+	g.mapAmbience = g.mapDef.Ambience()
+}
+
+// skipTo moves a fresh run on to level, if it is past 1
+func (g *Game) skipTo(level int) {
+	if level <= 1 {
+		return
+	}
+	for g.level < level {
+		g.newLevel()
+	}
+	g.startLevel = g.level
+	g.levelMsg = trf("Starting at level %d", g.level)
+	g.levelMsgTimer = 3000
+}
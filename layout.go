@@ -0,0 +1,129 @@
+package datagame
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// The logical view is at least ScreenW x ScreenH and grows along whichever axis
+// the window is relatively larger in, so at zoom 1 an 800x600 map is shown whole
+// (letterboxed, see Camera) while HUD elements anchor to the real edges of the window.
+// Small screens get a smaller view, down to compactW x compactH, where the map
+// is panned and pinched into view and the HUD keeps to the narrow() layout.
+
+// Rect is an axis-aligned UI rectangle in view coordinates
+type Rect struct{ X, Y, W, H float64 }
+
+func (r Rect) Contains(x, y float64) bool {
+	return x >= r.X && x <= r.X+r.W && y >= r.Y && y <= r.Y+r.H
+}
+
+// Anchor names the view edge or corner a UI element is pinned to
+type Anchor int
+
+const (
+	AnchorTopLeft Anchor = iota
+	AnchorTop
+	AnchorTopRight
+	AnchorCenter
+	AnchorBottomLeft
+	AnchorBottom
+	AnchorBottomRight
+)
+
+// place positions a w x h element against an anchor, inset by margin
+func (g *Game) place(a Anchor, w, h, margin float64) Rect {
+	vw, vh := float64(g.viewW), float64(g.viewH)
+	x := (vw - w) / 2
+	y := (vh - h) / 2
+	switch a {
+	case AnchorTopLeft, AnchorBottomLeft:
+		x = margin
+	case AnchorTopRight, AnchorBottomRight:
+		x = vw - w - margin
+	}
+	switch a {
+	case AnchorTopLeft, AnchorTop, AnchorTopRight:
+		y = margin
+	case AnchorBottomLeft, AnchorBottom, AnchorBottomRight:
+		y = vh - h - margin
+	}
+	return Rect{x, y, w, h}
+}
+
+// centered is shorthand for a box in the middle of the view, narrowed to fit
+// a narrow one
+func (g *Game) centered(w, h float64) Rect {
+	return g.place(AnchorCenter, min(w, float64(g.viewW)-2*hudMargin), h, 0)
+}
+
+// narrow reports whether the view is narrower than the map, as on a phone
+// held upright
+func (g *Game) narrow() bool { return g.viewW < ScreenW }
+
+// cursor returns the mouse position in view coordinates, or the touch
+// position while the screen is being touched
+func cursor() (float64, float64) {
+	if touch.recent {
+		return touch.pos.X, touch.pos.Y
+	}
+	x, y := ebiten.CursorPosition()
+	return float64(x) / pixelScale, float64(y) / pixelScale
+}
+
+// --- named layout boxes shared by Draw and click handling ---
+
+func (g *Game) challengeRect() Rect  { return g.centered(500, 140) }
+func (g *Game) shopRect() Rect       { return g.centered(480, 280) }
+func (g *Game) interLevelRect() Rect { return g.centered(360, 190) }
+func (g *Game) reportRect() Rect     { return g.centered(560, 420) }
+func (g *Game) drillRect() Rect      { return g.centered(500, 240) }
+
+func (g *Game) startButtonRect() Rect {
+	r := g.interLevelRect()
+	return Rect{r.X + r.W - 140, r.Y + r.H - 36, 120, 28}
+}
+
+func (g *Game) challengeButtonRect() Rect { return g.place(AnchorBottomRight, 100, 28, 10) }
+
+func (g *Game) shopButtonRect() Rect {
+	r := g.challengeButtonRect()
+	return Rect{r.X - 88, r.Y, 80, r.H}
+}
+
+// the smallest view, for screens that would show the full one at less than
+// compactScale
+const (
+	compactW     = 560
+	compactH     = 480
+	compactScale = 0.8
+)
+
+// Layout keeps the map's aspect ratio inside the window and extends the view
+// sideways or downwards to fill the rest, instead of stretching. The screen
+// itself is sized in device pixels (see pixelScale).
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	if outsideWidth <= 0 || outsideHeight <= 0 {
+		g.viewW, g.viewH = ScreenW, ScreenH
+		setPixelScale(1)
+		return ScreenW, ScreenH
+	}
+	w, h := float64(outsideWidth), float64(outsideHeight)
+	minW, minH := ScreenW, ScreenH
+	// on a small screen, such as a phone's, a full-size view would shrink the
+	// HUD past reading and tapping, so the view gets smaller instead
+	if math.Min(w/ScreenW, h/ScreenH) < compactScale {
+		minW, minH = compactW, compactH
+	}
+	scale := math.Min(w/float64(minW), h/float64(minH))
+	g.viewW = max(minW, int(math.Ceil(w/scale)))
+	g.viewH = max(minH, int(math.Ceil(h/scale)))
+	setPixelScale(scale * deviceScale())
+	return scaledSize(float64(g.viewW), float64(g.viewH))
+}
+
+// toggleFullscreen switches between windowed and fullscreen (F11)
+func toggleFullscreen() {
+	ebiten.SetFullscreen(!ebiten.IsFullscreen())
+}
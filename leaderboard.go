@@ -0,0 +1,79 @@
+package datagame
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// damage leaderboard panel size
+const (
+	leaderboardW     = 280.0
+	leaderboardRowH  = 20
+	leaderboardRows  = 8
+	leaderboardBarsX = 118.0
+)
+
+// rankedTowers returns tower indices ordered by damage dealt, then kills
+func (g *Game) rankedTowers() []int {
+	idx := make([]int, len(g.towers))
+	for i := range idx {
+		idx[i] = i
+	}
+	slices.SortStableFunc(idx, func(a, b int) int {
+		ta, tb := g.towers[a], g.towers[b]
+		switch {
+		case ta.DamageDealt != tb.DamageDealt:
+			if ta.DamageDealt > tb.DamageDealt {
+				return -1
+			}
+			return 1
+		default:
+			return tb.Kills - ta.Kills
+		}
+	})
+	return idx
+}
+
+// drawDamageBoard ranks the towers by damage and kills this run (toggled with K),
+// under the stats panel; the selected tower's row is highlighted
+func (g *Game) drawDamageBoard(screen *ebiten.Image) {
+	ranked := g.rankedTowers()
+	n := min(len(ranked), leaderboardRows)
+	lines := max(n, 1)
+	if len(ranked) > n {
+		lines++ // "+N more"
+	}
+	h := 30.0 + float64(lines*leaderboardRowH)
+	r := g.place(AnchorTopRight, leaderboardW, h, hudMargin)
+	r.Y += hudStatsH + hudMargin
+	p := Panel{Rect: r, Title: tr("Tower damage")}
+	p.Draw(screen)
+	x, y := p.Content()
+	if n == 0 {
+		drawText(screen, tr("No towers yet"), x, y, pal.TextDim)
+		return
+	}
+	top := g.towers[ranked[0]].DamageDealt
+	for row, i := range ranked[:n] {
+		tw := g.towers[i]
+		yy := y + row*leaderboardRowH
+		col := pal.Text
+		if i == g.selected {
+			col = pal.Warn
+		}
+		drawText(screen, fmt.Sprintf("%d. %s", row+1, tr(tw.Type)), x, yy, col)
+		// damage bar relative to the leader, with totals on top
+		bar := Rect{r.X + leaderboardBarsX, float64(yy - 11), r.W - leaderboardBarsX - 8, 14}
+		frac := 0.0
+		if top > 0 {
+			frac = tw.DamageDealt / top
+		}
+		ProgressBar(screen, bar, frac, pal.Bad)
+		drawText(screen, trf("%.0f dmg, %d kills", tw.DamageDealt, tw.Kills), int(bar.X)+4, yy, pal.Text)
+	}
+	if len(ranked) > n {
+		drawText(screen, trf("+%d more", len(ranked)-n), x, y+n*leaderboardRowH, pal.TextDim)
+	}
+}
@@ -0,0 +1,47 @@
+package datagame
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// leak warning tuning
+const (
+	leakWarnAt       = 0.8    // fraction of the path an enemy crosses before the alarm
+	leakAlarmGapMS   = 1500.0 // the alarm sounds at most this often
+	exitFlashMS      = 1200.0
+	exitFlashRadius  = 34.0
+	exitFlashPeriodS = 0.25 // blink period
+)
+
+// checkLeakWarning sounds the alarm and flashes the exit the first time an
+// enemy crosses leakWarnAt of the path, so a player busy with a question
+// notices before HP is lost
+func (g *Game) checkLeakWarning(e *Enemy) {
+	if e.Warned || g.pathProgress(e.Dist) < leakWarnAt {
+		return
+	}
+	e.Warned = true
+	g.exitFlashMS = exitFlashMS
+	if g.alarmCooldownMS <= 0 {
+		exit := g.path[len(g.path)-1]
+		sound.PlaySFXAt(SfxAlarm, g.panAt(exit.X, exit.Y))
+		g.alarmCooldownMS = leakAlarmGapMS
+	}
+}
+
+// drawExitWarning blinks a red glow over the path's exit while the warning
+// lasts; with reduced motion it fades out instead of blinking
+func (g *Game) drawExitWarning(screen *ebiten.Image) {
+	if g.exitFlashMS <= 0 || len(g.path) == 0 {
+		return
+	}
+	exit := g.path[len(g.path)-1]
+	a := g.exitFlashMS / exitFlashMS
+	if !g.reducedMotion() {
+		a = 0.5 + 0.5*math.Cos(2*math.Pi*(exitFlashMS-g.exitFlashMS)/1000/exitFlashPeriodS)
+	}
+	disc(screen, exit.X, exit.Y, exitFlashRadius, fade(pal.Danger, uint8(0x70*a)))
+	ring(screen, exit.X, exit.Y, exitFlashRadius, 3, fade(pal.Danger, uint8(0xFF*a)))
+}
@@ -0,0 +1,70 @@
+package datagame
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Lives: escaping enemies wear down HP, and lives are how many times it can
+// run out. Running out refills HP at the cost of a life, and on the last life
+// ends the run. The shop's Consumables tab sells HP repairs and lives back,
+// each dearer than the one before, and now and then a challenge offers
+// healing in place of its usual tower reward (Tab or the reward button
+// switches).
+
+const (
+	PlayerLives    = 2 // at the start of a run
+	PlayerMaxLives = 5
+	RepairHP       = 25.0 // per repair bought
+	RepairBaseCost = 40
+	LifeBaseCost   = 150
+	RewardHealHP   = 20.0 // a challenge's healing reward
+	healOfferOdds  = 0.3  // that a challenge offers healing while HP isn't full
+)
+
+// spendLife is called when HP runs out: it refills HP from a spare life, or
+// ends the run on the last one
+func (g *Game) spendLife() {
+	g.playerLives--
+	if g.playerLives <= 0 {
+		g.playerLives, g.playerHP = 0, 0
+		g.endRun("GAME OVER")
+		return
+	}
+	g.playerHP = PlayerMaxHP
+	g.levelMsg = trf("A life lost! %d left", g.playerLives)
+	g.levelMsgTimer = 3000
+}
+
+// heal restores hp, up to PlayerMaxHP
+func (g *Game) heal(hp float64) {
+	g.playerHP = math.Min(PlayerMaxHP, g.playerHP+hp)
+}
+
+// offerHeal decides whether the challenge just opened offers healing. It rolls
+// the question generator, which only this player's questions draw on, so it
+// can't put networked games out of step.
+func (g *Game) offerHeal() {
+	g.healOffer = g.playerHP < PlayerMaxHP && g.qrand.Float64() < healOfferOdds
+	g.healChosen = false
+}
+
+// updateHealOffer switches the open challenge's reward with Tab
+func (g *Game) updateHealOffer() {
+	if g.healOffer && inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		g.healChosen = !g.healChosen
+	}
+}
+
+// healButton shows the open challenge's reward, and switches it when clicked
+func (g *Game) healButton() Button {
+	r := g.challengeRect()
+	text := tr("Reward: tower")
+	if g.healChosen {
+		text = trf("Reward: +%.0f HP", RewardHealHP)
+	}
+	return Button{Rect: Rect{r.X + r.W - 190, r.Y + 72, 180, 24}, Text: text, Active: g.healChosen,
+		OnClick: func() { g.healChosen = !g.healChosen }}
+}
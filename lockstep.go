@@ -0,0 +1,440 @@
+package datagame
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"hash/fnv"
+	"math"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Lockstep: two games on a network run one shared battlefield by running the
+// same simulation. They start the host's run, from its seed, map, start level
+// and wave set, whatever the joiner's own flags and settings say, and from
+// then on only player actions travel: each Command issued on tick t is sent
+// to the other game and applied by both on tick t+lockstepDelay, in player
+// order. A game whose tick has no inputs from the other yet waits for them,
+// so neither runs ahead. Every lockstepHashEvery ticks both games hash their
+// state and compare; a mismatch means the simulations have drifted apart, and
+// is reported.
+//
+// Networked co-op is built on it: one player hosts with -coop-host, the other
+// joins with -coop-join, and both defend the same path with shared gold. The
+// game waits in a lobby until they connect, where Esc plays solo instead.
+// Questions are each player's own (they come from qrand, outside the shared
+// state); the towers and upgrades they earn are Commands. The teacher's
+// answer gate and session length are each player's own too, so they are off
+// while the games share a run, and the next wave starts when its pause runs
+// out or either player starts it, which is a Command as well.
+//
+// Both games should run at the normal tick rate: the simulation advances one
+// fixed step per tick, so a game on the low tick rate slows the match down.
+
+var (
+	lockstepHost = flag.String("coop-host", "", "host networked co-op on one shared battlefield, listening on this address, e.g. :7778")
+	lockstepJoin = flag.String("coop-join", "", "join the networked co-op game hosted at this address, e.g. 192.168.1.20:7778")
+)
+
+const (
+	lockstepProtocol  = 2
+	lockstepDelay     = 6  // ticks from issuing a command to applying it, covering the round trip
+	lockstepHashEvery = 60 // ticks between state hash checks
+	lockstepStepMS    = 1000.0 / normalTPS
+	lockstepWaitShow  = 500.0 // ms of waiting on the other game before saying so
+)
+
+// lockstepMsg is one message between the two games. "hello" (host to joiner)
+// starts the game with Seed, on Map from Level with the Waves set; a content
+// folder map comes with its file in MapData, and Waves is nil for the game's
+// own waves. "inputs" carries the sender's Commands for Tick, one message for
+// every tick, in order; "hash" is the sender's state hash at the start of
+// Tick. "closed" is never sent: the connection goroutines post it when the
+// link drops, with err set.
+type lockstepMsg struct {
+	Type     string          `json:"type"`
+	Protocol int             `json:"protocol,omitempty"`
+	Seed     int64           `json:"seed,omitempty"`
+	Map      string          `json:"map,omitempty"`
+	MapData  json.RawMessage `json:"map_data,omitempty"`
+	Level    int             `json:"level,omitempty"`
+	Waves    json.RawMessage `json:"waves,omitempty"`
+	Tick     int             `json:"tick"`
+	Commands []Command       `json:"commands,omitempty"`
+	Hash     uint64          `json:"hash,omitempty"`
+	err      error
+}
+
+// Lockstep is the connection to the other game and the tick bookkeeping
+type Lockstep struct {
+	host bool
+	addr string
+	in   chan lockstepMsg // from the connection goroutines, drained each Update
+	out  chan lockstepMsg // to the writer goroutine
+	done chan struct{}    // closed by close, to stop the writer
+	// mu guards what the connecting goroutine sets, so close can undo it
+	mu       sync.Mutex
+	listener net.Listener
+	conn     net.Conn
+	closed   bool
+	started  bool
+	err      error
+	self     int               // this game's player number: 0 hosting, 1 joined
+	tick     int               // the next tick to run
+	pending  []Command         // issued since the last tick, sent with the next
+	inputs   map[int][]Command // both players' commands, by the tick they land on
+	heard    int               // ticks the other game's inputs are in for
+	ours     map[int]uint64    // state hashes by tick, until the other game's arrives
+	theirs   map[int]uint64
+	desync   int     // first tick the hashes differed, 0 while in sync
+	waitMS   float64 // how long this game has waited on the other
+}
+
+// lockstepFromFlags starts hosting or joining when -coop-host or -coop-join
+// is given, else returns nil. A host offers g's map, start level and wave set.
+func (g *Game) lockstepFromFlags() *Lockstep {
+	switch {
+	case *lockstepHost != "":
+		hello := lockstepMsg{Type: "hello", Protocol: lockstepProtocol, Map: g.mapDef.File, Level: g.startLevel}
+		if it := usableContent(contentMap, g.mapDef.File); it != nil && !it.builtIn {
+			hello.MapData = it.data
+		}
+		if it := usableContent(contentWaves, g.settings.ContentWaves); it != nil && !it.builtIn {
+			hello.Waves = it.data
+		}
+		return startLockstep(true, *lockstepHost, hello)
+	case *lockstepJoin != "":
+		return startLockstep(false, *lockstepJoin, lockstepMsg{})
+	}
+	return nil
+}
+
+// startLockstep listens for or dials the other game in the background; a
+// host sends hello once the joiner is in
+func startLockstep(host bool, addr string, hello lockstepMsg) *Lockstep {
+	l := &Lockstep{host: host, addr: addr, in: make(chan lockstepMsg, 256), out: make(chan lockstepMsg, 256), done: make(chan struct{}),
+		inputs: map[int][]Command{}, ours: map[int]uint64{}, theirs: map[int]uint64{}, heard: lockstepDelay}
+	if !host {
+		l.self = 1
+	}
+	go func() {
+		conn, err := l.connect()
+		if err != nil {
+			l.in <- lockstepMsg{Type: "closed", err: err}
+			return
+		}
+		l.mu.Lock()
+		l.conn = conn
+		if l.closed {
+			conn.Close()
+		}
+		l.mu.Unlock()
+		go l.read(conn)
+		go l.write(conn)
+		if host {
+			hello.Seed = time.Now().UnixNano()
+			l.out <- hello
+			l.in <- hello
+		}
+	}()
+	return l
+}
+
+func (l *Lockstep) connect() (net.Conn, error) {
+	if !l.host {
+		return net.DialTimeout("tcp", l.addr, versusDialTimeout)
+	}
+	ln, err := net.Listen("tcp", l.addr)
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+	l.mu.Lock()
+	l.listener = ln
+	if l.closed {
+		ln.Close()
+	}
+	l.mu.Unlock()
+	return ln.Accept()
+}
+
+func (l *Lockstep) read(conn net.Conn) {
+	dec := json.NewDecoder(conn)
+	for {
+		var m lockstepMsg
+		if err := dec.Decode(&m); err != nil {
+			l.in <- lockstepMsg{Type: "closed", err: err}
+			return
+		}
+		l.in <- m
+	}
+}
+
+func (l *Lockstep) write(conn net.Conn) {
+	enc := json.NewEncoder(conn)
+	for {
+		select {
+		case m := <-l.out:
+			if enc.Encode(m) != nil {
+				return
+			}
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// send queues m for the other game. Unlike a versus message it can't be
+// dropped, as the other game would wait forever for the tick, so a backed-up
+// link holds the frame instead.
+func (l *Lockstep) send(m lockstepMsg) {
+	select {
+	case l.out <- m:
+	case <-l.done:
+	}
+}
+
+// close hangs up, or stops listening or dialling if still in the lobby
+func (l *Lockstep) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	l.closed = true
+	close(l.done)
+	if l.listener != nil {
+		l.listener.Close()
+	}
+	if l.conn != nil {
+		l.conn.Close()
+	}
+}
+
+// issue queues this player's command for the next tick's inputs
+func (l *Lockstep) issue(c Command) {
+	c.Player = l.self
+	l.pending = append(l.pending, c)
+}
+
+// updateLockstep handles the other game's messages. It reports true while the
+// lobby is up, when nothing else should run.
+func (g *Game) updateLockstep() bool {
+	l := g.lockstep
+	for {
+		var m lockstepMsg
+		select {
+		case m = <-l.in:
+		default:
+			if !l.started {
+				if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+					l.close()
+					g.lockstep = nil
+				}
+				return true
+			}
+			return false
+		}
+		switch m.Type {
+		case "hello":
+			if m.Protocol != lockstepProtocol {
+				l.err = errors.New(tr("the other game is a different version"))
+				l.close()
+				continue
+			}
+			ng, err := newLockstepGame(m)
+			if err != nil {
+				l.err = err
+				l.close()
+				continue
+			}
+			g.startRun(ng)
+			g.lockstep = l
+			l.started = true
+			g.levelMsg = tr("Co-op started: one battlefield, shared gold")
+			g.levelMsgTimer = 3000
+		case "inputs":
+			for _, c := range m.Commands {
+				c.Player = 1 - l.self
+				l.inputs[m.Tick] = append(l.inputs[m.Tick], c)
+			}
+			l.heard = m.Tick + 1
+		case "hash":
+			l.theirs[m.Tick] = m.Hash
+			g.checkLockstepHash(m.Tick)
+		case "closed":
+			if !l.started {
+				l.err = m.err
+				continue
+			}
+			l.close()
+			g.lockstep = nil
+			// back to this player's own wave set, from the next enemy
+			useWaveSet(g.settings.ContentWaves)
+			g.spawnInt = max(g.spawnInt, waveTuning.SpawnIntervalMin)
+			g.levelMsg = tr("The other player disconnected; play on solo")
+			g.levelMsgTimer = 3000
+			return false
+		}
+	}
+}
+
+// newLockstepGame is the run hello starts on both games: the host's seed,
+// map, start level and wave set
+func newLockstepGame(hello lockstepMsg) (*Game, error) {
+	waves := builtinWaves
+	if hello.Waves != nil {
+		w, err := parseWaveTuning(hello.Waves)
+		if err != nil {
+			return nil, errors.New(tr("the host's wave set didn't load: ") + err.Error())
+		}
+		waves = w
+	}
+	var m *MapDef
+	var err error
+	if hello.MapData != nil {
+		m, err = parseContentMap(hello.Map, hello.MapData)
+	} else {
+		m, err = loadMap(hello.Map)
+	}
+	if err != nil {
+		return nil, errors.New(tr("the host's map didn't load: ") + err.Error())
+	}
+	// the run's first rolls already use the shared waves
+	waveTuning = waves
+	g := newGameSeeded(hello.Seed)
+	g.useMap(m)
+	g.skipTo(hello.Level)
+	return g, nil
+}
+
+// stepLockstep runs the next tick's commands once the other game's are in,
+// sending this player's for the tick lockstepDelay ahead. It reports false
+// while waiting, when the simulation must hold still.
+func (g *Game) stepLockstep(dt float64) bool {
+	l := g.lockstep
+	if l.heard <= l.tick {
+		l.waitMS += dt
+		return false
+	}
+	l.waitMS = 0
+	if l.tick > 0 && l.tick%lockstepHashEvery == 0 {
+		l.ours[l.tick] = g.stateHash()
+		l.send(lockstepMsg{Type: "hash", Tick: l.tick, Hash: l.ours[l.tick]})
+		g.checkLockstepHash(l.tick)
+	}
+	at := l.tick + lockstepDelay
+	l.inputs[at] = append(l.inputs[at], l.pending...)
+	l.send(lockstepMsg{Type: "inputs", Tick: at, Commands: l.pending})
+	l.pending = nil
+	cmds := l.inputs[l.tick]
+	delete(l.inputs, l.tick)
+	// the two games heard these in different orders; player order is the same on both
+	sort.SliceStable(cmds, func(i, j int) bool { return cmds[i].Player < cmds[j].Player })
+	for _, c := range cmds {
+		g.apply(c)
+	}
+	l.tick++
+	return true
+}
+
+// checkLockstepHash compares both games' hashes for tick once both are in
+func (g *Game) checkLockstepHash(tick int) {
+	l := g.lockstep
+	ours, ok1 := l.ours[tick]
+	theirs, ok2 := l.theirs[tick]
+	if !ok1 || !ok2 {
+		return
+	}
+	delete(l.ours, tick)
+	delete(l.theirs, tick)
+	if ours != theirs && l.desync == 0 {
+		l.desync = tick
+		g.levelMsg = trf("Out of sync with the other game since tick %d", tick)
+		g.levelMsgTimer = 5000
+	}
+}
+
+// stateHash sums up the shared simulation: what both games must agree on
+func (g *Game) stateHash() uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	add := func(v float64) {
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+		h.Write(buf[:])
+	}
+	paused := 0.0
+	if g.paused {
+		paused = 1
+	}
+	add(float64(g.level))
+	add(g.playerHP)
+	add(float64(g.playerLives))
+	for _, n := range g.items {
+		add(float64(n))
+	}
+	add(float64(g.playerGold))
+	add(float64(g.enemiesSpawned))
+	add(float64(g.speedIdx))
+	add(paused)
+	for _, e := range g.enemies {
+		add(float64(e.Serial))
+		add(e.Dist)
+		add(e.HP)
+	}
+	for _, tw := range g.towers {
+		add(tw.X)
+		add(tw.Y)
+		add(float64(tw.Upgrades))
+		h.Write([]byte(tw.Type))
+	}
+	return h.Sum64()
+}
+
+// lockstepLocked stops this game leaving the shared run on its own; it
+// reports true, and says so, under lockstep
+func (g *Game) lockstepLocked() bool {
+	if g.lockstep == nil {
+		return false
+	}
+	g.levelMsg = tr("Not during networked co-op: both games share this run")
+	g.levelMsgTimer = 2000
+	return true
+}
+
+// drawLockstep draws the lobby while connecting, and a notice while waiting
+// on the other game
+func (g *Game) drawLockstep(screen *ebiten.Image) {
+	l := g.lockstep
+	if l == nil {
+		return
+	}
+	if !l.started {
+		p := Panel{Rect: g.centered(440, 110), Title: tr("Co-op")}
+		p.Draw(screen)
+		x, y := p.Content()
+		msg := trf("Connecting to %s...", l.addr)
+		if l.host {
+			msg = trf("Waiting for player 2 on %s", l.addr)
+		}
+		drawText(screen, msg, x, y, pal.Text)
+		if l.err != nil {
+			drawText(screen, tr("Couldn't connect: ")+l.err.Error(), x, y+22, pal.Bad)
+		}
+		drawText(screen, tr("Esc: play solo instead"), x, y+44, pal.TextDim)
+		return
+	}
+	if l.waitMS >= lockstepWaitShow {
+		p := Panel{Rect: g.centered(320, 50), Title: tr("Co-op")}
+		p.Draw(screen)
+		x, y := p.Content()
+		drawText(screen, tr("Waiting for the other player..."), x, y, pal.Warn)
+	}
+}
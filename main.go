@@ -1,17 +1,19 @@
-package main
+package datagame
 
 import (
+	"flag"
 	"fmt"
+	"image"
 	"image/color"
 	"math"
 	"math/rand"
-	"strconv"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
-	"github.com/hajimehoshi/ebiten/v2/text"
-	"golang.org/x/image/font/basicfont"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
 const (
@@ -19,48 +21,51 @@ const (
 	ScreenH = 600
 )
 
-// --- tuning constants for enemy scaling and waves ---
+// map (world) size; the camera pans and zooms over it
+const (
+	MapW = 800
+	MapH = 600
+)
+
+// --- player tuning; enemy and wave tuning is in data/waves.json (balance.go) ---
 const (
-	// enemy HP base range (float)
-	EnemyBaseHPMin = 100.0
-	EnemyBaseHPMax = 200.0
-	// per-level HP scale factor applied to base: hp = base * (1 + (level-1)*EnemyHPScalePerLevel)
-	EnemyHPScalePerLevel = 0.18
-	// armor added per level
-	EnemyArmorPerLevel = 0.5
-	// speed base and random range, and incremental speed per level
-	EnemySpeedBase     = 10.0
-	EnemySpeedRandMax  = 40.0
-	EnemySpeedPerLevel = 2.0
-	// enemies per level (inclusive range)
-	EnemiesPerLevelMin = 30
-	EnemiesPerLevelMax = 50
-	// spawn interval base (ms) and how much it reduces per level
-	SpawnIntervalBase  = 2000.0
-	SpawnIntervalDecay = 150.0
-	// minimum spawn interval allowed
-	SpawnIntervalMin = 600.0
 	// player escape base damage before armor mitigation
 	PlayerEscapeBaseDamage = 10.0
+	// player base health at the start of a run
+	PlayerMaxHP = 100.0
 )
 
-// inter-level pause (ms)
-const InterLevelPauseMS = 20000.0
-
 type Vec struct{ X, Y float64 }
 
 type Enemy struct {
-	HP    float64
-	MaxHP float64
-	Armor float64
-	Speed float64 // px/sec
-	T     float64 // progress along path
+	HP       float64
+	MaxHP    float64
+	Armor    float64
+	Speed    float64 // px/sec
+	Dist     float64 // progress: distance travelled along the path (px)
+	PrevDist float64 // Dist at the start of the current tick, for render interpolation
+	Pos      Vec     // map position at Dist, cached once per tick after moving
+	Serial   int     // spawn number, unique in a run; 0 once removed
 	// status effects
 	BurnTime   float64 // ms remaining
 	BurnLevel  int     // damage multiplier level for burn
 	BurnTick   float64 // accumulator for burn tick interval (ms)
+	BurnDue    int     // burn ticks fallen due in updateStatus, not yet dealt
 	SlowTime   float64 // ms remaining for slow
 	SlowFactor float64 // multiplier applied to speed when slowed (0-1)
+	FreezeTime float64 // ms remaining held in place by a freeze bomb
+	BurnSrc    *Tower  // flame tower whose burn is ticking, credited with its damage
+	LastHit    *Tower  // tower that last damaged the enemy, credited with the kill
+	Anim       AnimState
+	// hit feedback: a white flash and a nudge away from the shot, both decaying
+	HitFlash float64 // ms remaining
+	Knock    Vec     // render offset (px)
+	Warned   bool    // leak warning given
+	Bounty   int     // gold for the kill
+	// boss enemies carry a shield that blocks all damage until answers strip it
+	Boss      bool
+	Shield    float64
+	MaxShield float64
 }
 
 type Tower struct {
@@ -69,10 +74,19 @@ type Tower struct {
 	Damage float64
 	Fire   float64 // ms
 	Cd     float64
-	Type   string // "normal", "flame", "slow"
+	Type   string  // "normal", "flame", "slow"
+	Angle  float64 // turret heading in radians, toward the current target
+	Anim   AnimState
+	// number of challenge upgrades applied to this tower
+	Upgrades int
 	// optional for special towers
 	FlameDuration float64 // ms that a flame effect lasts on target when hit
 	PulseDuration float64 // ms that a slow pulse lasts on enemy
+	// run totals for the damage leaderboard
+	DamageDealt float64
+	Kills       int
+	// Owner is 1 for player 2's towers in co-op, else 0
+	Owner int
 }
 
 type Bullet struct {
@@ -82,30 +96,185 @@ type Bullet struct {
 	Damage      float64
 	Penetration float64
 	AoeRadius   float64
-}
-
-type Question struct {
-	Text string
-	Ans  int
+	Src         *Tower // tower that fired it
+	// the enemy it homes in on while that enemy lives; TargetSerial guards
+	// against the Enemy having been recycled for a new spawn
+	Target       *Enemy
+	TargetSerial int
+	Age          float64 // ms since fired
+	// recent positions, newest at trail[(trailN-1)%len], for the fading trail
+	trail  [bulletTrailLen]Vec
+	trailN int
 }
 
 type Game struct {
-	path    []Vec
-	enemies []*Enemy
-	towers  []*Tower
-	bullets []*Bullet
+	mapDef      *MapDef
+	mapAmbience []Ambience // the map's ambient loops, played under the music
+	background  background
+	path        []Vec
+	pathCum     []float64 // distance along the path to each waypoint; see setPath
+	enemies     []*Enemy
+	towers      []*Tower
+	bullets     []*Bullet
+	// visual effects only; updated with game time so they freeze while paused
+	particles *Particles
+	fxMS      float64 // effect clock for shaders
+	blasts    []Blast
+	corpses   []Corpse
+	coins     []Coin // in view coordinates, drawn over the HUD
+	popups    []GoldPopup
+	// enemies in draw order for the current frame, reused between frames
+	drawnEnemies []drawnEnemy
+	// world area in view this frame; anything outside it is not drawn
+	cull Rect
+	// enemies bucketed by position for tower and AoE range queries, rebuilt
+	// each tick, and a scratch buffer for query results
+	grid    enemyGrid
+	nearBuf []*Enemy
 
 	lastSpawn float64
 	spawnInt  float64
 
 	selected  int
 	lastClick Vec
+	buildType int // index into towerTypes of the tower the next build makes
+	// keepBuilding leaves build mode on after a build (placement confirmed with shift-click)
+	keepBuilding bool
+	// tower being dragged to a new spot, if any
+	drag *towerDrag
 
 	challengeActive bool
 	question        *Question
 	inputBuf        string
+	// scratch buffer for ebiten.AppendInputChars
+	inputChars []rune
+	// challengeReward runs on a correct answer; nil falls back to applyReward
+	challengeReward func()
+	// the open challenge offers healing in place of applyReward, and the
+	// player has picked it
+	healOffer  bool
+	healChosen bool
+	// math-gated mode: every build, upgrade and purchase must be paid for with a correct answer
+	mathGated bool
+	// today's daily challenge (J) when this run is one, and the score
+	// server's answer while it is being fetched
+	daily      *dailyChallenge
+	dailyFetch chan dailyChallenge
+	// horde mode (H) makes levels from the next one on hordes; hordeLevel is
+	// whether the current level is one
+	horde, hordeLevel bool
+	// HUD text kept between frames while its values don't change, and the
+	// buttons list reused by buttons()
+	hud       hudMemos
+	buttonBuf []Button
+	// removed enemies kept for reuse, and the batch crowds are drawn with;
+	// enemySerial numbers spawns (see Enemy.Serial)
+	enemyPool   []*Enemy
+	batch       spriteBatch
+	enemySerial int
+	// time the current question has been open (ms, real time)
+	challengeElapsed float64
+	// right answers in a row, for the streak jingles
+	answerStreak int
+	// learner profile (answer history across runs) and the report overlay
+	profile      *Profile
+	reportActive bool
+	reportPage   int // 0 = per operation, 1 = times-table mastery
+	// online score board (U) and this run's score submission
+	online *onlineScores
+	// player 2 in local co-op, nil until a gamepad joins
+	coop *coopPlayer
+	// the networked versus match (-host / -join), nil when playing alone
+	versus *versus
+	// networked co-op on one shared battlefield (-coop-host / -coop-join),
+	// kept in step by lockstep.go; nil when playing alone
+	lockstep *Lockstep
+	// multiplayer chat messages and emotes
+	chat chatBox
+	// classroom mode: student is this game's link to the teacher's dashboard
+	// (-classroom); dashboard replaces the game on the teacher's machine
+	// (-classroom-host)
+	student   *classroomStudent
+	dashboard *classroomDashboard
+	// spectator mode: spectators sends this game to anyone watching
+	// (-spectate-host); watch replaces the game with a view of another one
+	// (-spectate)
+	spectators *spectateServer
+	watch      *spectateView
+	// practice drill (no tower defense) when non-nil
+	drill *Drill
+	// missed questions waiting to be asked again in later waves
+	reviews ReviewQueue
+	// player options and the settings overlay
+	settings       *Settings
+	settingsActive bool
+	settingsRow    int
+	settingsPage   int  // 0 = options, 1 = controls
+	rebinding      bool // waiting for a key on the controls page
+	bindMsg        string
+	// every question answered this run, and the log overlay
+	history       []HistoryEntry
+	historyActive bool
+	historyScroll int
+	// logical view size from Layout, the offscreen map and the camera showing it
+	viewW, viewH int
+	worldImg     *ebiten.Image
+	pausedImg    *ebiten.Image // worldImg desaturated while paused
+	camera       Camera
+	// tower coverage heatmap overlay (V)
+	showCoverage bool
+	showDamage   bool // tower damage leaderboard (K)
+	photo        *PhotoMode
+	content      *ContentManager // the content overlay (I), nil when closed
+	coverage     coverage
+	// shop hold-to-repeat: held Buy button (-1 none), time held, purchases made
+	shopHoldIdx int
+	shopHoldMS  float64
+	shopRepeats int
+	// open yes/no dialog, nil when none
+	confirm *ConfirmDialog
+	// run ended (player HP reached 0 or the teacher's session ran out)
+	gameOver  bool
+	endReason string
+	// the tech tree, the points this run earned and the tree's overlay on
+	// the game-over screen
+	tech       *TechTree
+	techEarned int
+	techScreen *TechScreen
+	// teacher mode: curriculum config, PIN/panel overlay state
+	teacher      *TeacherConfig
+	teacherState int
+	teacherRow   int
+	teacherMsg   string
+	sessionStart time.Time
+	playTime     float64 // ms of tower defense played this run
+	runMS        float64 // game time played this run (ms), the replay clock
+	waveAnswered int     // questions answered since the current wave started
+	// this run's replay, filed when it ends, and the replay being raced (-ghost)
+	replay Replay
+	ghost  *Replay
+	// per-level stats, and the finished level's copy shown in the inter-level pause
+	wave     WaveStats
+	lastWave WaveStats
+	// whole-run history for the end-screen graphs
+	waves        []WaveStats // every finished level, in order
+	goldHistory  []int       // gold sampled every runGoldSampleMS of game time
+	goldSampleMS float64
+
+	tickAt time.Time // start of the last Update, for render interpolation
 
 	rand *rand.Rand
+	seed int64 // seed of rand, shown in the F3 debug overlay
+	// startLevel is the level the run began on, past 1 with -level
+	startLevel int
+	// customContent is set once the run uses the content folder's waves or
+	// questions
+	customContent bool
+	// qrand picks questions, apart from rand so each player's own questions
+	// leave the shared simulation alone under lockstep
+	qrand *rand.Rand
+	// F3 debug overlay
+	debugActive bool
 	// level progression
 	killCount          int
 	nextLevelThreshold int
@@ -117,8 +286,34 @@ type Game struct {
 	enemiesSpawned int
 	// player stats
 	playerHP    float64
+	hpFlashMS   float64 // HUD health bar flashes red after an escape
+	playerLives int     // times HP can run out before the run ends
 	playerArmor float64
 	playerGold  int
+	// challenge upgrades' extra effect from the tech tree, as a fraction
+	upgradeBonus float64
+	// HP repairs and lives bought so far, which raise their prices
+	repairsBought int
+	livesBought   int
+	// consumables held, by itemKind; aiming is an airstrike waiting for its
+	// spot, and goldRushMS what is left of a gold rush
+	items      [3]int
+	aiming     bool
+	goldRushMS float64
+	// the run's score and combo (see scoring.go); at the end, its stars on
+	// the map and whether it beat the map's record
+	score     int
+	combo     int
+	bestCombo int
+	runStars  int
+	newBest   bool
+	records   Records
+	// leak warning: the exit flashes and an alarm sounds as enemies near it
+	exitFlashMS     float64
+	alarmCooldownMS float64
+	// impact sounds are rate limited
+	impactCooldownMS float64
+	leakCooldownMS   float64
 	// shop / upgrades
 	shopActive bool
 	// upgrade levels
@@ -126,35 +321,96 @@ type Game struct {
 	upSpeedLevel  int
 	upPenLevel    int
 	upAOELevel    int
+	upFlameLevel  int
+	upSlowLevel   int
+	// shop overlay: selected tab and first visible line
+	shopTab    int
+	shopScroll int
 	// inter-level pause
 	interLevelActive bool
 	interLevelTimer  float64 // ms
+	// simulation speed: index into gameSpeeds, and the Space pause
+	speedIdx int
+	paused   bool
+	// holding Space (or a gamepad's right trigger) fast-forwards; pauseHeldMS
+	// is how long Space has been down, to tell a hold from a pause tap
+	fastForward bool
+	pauseHeldMS float64
+	gamepads    []ebiten.GamepadID // scratch buffer for ebiten.AppendGamepadIDs
 }
 
+// NewGame starts a fresh run on a new seed, or on the seed, map and level
+// the launch flags ask for
 func NewGame() *Game {
+	seed := *launchSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	g := newGameSeeded(seed)
+	g.launch()
+	g.useTech()
+	return g
+}
+
+// startRun replaces the run in place with ng, keeping what lasts the whole
+// session: the connections to the classroom dashboard and to spectators
+func (g *Game) startRun(ng *Game) {
+	student, spectators := g.student, g.spectators
+	*g = *ng
+	g.student, g.spectators = student, spectators
+}
+
+// newGameSeeded starts a run whose randomness (paths, spawns, questions)
+// comes from seed, so two games with the same seed start out the same
+func newGameSeeded(seed int64) *Game {
 	g := &Game{
-		path:     []Vec{{0, 300}, {200, 300}, {200, 100}, {600, 100}, {600, 400}, {800, 400}},
-		spawnInt: SpawnIntervalBase,
-		selected: -1,
-		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
-	}
-	// starter tower
-	g.towers = append(g.towers, &Tower{X: 150, Y: 220, Range: 120, Damage: 2, Fire: 700, Cd: 0, Type: "normal"})
-	// flame tower
-	g.towers = append(g.towers, &Tower{X: 300, Y: 220, Range: 100, Damage: 0, Fire: 200, Cd: 0, Type: "flame", FlameDuration: 5000})
-	// slowing tower (pulse)
-	g.towers = append(g.towers, &Tower{X: 450, Y: 220, Range: 140, Damage: 0, Fire: 1500, Cd: 0, Type: "slow", PulseDuration: 1200})
+		seed:        seed,
+		mapDef:      mustLoadMap(defaultMap),
+		spawnInt:    waveTuning.SpawnIntervalBase,
+		selected:    -1,
+		rand:        rand.New(rand.NewSource(seed)),
+		qrand:       rand.New(rand.NewSource(^seed)),
+		viewW:       ScreenW,
+		viewH:       ScreenH,
+		camera:      newCamera(),
+		particles:   newParticles(),
+		shopHoldIdx: -1,
+		profile:     loadProfile(),
+		settings:    loadSettings(),
+		online:      newOnlineScores(),
+		teacher:     loadTeacherConfig(),
+		tech:        loadTechTree(),
+		records:     loadRecords(),
+	}
+	// tech unlocks are off unless useTech turns them on for the run
+	runTech = nil
+	towerTypes = towerTypeList(towerStats)
+	launchSettings(g.settings)
+	g.noteContent()
+	g.sessionStart = time.Now()
+	g.setPath(g.mapDef.Waypoints())
+	g.mapAmbience = g.mapDef.Ambience()
+	setLanguage(g.settings.Language)
+	setTheme(g.settings.Theme)
+	applyTickRate(g.settings)
+	// one starter tower of each type
+	for i, typ := range builtinTowers {
+		g.towers = append(g.towers, newTower(typ, Vec{150 + 150*float64(i), 220}))
+	}
 	// initial level threshold
-	g.nextLevelThreshold = 20 + g.rand.Intn(11) // 20..30
+	g.nextLevelThreshold = g.killsToAdvance()
 	g.level = 1
+	g.wave.Level = g.level
 	// per-level spawn targets
-	g.enemiesToSpawn = EnemiesPerLevelMin + g.rand.Intn(EnemiesPerLevelMax-EnemiesPerLevelMin+1)
+	g.enemiesToSpawn = g.enemiesPerLevel()
 	g.enemiesSpawned = 0
+	g.runLevelScript()
 	// do not start an inter-level pause at game start; first level should begin immediately
 	g.interLevelActive = false
 	g.interLevelTimer = 0
 	// player defaults
-	g.playerHP = 100.0
+	g.playerHP = PlayerMaxHP
+	g.playerLives = PlayerLives
 	g.playerArmor = 2.0
 	g.playerGold = 0
 	// upgrades
@@ -163,112 +419,341 @@ func NewGame() *Game {
 	g.upSpeedLevel = 0
 	g.upPenLevel = 0
 	g.upAOELevel = 0
+	g.upFlameLevel = 0
+	g.upSlowLevel = 0
 	return g
 }
 
-func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) { return ScreenW, ScreenH }
-
 func (g *Game) Update() error {
-	dt := 1.0 / 60.0 * 1000.0 // ms per frame approx
+	dt := tickMS()
+	touch.update()
+	// a spectator only shows the watched game's snapshots, silently
+	if g.watch != nil {
+		g.updateWatch(dt)
+		return nil
+	}
+	g.markTick()
+	g.updateDev(dt)
+	g.updateClip()
+	g.updateDiscord(dt)
+	g.updateAudio(dt)
+	if g.dashboard != nil {
+		g.updateDashboard()
+		return nil
+	}
+	g.updateStudent(dt)
+	g.updateSpectators(dt)
+	// a versus match holds the game in its lobby until the opponent connects
+	if g.versus != nil && g.updateVersus(dt) {
+		return nil
+	}
+	// and networked co-op until the other player connects
+	if g.lockstep != nil && g.updateLockstep() {
+		return nil
+	}
+
+	if g.gameOver {
+		g.updateGameOver()
+		return nil
+	}
+
+	// teacher overlay pauses everything while open
+	if g.teacherState != teacherClosed {
+		g.updateTeacher()
+		return nil
+	}
+	// waiting for a key to bind: nothing else sees the keyboard this frame
+	if g.rebinding {
+		g.captureBinding()
+		return nil
+	}
+	// an open confirmation dialog is modal
+	if g.confirm != nil {
+		g.updateConfirm()
+		return nil
+	}
+	// so is the content manager
+	if g.content != nil {
+		g.updateContent()
+		return nil
+	}
+	// a chat line being typed takes the keyboard, holding the game like the pause
+	if g.chat.typing {
+		g.updateChatLine()
+		return nil
+	}
+	g.updateDailyFetch()
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) && !g.challengeActive {
+		g.openTeacher()
+		return nil
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyI) && !g.challengeActive {
+		g.openContent()
+		return nil
+	}
+
+	// practice drill replaces the tower defense entirely while running
+	if g.drill != nil {
+		g.updateDrill(dt)
+		return nil
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) && !g.challengeActive {
+		g.startDrill()
+		return nil
+	}
+
+	// photo mode hides the HUD and holds the game still while the shot is framed
+	if g.photo != nil {
+		g.updatePhoto(dt)
+		return nil
+	}
+	if inpututil.IsKeyJustPressed(g.settings.Keys.Photo) && !g.challengeActive {
+		g.openPhoto()
+		return nil
+	}
+
+	// wheel zoom, WASD / middle-drag pan; over the build ghost the wheel picks the tower type
+	g.cycleBuildType()
+	g.updateCamera(dt)
+
+	// F3 toggles the debug overlay
+	if inpututil.IsKeyJustPressed(ebiten.KeyF3) {
+		g.debugActive = !g.debugActive
+	}
+
+	// F11 toggles fullscreen
+	if inpututil.IsKeyJustPressed(ebiten.KeyF11) {
+		toggleFullscreen()
+	}
+	// M mutes and unmutes all sound
+	if inpututil.IsKeyJustPressed(ebiten.KeyM) {
+		g.toggleMute()
+	}
+
+	// holding a shop Buy button repeats it; the wheel scrolls the item list
+	if g.shopActive {
+		g.updateShopHold(dt)
+		g.scrollShop()
+	}
+
+	// dragging a tower moves it; the release that drops it is not a click
+	dropped := g.updateTowerDrag()
+	// right-click backs out of whatever is in progress
+	if cancelJustPressed() && !g.challengeActive {
+		g.rightClick()
+	}
 
 	// input: mouse just released
-	if inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) {
-		x, y := ebiten.CursorPosition()
-		gx := float64(x)
-		gy := float64(y)
-		// if inter-level pause active, handle its clicks (Start now button)
-		if g.interLevelActive {
-			g.handleInterLevelClick(gx, gy)
-		}
-		// if shop active, handle purchase clicks
-		if g.shopActive {
-			g.handleShopClick(gx, gy)
-		}
-		// select near tower
-		sel := -1
-		for i, tw := range g.towers {
-			if math.Hypot(tw.X-gx, tw.Y-gy) < 18 {
-				sel = i
-				break
+	if pointerJustReleased() && !dropped {
+		ux, uy := cursor()
+		// world clicks are in map coordinates
+		gx, gy := g.toWorld(ux, uy)
+		// buttons take the click first; otherwise select near tower.
+		// While a challenge is open map clicks belong to the keypad
+		overShop := g.shopActive && g.shopRect().Contains(ux, uy)
+		if g.endShopHold() {
+			// holding Buy already repeated the purchase; the release isn't another click
+		} else if !pressButton(g.buttons(), ux, uy) && !g.challengeActive && !overShop {
+			if g.aiming {
+				g.aiming = false
+				g.do(Command{Kind: "item", Type: itemDefs[itemAirstrike].name, X: gx, Y: gy})
+			} else if sel := g.towerAt(gx, gy); sel >= 0 {
+				g.selected = sel
+			} else {
+				g.selected = -1
+				g.lastClick = Vec{gx, gy}
+				// shift-click confirms the spot straight away and stays in build mode
+				g.keepBuilding = shiftHeld()
+				if g.keepBuilding {
+					g.startChallenge()
+				}
 			}
 		}
-		if sel >= 0 {
-			g.selected = sel
-		} else {
-			g.selected = -1
-			g.lastClick = Vec{gx, gy}
-		}
 	}
 
-	// toggle challenge with C key
-	if inpututil.IsKeyJustPressed(ebiten.KeyC) && !g.challengeActive {
-		q := genQuestion(g.rand, g.level)
-		g.question = q
-		g.inputBuf = ""
-		g.challengeActive = true
+	// Space pauses (held, it fast-forwards), F cycles 1x/2x/4x; 1, 2 and 3 use items
+	if !g.challengeActive {
+		g.updateSpeedKeys(dt)
+		g.updateItems()
+	} else {
+		g.fastForward, g.pauseHeldMS = false, 0
+	}
+
+	// toggle challenge with C key (rebindable)
+	if inpututil.IsKeyJustPressed(g.settings.Keys.Challenge) && !g.challengeActive {
+		g.startChallenge()
+	}
+
+	// settings overlay (O) captures arrow keys while open
+	if inpututil.IsKeyJustPressed(ebiten.KeyO) && !g.challengeActive {
+		g.settingsActive = !g.settingsActive
+	}
+	if g.settingsActive {
+		g.updateSettings()
+	}
+
+	// toggle question history log with L key
+	if inpututil.IsKeyJustPressed(ebiten.KeyL) && !g.challengeActive {
+		g.historyActive = !g.historyActive
+		// open scrolled to the most recent entries
+		g.historyScroll = len(g.history) - historyRows(historyLogH)
+	}
+	if g.historyActive {
+		g.scrollHistory(historyRows(historyLogH))
+	}
+
+	// toggle performance report with R key
+	if inpututil.IsKeyJustPressed(ebiten.KeyR) && !g.challengeActive {
+		g.reportActive = !g.reportActive
+	}
+	if g.reportActive && inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		g.reportPage = 1 - g.reportPage
+	}
+	g.updateScoreBoard()
+	g.updateChat(dt)
+
+	// toggle the tower coverage heatmap with V key
+	if inpututil.IsKeyJustPressed(ebiten.KeyV) && !g.challengeActive {
+		g.showCoverage = !g.showCoverage
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyK) && !g.challengeActive {
+		g.showDamage = !g.showDamage
+	}
+
+	// toggle math-gated mode with G key
+	if inpututil.IsKeyJustPressed(ebiten.KeyG) && !g.challengeActive && !g.dailyLocked() {
+		g.do(Command{Kind: "mode", Type: "math-gated"})
 	}
 
-	// toggle shop with B key
-	if inpututil.IsKeyJustPressed(ebiten.KeyB) {
+	// toggle horde mode with H key
+	if inpututil.IsKeyJustPressed(ebiten.KeyH) && !g.challengeActive && !g.dailyLocked() {
+		g.do(Command{Kind: "mode", Type: "horde"})
+	}
+
+	// X / Delete sells the selected tower, N restarts the run; both ask first
+	if !g.challengeActive {
+		if inpututil.IsKeyJustPressed(ebiten.KeyX) || inpututil.IsKeyJustPressed(ebiten.KeyDelete) {
+			g.confirmSell()
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyN) && !g.lockstepLocked() {
+			g.confirmRestart()
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyJ) && !g.lockstepLocked() {
+			g.confirmDaily()
+		}
+	}
+
+	// toggle shop with B key (rebindable)
+	if inpututil.IsKeyJustPressed(g.settings.Keys.Shop) {
 		g.shopActive = !g.shopActive
 		// close challenge if shop opened
 		if g.shopActive {
-			g.challengeActive = false
+			g.closeChallenge()
 		}
 	}
 
 	// while challenge active, capture numeric keys, backspace and enter
 	if g.challengeActive {
-		// digits
-		digits := []ebiten.Key{ebiten.Key0, ebiten.Key1, ebiten.Key2, ebiten.Key3, ebiten.Key4, ebiten.Key5, ebiten.Key6, ebiten.Key7, ebiten.Key8, ebiten.Key9}
-		for k, d := range digits {
-			if inpututil.IsKeyJustPressed(d) {
-				g.inputBuf += strconv.Itoa(k)
-			}
-		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) {
-			if len(g.inputBuf) > 0 {
-				g.inputBuf = g.inputBuf[:len(g.inputBuf)-1]
+		g.challengeElapsed += dt
+		g.updateHealOffer()
+		submitted, cancelled := g.readAnswerInput()
+		if submitted {
+			g.waveAnswered++
+			g.wave.Answered++
+			correct := g.checkAnswer()
+			// the battle's answers score, not the practice drill's
+			g.scoreAnswer(correct, g.challengeElapsed)
+			if correct {
+				g.wave.Correct++
+				g.do(Command{Kind: "shield"})
+				g.versusSend()
+				switch {
+				case g.challengeReward != nil:
+					g.challengeReward()
+				case g.healChosen:
+					g.do(Command{Kind: "heal"})
+				default:
+					g.applyReward()
+				}
 			}
+			g.closeChallenge()
 		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyMinus) {
-			if len(g.inputBuf) == 0 {
-				g.inputBuf = "-"
-			}
+		// also allow closing with Escape
+		if cancelled {
+			g.closeChallenge()
 		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyKPEnter) {
-			// submit
-			ans, err := strconv.Atoi(g.inputBuf)
-			if err == nil && ans == g.question.Ans {
-				g.applyReward()
-			}
-			g.challengeActive = false
-			g.inputBuf = ""
+	}
+	g.updateCoop(dt)
+
+	// teacher session length
+	g.playTime += dt
+	if g.sessionExpired() {
+		g.endRun("SESSION COMPLETE")
+		return nil
+	}
+
+	if g.hpFlashMS > 0 {
+		g.hpFlashMS -= dt
+	}
+	g.exitFlashMS -= dt
+	g.alarmCooldownMS -= dt
+	g.impactCooldownMS -= dt
+	g.leakCooldownMS -= dt
+
+	// decrement level message timer
+	if g.levelMsgTimer > 0 {
+		g.levelMsgTimer -= dt
+		if g.levelMsgTimer < 0 {
+			g.levelMsgTimer = 0
+			g.levelMsg = ""
 		}
-		// also allow closing with Escape
-		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
-			g.challengeActive = false
-			g.inputBuf = ""
+	}
+
+	// the simulation runs on scaled time; UI timers above stay real time
+	sim := g.simDT(dt)
+	// under lockstep the simulation moves one fixed step a tick, and only once
+	// the other game's inputs for the tick are in
+	if g.lockstep != nil {
+		if !g.stepLockstep(dt) {
+			return nil
 		}
+		sim = g.simDT(lockstepStepMS)
+	}
+	if sim == 0 {
+		return nil
 	}
+	g.sampleGold(sim)
+	g.fxMS += sim
+	g.runMS += sim
+	g.particles.Update(sim)
+	g.updateBlasts(sim)
+	g.updateDeaths(sim)
 
 	// inter-level pause handling
 	if g.interLevelActive {
-		g.interLevelTimer -= dt
+		g.interLevelTimer -= sim
 		if g.interLevelTimer <= 0 {
-			g.interLevelActive = false
 			g.interLevelTimer = 0
-			// reset spawn counters for the level
-			g.enemiesSpawned = 0
-			g.lastSpawn = 0
+			// the teacher may require answers before the next wave
+			if g.waveGateRemaining() == 0 {
+				g.startWave()
+			}
 		}
 	} else {
 		// spawn: only while we haven't spawned the per-level total
-		g.lastSpawn += dt
+		g.lastSpawn += sim
 		if g.enemiesSpawned < g.enemiesToSpawn {
 			if g.lastSpawn > g.spawnInt {
-				g.spawnEnemy()
-				g.enemiesSpawned++
+				if g.isBossLevel() && g.enemiesSpawned == 0 {
+					g.spawnBoss()
+					g.enemiesSpawned++
+				} else {
+					// horde levels spawn a pack at a time
+					for range g.spawnPack() {
+						g.spawnEnemy()
+						g.enemiesSpawned++
+					}
+				}
 				g.lastSpawn = 0
 			}
 		} else {
@@ -279,70 +764,84 @@ func (g *Game) Update() error {
 		}
 	}
 
-	// update enemies
-	for i := len(g.enemies) - 1; i >= 0; i-- {
-		e := g.enemies[i]
-		seg := int(math.Floor(e.T))
-		segLen := 1.0
-		if seg < len(g.path)-1 {
-			segLen = dist(g.path[seg], g.path[seg+1])
+	// update enemies: movement may run across cores (see forEnemies); warnings
+	// and escapes touch shared state, so they follow in order
+	g.forEnemies(func(e *Enemy) {
+		if e.FreezeTime <= 0 {
+			e.Dist += e.Speed * sim / 1000.0
 		}
-		frac := (e.Speed * dt / 1000.0) / (segLen)
-		e.T += frac
-		if e.T >= float64(len(g.path)-1) {
+		e.Pos = g.posAlongPath(e.Dist)
+	})
+	// escaped enemies are dropped in one compaction pass, keeping spawn order
+	live := g.enemies[:0]
+	for _, e := range g.enemies {
+		g.checkLeakWarning(e)
+		if e.Dist >= g.pathLength() {
 			// reached end -> enemy escaped: damage the player (armor mitigates flat damage)
 			mitig := PlayerEscapeBaseDamage - g.playerArmor
 			if mitig < 1.0 {
 				mitig = 1.0
 			}
+			if e.Boss {
+				mitig *= BossEscapeMultiplier
+			} else if g.hordeLevel {
+				mitig *= hordeLeakScale
+			}
 			g.playerHP -= mitig
-			// remove enemy
-			g.enemies = append(g.enemies[:i], g.enemies[i+1:]...)
+			g.wave.Leaks++
+			g.combo = 0
+			g.leakCue()
+			g.hpFlashMS = hpFlashDuration
+			g.wave.HPLost += mitig
+			if g.playerHP <= 0 {
+				g.spendLife()
+			}
+			g.freeEnemy(e)
 			continue
 		}
+		live = append(live, e)
 	}
+	g.enemies = g.compactEnemies(live)
+	// index the enemies where they now stand for the range queries below
+	g.grid.rebuild(g.enemies)
 
 	// towers shooting
 	for _, tw := range g.towers {
-		tw.Cd -= dt
-		if tw.Cd <= 0 {
-			// find nearest target
-			var target *Enemy
-			best := 1e9
-			for _, e := range g.enemies {
-				p := g.posAlongPath(e.T)
-				d := math.Hypot(p.X-tw.X, p.Y-tw.Y)
-				if d <= tw.Range && d < best {
-					best = d
-					target = e
-				}
-			}
-			if target != nil {
-				p := g.posAlongPath(target.T)
+		tw.Cd -= sim
+		tw.updateTowerAnim(sim)
+		// find nearest target; the turret tracks it between shots
+		target, _ := g.grid.Nearest(tw.X, tw.Y, tw.Range)
+		if target != nil {
+			p := target.Pos
+			tw.aim(p.X, p.Y)
+			if tw.Cd <= 0 {
 				// fire
 				tw.Cd = tw.Fire
-				if tw.Type == "flame" {
+				tw.Anim.Play(fireAnim(tw.Type))
+				if kind := towerKind(tw.Type); kind == "flame" {
 					// flamethrower: apply burn status to target
-					target.BurnTime = math.Max(target.BurnTime, tw.FlameDuration)
+					target.BurnTime = math.Max(target.BurnTime, tw.FlameDuration+ShopFlameStepMS*float64(g.upFlameLevel))
 					// burn level scales with game level
 					target.BurnLevel = g.level
+					target.BurnSrc = tw
+					g.emitFlame(tw, p.X, p.Y)
 					// also create short lived visual bullet for flame
 					dmg := 100.0
 					// damage multiplier from upgrades: 10% per level
 					dmg *= 1.0 + 0.10*float64(g.upDamageLevel)
 					pen := float64(g.upPenLevel)
 					aoe := 0.0 + 4.0*float64(g.upAOELevel)
-					g.bullets = append(g.bullets, &Bullet{X: tw.X, Y: tw.Y, Tx: p.X, Ty: p.Y, Speed: 800, Damage: dmg, Penetration: pen, AoeRadius: aoe})
-				} else if tw.Type == "slow" {
+					g.bullets = append(g.bullets, &Bullet{X: tw.X, Y: tw.Y, Tx: p.X, Ty: p.Y, Speed: 800, Damage: dmg, Penetration: pen, AoeRadius: aoe, Src: tw, Target: target, TargetSerial: target.Serial})
+				} else if kind == "slow" {
 					// apply slow pulse
-					target.SlowTime = math.Max(target.SlowTime, tw.PulseDuration)
+					target.SlowTime = math.Max(target.SlowTime, tw.PulseDuration+ShopSlowStepMS*float64(g.upSlowLevel))
 					// slow factor scales with tower damage field (if any), default 0.5
 					target.SlowFactor = 0.5
 					dmg := 100.0
 					dmg *= 1.0 + 0.10*float64(g.upDamageLevel)
 					pen := float64(g.upPenLevel)
 					aoe := 0.0 + 4.0*float64(g.upAOELevel)
-					g.bullets = append(g.bullets, &Bullet{X: tw.X, Y: tw.Y, Tx: p.X, Ty: p.Y, Speed: 600, Damage: dmg, Penetration: pen, AoeRadius: aoe})
+					g.bullets = append(g.bullets, &Bullet{X: tw.X, Y: tw.Y, Tx: p.X, Ty: p.Y, Speed: 600, Damage: dmg, Penetration: pen, AoeRadius: aoe, Src: tw, Target: target, TargetSerial: target.Serial})
 				} else {
 					// base damage adjusted by tower damage and upgrades
 					base := tw.Damage
@@ -351,578 +850,862 @@ func (g *Game) Update() error {
 					tw.Fire = tw.Fire * math.Pow(0.90, float64(g.upSpeedLevel))
 					pen := float64(g.upPenLevel)
 					aoe := 0.0 + 4.0*float64(g.upAOELevel)
-					g.bullets = append(g.bullets, &Bullet{X: tw.X, Y: tw.Y, Tx: p.X, Ty: p.Y, Speed: 400, Damage: base, Penetration: pen, AoeRadius: aoe})
+					g.bullets = append(g.bullets, &Bullet{X: tw.X, Y: tw.Y, Tx: p.X, Ty: p.Y, Speed: 400, Damage: base, Penetration: pen, AoeRadius: aoe, Src: tw, Target: target, TargetSerial: target.Serial})
 				}
 			}
 		}
 	}
 
-	// process enemy status effects (burn damage over time, slow timers)
+	// process enemy status effects (burn damage over time, slow timers); the
+	// timers may run across cores, then burn damage, which credits the flame
+	// towers, and embers are applied in order
+	g.forEnemies(func(e *Enemy) { e.updateStatus(sim) })
+	g.goldRushMS = math.Max(0, g.goldRushMS-sim)
 	for _, e := range g.enemies {
-		// burn: deal damage per tick (1000ms tick) scaled by level
-		if e.BurnTime > 0 {
-			e.BurnTick += dt
-			for e.BurnTick >= 1000 {
-				// each tick deals 10 damage * level
-				dmg := float64(100 * e.BurnLevel)
-				e.HP -= dmg
-				e.BurnTick -= 1000
-			}
-			e.BurnTime -= dt
-			if e.BurnTime < 0 {
-				e.BurnTime = 0
-			}
+		if e.BurnTime > 0 || e.BurnDue > 0 {
+			g.emitBurning(e.Pos.X, e.Pos.Y, sim)
 		}
-		// slow: decrement timer
-		if e.SlowTime > 0 {
-			e.SlowTime -= dt
-			if e.SlowTime < 0 {
-				e.SlowTime = 0
-				e.SlowFactor = 1.0
-			}
+		for ; e.BurnDue > 0; e.BurnDue-- {
+			// each tick deals 10 damage * level
+			e.TakeDamage(float64(100*e.BurnLevel), e.BurnSrc)
 		}
 	}
 
-	// bullets
-	for i := len(g.bullets) - 1; i >= 0; i-- {
-		b := g.bullets[i]
+	// bullets; spent ones are dropped in one compaction pass
+	flying := g.bullets[:0]
+	for _, b := range g.bullets {
+		b.Age += sim
+		if b.outOfBounds() || b.Age > bulletMaxMS {
+			continue
+		}
+		b.follow()
 		dx := b.Tx - b.X
 		dy := b.Ty - b.Y
 		d := math.Hypot(dx, dy)
-		move := b.Speed * dt / 1000.0
+		move := b.Speed * sim / 1000.0
 		if d <= move || d == 0 {
 			// apply damage at impact point, considering penetration and AoE
-			g.applyDamageAt(b.Tx, b.Ty, b.Damage, b.Penetration, b.AoeRadius)
-			g.bullets = append(g.bullets[:i], g.bullets[i+1:]...)
+			g.emitImpact(b.Tx, b.Ty)
+			g.impactCue(b.Tx, b.Ty)
+			g.applyDamageAt(b.Tx, b.Ty, g.onHit(b), b.Penetration, b.AoeRadius, b.Src)
 			continue
 		}
+		b.pushTrail()
 		b.X += dx / d * move
 		b.Y += dy / d * move
+		flying = append(flying, b)
 	}
+	clear(g.bullets[len(flying):])
+	g.bullets = flying
 
-	// remove dead enemies
-	for i := len(g.enemies) - 1; i >= 0; i-- {
-		if g.enemies[i].HP <= 0 {
-			// count kills
-			g.killCount++
-			// award gold: multiples of 10. Use current killCount as multiplier (e.g., 1st kill = 10, 2nd = 20...)
-			goldAward := 10 * g.killCount
-			g.playerGold += goldAward
-			// remove
-			g.enemies = append(g.enemies[:i], g.enemies[i+1:]...)
-			// check for new level
-			if g.killCount >= g.nextLevelThreshold {
-				g.newLevel()
-			}
+	// remove dead enemies, again in one compaction pass
+	live = g.enemies[:0]
+	for _, e := range g.enemies {
+		if e.HP > 0 {
+			live = append(live, e)
+			continue
 		}
-	}
-
-	// decrement level message timer
-	if g.levelMsgTimer > 0 {
-		g.levelMsgTimer -= dt
-		if g.levelMsgTimer < 0 {
-			g.levelMsgTimer = 0
-			g.levelMsg = ""
+		// count kills
+		g.killCount++
+		if src := e.LastHit; src != nil {
+			src.Kills++
+		}
+		// award the enemy's bounty, set when it spawned, doubled in a gold rush
+		goldAward := e.Bounty
+		if g.goldRushMS > 0 {
+			goldAward *= goldRushMultiple
+		}
+		g.killEnemy(e, goldAward)
+		g.earn(e.LastHit, goldAward)
+		g.wave.Kills++
+		g.wave.Gold += goldAward
+		g.scoreKill(e)
+		g.freeEnemy(e)
+		// check for new level
+		if g.killCount >= g.nextLevelThreshold {
+			g.newLevel()
 		}
 	}
+	g.enemies = g.compactEnemies(live)
 
 	return nil
 }
 
+// worldOp places the rendered map on screen; reused so Draw doesn't allocate
+var worldOp = ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+
 func (g *Game) Draw(screen *ebiten.Image) {
+	defer clip.capture(screen)
 	// clear
-	screen.Fill(color.RGBA{0xA7, 0xD0, 0xFF, 0xFF})
+	screen.Fill(pal.Sky)
 
-	// draw path
-	for i := 0; i < len(g.path)-1; i++ {
-		p := g.path[i]
-		n := g.path[i+1]
-		ebitenutilDrawLine(screen, p.X, p.Y, n.X, n.Y, color.RGBA{0x33, 0x33, 0x33, 0xFF})
+	if g.drill != nil {
+		g.drawDrill(screen)
+		return
 	}
-
-	// enemies
-	for _, e := range g.enemies {
-		p := g.posAlongPath(e.T)
-		// visual tinting: burning -> reddish, slowed -> bluish
-		col := color.RGBA{0xD9, 0x53, 0x4F, 0xFF}
-		if e.BurnTime > 0 {
-			// stronger red when burn active
-			col = color.RGBA{0xFF, 0x88, 0x66, 0xFF}
-		}
-		if e.SlowTime > 0 {
-			// mix with blue tint when slowed
-			col = color.RGBA{0x66, 0x99, 0xFF, 0xFF}
-		}
-		ebitenutilFillCircle(screen, p.X, p.Y, 12, col)
-
-		// flame particles for burning enemies
-		if e.BurnTime > 0 {
-			// draw a few small flicker rects above the enemy
-			for i := 0; i < 6; i++ {
-				offx := (float64(i)-3.0)*2.0 + math.Sin(float64(i)+e.BurnTick/50.0)*2.0
-				offy := -6.0 + math.Mod(e.BurnTick/100.0, 6.0)
-				rect(screen, p.X+offx, p.Y+offy, 3, 3, color.RGBA{0xFF, 0x66, 0x00, 0xFF})
-			}
-		}
-
-		// slow ring indicator
-		if e.SlowTime > 0 {
-			ringR := 18.0 + (e.SlowTime/1000.0)*6.0
-			rect(screen, p.X-ringR/2, p.Y-ringR/2, ringR, 2, color.RGBA{0x66, 0x99, 0xFF, 0x80})
-		}
-		// hp bar
-		barW := 30.0
-		healthW := barW * (e.HP / e.MaxHP)
-		rect(screen, p.X-barW/2, p.Y-20, barW, 5, color.RGBA{0xFF, 0xFF, 0xFF, 0xFF})
-		rect(screen, p.X-barW/2, p.Y-20, healthW, 5, color.RGBA{0x5C, 0xB8, 0x5C, 0xFF})
+	if g.dashboard != nil {
+		g.drawDashboard(screen)
+		return
 	}
 
-	// towers
-	for i, tw := range g.towers {
-		c := color.RGBA{0x2B, 0x6C, 0xB0, 0xFF}
-		if g.selected == i {
-			c = color.RGBA{0xFF, 0xCC, 0x00, 0xFF}
-		}
-		ebitenutilFillCircle(screen, tw.X, tw.Y, 14, c)
-		// range
-		rangec := color.RGBA{0x2B, 0x6C, 0xB0, 0x20}
-		circleFill(screen, tw.X, tw.Y, tw.Range, rangec)
+	// the map is rendered at its native size, then placed by the camera
+	g.worldImg = scaledImage(g.worldImg, MapW, MapH)
+	g.worldImg.Fill(pal.Sky)
+	g.drawWorld(g.worldImg)
+	screen.Fill(pal.Letterbox)
+	op := &worldOp
+	op.GeoM.Reset()
+	op.GeoM.Scale(1/pixelScale, 1/pixelScale)
+	op.GeoM.Concat(g.camera.GeoM(g.viewW, g.viewH))
+	op.GeoM.Scale(pixelScale, pixelScale)
+	if g.photo != nil {
+		// the shot shows the battlefield in colour, not the paused look
+		screen.DrawImage(g.worldImg, op)
+		g.drawPhoto(screen)
+		return
 	}
+	screen.DrawImage(g.pausedWorld(), op)
 
-	// bullets
-	for _, b := range g.bullets {
-		ebitenutilFillCircle(screen, b.X, b.Y, 4, color.RGBA{0x22, 0x22, 0x22, 0xFF})
-	}
+	g.drawUI(screen)
+}
 
-	// UI text
-	drawText(screen, "Press C to open math challenge", 10, 20, color.White)
-	// player stats
-	drawText(screen, fmt.Sprintf("HP: %.0f", g.playerHP), ScreenW-180, 20, color.White)
-	drawText(screen, fmt.Sprintf("Armor: %.0f", g.playerArmor), ScreenW-180, 40, color.White)
-	drawText(screen, fmt.Sprintf("Gold: %d", g.playerGold), ScreenW-180, 60, color.White)
-	// level and remaining enemies
-	remaining := (g.enemiesToSpawn - g.enemiesSpawned)
-	if remaining < 0 {
-		remaining = 0
-	}
-	remaining += len(g.enemies)
-	drawText(screen, fmt.Sprintf("Level: %d  Remaining: %d", g.level, remaining), ScreenW/2-80, 20, color.White)
-	if g.selected >= 0 {
-		tw := g.towers[g.selected]
-		drawText(screen, fmt.Sprintf("Selected Tower: dmg=%.0f range=%.0f fire=%.0fms", tw.Damage, tw.Range, tw.Fire), 10, 40, color.White)
-	}
-	drawText(screen, "Click to select a tower or set place point. Press C for challenge.", 10, 60, color.White)
+// drawUI draws the HUD and overlays in view coordinates, positioned by the layout helpers
+func (g *Game) drawUI(screen *ebiten.Image) {
+	g.drawHUD(screen)
+	g.drawCoins(screen)
+	g.drawBuildCursor(screen)
+	g.drawSpeedButtons(screen)
+	g.drawCoopPanel(screen)
 
-	// last click indicator
-	if g.selected == -1 {
-		drawText(screen, fmt.Sprintf("Placement point: %.0f, %.0f (click then press C)", g.lastClick.X, g.lastClick.Y), 10, 80, color.White)
+	// challenge button and the item hotbar
+	if !g.challengeActive {
+		g.challengeButton().Draw(screen)
+		for _, b := range g.itemButtons() {
+			b.Draw(screen)
+		}
+	}
+	if touch.used {
+		g.shopButton().Draw(screen)
 	}
 
 	// challenge overlay
 	if g.challengeActive && g.question != nil {
 		// translucent box
-		w := 500.0
-		h := 140.0
-		rect(screen, (ScreenW-w)/2, (ScreenH-h)/2, w, h, color.RGBA{0, 0, 0, 0x80})
-		drawText(screen, "Solve:", int((ScreenW-w)/2+20), int((ScreenH-h)/2+30), color.White)
-		drawText(screen, g.question.Text, int((ScreenW-w)/2+20), int((ScreenH-h)/2+60), color.White)
-		drawText(screen, "Answer: "+g.inputBuf, int((ScreenW-w)/2+20), int((ScreenH-h)/2+90), color.White)
-		drawText(screen, "Enter to submit, Esc to cancel", int((ScreenW-w)/2+20), int((ScreenH-h)/2+120), color.White)
+		r := g.challengeRect()
+		x0, y0 := int(r.X), int(r.Y)
+		rect(screen, r.X, r.Y, r.W, r.H, fade(pal.Scrim, 0x80))
+		drawText(screen, tr("Solve:"), x0+20, y0+30, pal.Text)
+		if g.reviews.Contains(g.question) {
+			drawText(screen, tr("Review - you missed this one before"), x0+200, y0+30, pal.Warn)
+		}
+		drawText(screen, g.question.Text, x0+20, y0+60, pal.Text)
+		drawText(screen, tr("Answer: ")+g.inputBuf, x0+20, y0+90, pal.Text)
+		drawText(screen, tr("Enter to submit, Esc to cancel"), x0+20, y0+120, pal.Text)
+		if g.healOffer {
+			g.healButton().Draw(screen)
+		}
+		g.drawKeypad(screen)
 	}
 
 	// shop overlay
 	if g.shopActive {
-		w := 420.0
-		h := 260.0
-		x0 := (ScreenW - int(w)) / 2
-		y0 := (ScreenH - int(h)) / 2
-		rect(screen, float64(x0), float64(y0), w, h, color.RGBA{0, 0, 0, 0xC0})
-		drawText(screen, "Shop - Buy Upgrades (press B to close)", x0+10, y0+20, color.White)
-		drawText(screen, fmt.Sprintf("Gold: %d", g.playerGold), x0+300, y0+20, color.White)
+		g.drawShop(screen)
+	}
 
-		// each upgrade line: label (x,y) and cost and level
-		lines := []struct {
-			label string
-			level int
-			cost  int
-		}{
-			{"Damage +10%", g.upDamageLevel, 50 * (1 + g.upDamageLevel)},
-			{"Fire Rate +10%", g.upSpeedLevel, 40 * (1 + g.upSpeedLevel)},
-			{"Armor Penetration +1", g.upPenLevel, 60 * (1 + g.upPenLevel)},
-			{"AOE Radius +4px", g.upAOELevel, 80 * (1 + g.upAOELevel)},
-		}
-		for i, l := range lines {
-			yy := y0 + 50 + i*40
-			drawText(screen, fmt.Sprintf("%s (Lv %d) - Cost: %d", l.label, l.level, l.cost), x0+10, yy, color.White)
-			drawText(screen, "Click to buy", x0+300, yy, color.White)
-		}
+	// settings overlay
+	if g.settingsActive {
+		g.drawSettings(screen)
 	}
 
-	// level message
-	if g.levelMsgTimer > 0 && g.levelMsg != "" {
-		drawText(screen, g.levelMsg, 10, ScreenH-20, color.White)
+	// question history overlay
+	if g.historyActive {
+		r := g.centered(700, historyLogH)
+		g.drawHistory(screen, int(r.X), int(r.Y), r.W, r.H, tr("Question history (press L to close)"))
+	}
+
+	// performance report overlay
+	if g.reportActive {
+		g.drawReport(screen)
+	}
+	if g.online.active {
+		g.drawScoreBoard(screen)
 	}
 
 	// inter-level large countdown
 	if g.interLevelActive {
 		secs := int(math.Ceil(g.interLevelTimer / 1000.0))
-		msg := fmt.Sprintf("Level %d starting in %d", g.level, secs)
+		msg := trf("Level %d starting in %d", g.level, secs)
+		if n := g.waveGateRemaining(); n > 0 && secs == 0 {
+			msg = trf("Answer %d more question(s) to start level %d", n, g.level)
+		}
 		// centered large text box
-		w := 360.0
-		h := 80.0
-		rect(screen, (ScreenW-w)/2, (ScreenH-h)/2, w, h, color.RGBA{0, 0, 0, 0xC0})
-		drawText(screen, msg, int((ScreenW-w)/2+20), int((ScreenH-h)/2+30), color.White)
-		// draw Start Now button with hover/pressed feedback
-		bx := float64((ScreenW-int(w))/2 + int(w) - 120)
-		by := float64((ScreenH-int(h))/2 + int(h) - 36)
-		bw := 100.0
-		bh := 28.0
-		// detect cursor over button
-		mx, my := ebiten.CursorPosition()
-		over := float64(mx) >= bx && float64(mx) <= bx+bw && float64(my) >= by && float64(my) <= by+bh
-		// pressed state
-		pressed := over && ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft)
-		btnCol := color.RGBA{0x33, 0x99, 0x33, 0xFF} // normal
-		if over {
-			btnCol = color.RGBA{0x44, 0xB2, 0x44, 0xFF} // hover
-		}
-		if pressed {
-			btnCol = color.RGBA{0x22, 0x66, 0x22, 0xFF} // pressed
-		}
-		rect(screen, bx, by, bw, bh, btnCol)
-		// subtle border
-		rect(screen, bx-1, by-1, bw+2, 1, color.RGBA{0x00, 0x00, 0x00, 0x60})
-		rect(screen, bx-1, by+bh, bw+2, 1, color.RGBA{0x00, 0x00, 0x00, 0x60})
-		drawText(screen, "Start level now", int(bx+8), int(by+18), color.White)
-	}
-}
-
-// drawText is a small wrapper that uses the classic text.Draw signature
-func drawText(img *ebiten.Image, s string, x, y int, col color.Color) {
-	text.Draw(img, s, basicfont.Face7x13, x, y, col)
+		r := g.interLevelRect()
+		rect(screen, r.X, r.Y, r.W, r.H, fade(pal.Scrim, 0xC0))
+		drawText(screen, msg, int(r.X+20), int(r.Y+30), pal.Text)
+		g.drawWaveSummary(screen, int(r.X+20), int(r.Y+58))
+		// big countdown just above the box
+		if secs > 0 {
+			n := fmt.Sprint(secs)
+			drawTextSize(screen, n, int((float64(g.viewW)-textWidthSize(n, FontBig))/2), int(r.Y)-12, FontBig, pal.Text)
+		}
+		// Start Now button, greyed out while the teacher's answer gate is closed
+		g.startButton().Draw(screen)
+	}
+
+	g.drawVersus(screen)
+	g.drawLockstep(screen)
+	g.drawChat(screen)
+	g.drawGhost(screen)
+	g.drawWatch(screen)
+	if g.gameOver {
+		g.drawGameOver(screen)
+	}
+
+	if g.teacherState != teacherClosed {
+		g.drawTeacher(screen)
+	}
+	if g.content != nil {
+		g.drawContent(screen)
+	}
+
+	g.drawTooltip(screen)
+
+	if g.debugActive {
+		g.drawDebug(screen)
+	}
+
+	if g.confirm != nil {
+		g.drawConfirm(screen)
+	}
+}
+
+// drawReport renders accuracy and average response time per operation/range as bar charts
+func (g *Game) drawReport(screen *ebiten.Image) {
+	r := g.reportRect()
+	x0, y0 := int(r.X), int(r.Y)
+	h := r.H
+	rect(screen, r.X, r.Y, r.W, r.H, fade(pal.Scrim, 0xD0))
+	drawText(screen, tr("Performance report (press R to close)"), x0+10, y0+20, pal.Text)
+	if g.reportPage == 1 {
+		drawText(screen, tr("Times-table mastery (Tab: by operation)"), x0+10, y0+40, pal.TextDim)
+		g.drawMastery(screen, x0+10, y0+50)
+		return
+	}
+	drawText(screen, tr("Tab: times-table mastery"), x0+380, y0+20, pal.TextDim)
+	drawText(screen, tr("op  range    accuracy                avg time"), x0+10, y0+44, pal.TextDim)
+	stats := g.profile.Sorted()
+	if len(stats) == 0 {
+		drawText(screen, trf("No answers recorded yet. Press %s to try a challenge.", g.settings.Keys.Challenge), x0+10, y0+70, pal.Text)
+		return
+	}
+	// bars: accuracy 0..100% over 150px, avg time 0..20s over 120px
+	const accW = 150.0
+	const timeW = 120.0
+	const maxMS = 20000.0
+	for i, s := range stats {
+		yy := y0 + 60 + i*22
+		if yy > y0+int(h)-20 {
+			break
+		}
+		drawText(screen, fmt.Sprintf("%-2s  %-6s", s.Op, s.Range), x0+10, yy+12, pal.Text)
+		acc := s.Accuracy()
+		rect(screen, float64(x0+110), float64(yy+2), accW, 12, pal.TrackLight)
+		accCol := pal.Good
+		if acc < 0.6 {
+			accCol = pal.Bad
+		}
+		if accW*acc >= 1 {
+			rect(screen, float64(x0+110), float64(yy+2), accW*acc, 12, accCol)
+		}
+		drawText(screen, fmt.Sprintf("%3.0f%% (%d)", acc*100, s.Asked), x0+265, yy+12, pal.Text)
+		avg := s.AvgMS()
+		rect(screen, float64(x0+350), float64(yy+2), timeW, 12, pal.TrackLight)
+		tw := timeW * math.Min(1, avg/maxMS)
+		if tw >= 1 {
+			rect(screen, float64(x0+350), float64(yy+2), tw, 12, pal.Timing)
+		}
+		drawText(screen, fmt.Sprintf("%.1fs", avg/1000.0), x0+475, yy+12, pal.Text)
+	}
 }
 
 func (g *Game) spawnEnemy() {
+	w := &waveTuning
 	// base hp grows with level; early levels weaker, later levels stronger
-	base := EnemyBaseHPMin + g.rand.Float64()*(EnemyBaseHPMax-EnemyBaseHPMin)
+	base := w.EnemyHPMin + g.rand.Float64()*(w.EnemyHPMax-w.EnemyHPMin)
 	// scale up with level
-	hp := base * (1.0 + float64(g.level-1)*EnemyHPScalePerLevel)
+	hp := base * (1.0 + float64(g.level-1)*w.EnemyHPPerLevel)
+	if g.hordeLevel {
+		hp *= hordeHPScale
+	}
+	if g.modifier("tough") {
+		hp *= dailyHPScale
+	}
 	// give enemies a small armor that scales with level
-	armor := float64(g.level) * EnemyArmorPerLevel
+	armor := float64(g.level) * w.EnemyArmorPerLevel
 	// slightly increase speed with level for later waves
-	speed := EnemySpeedBase + g.rand.Float64()*EnemySpeedRandMax + float64(g.level-1)*EnemySpeedPerLevel
-	e := &Enemy{HP: hp, MaxHP: hp, Armor: armor, Speed: speed, T: 0}
+	speed := w.EnemySpeedBase + g.rand.Float64()*w.EnemySpeedRand + float64(g.level-1)*w.EnemySpeedPerLevel
+	if g.modifier("fast") {
+		speed *= dailySpeedScale
+	}
+	bounty := g.bounty(false)
+	if s := w.spawnScript; s != nil {
+		vars := []float64{float64(g.level), float64(g.enemiesSpawned), hp, armor, speed, float64(bounty)}
+		s.run(nil, g.rand, vars)
+		hp, armor, speed = math.Max(1, vars[2]), math.Max(0, vars[3]), math.Max(0, vars[4])
+		bounty = int(math.Max(0, math.Round(vars[5])))
+	}
+	e := g.newEnemy(Enemy{HP: hp, MaxHP: hp, Armor: armor, Speed: speed, Bounty: bounty})
+	e.Anim.Play(animEnemyWalk)
+	// stagger the walk cycles so a wave doesn't step in unison
+	e.Anim.T = float64(g.enemiesSpawned) * 37
 	g.enemies = append(g.enemies, e)
 }
 
-// handleShopClick checks if the click was on a shop button and purchases if affordable
-func (g *Game) handleShopClick(x, y float64) {
-	w := 420.0
-	h := 260.0
-	x0 := float64((ScreenW - int(w)) / 2)
-	y0 := float64((ScreenH - int(h)) / 2)
-	if x < x0 || x > x0+w || y < y0 || y > y0+h {
+// towerSellValue refunds half of the notional build and upgrade prices in buildCost
+func towerSellValue(tw *Tower) int { return 25 + 20*tw.Upgrades }
+
+func (g *Game) sellTower(i int) {
+	if i < 0 || i >= len(g.towers) {
 		return
 	}
-	// compute which line clicked
-	relY := int(y - (y0 + 50))
-	if relY < 0 || relY > 200 {
-		return
+	g.earn(g.towers[i], towerSellValue(g.towers[i]))
+	g.towers = append(g.towers[:i], g.towers[i+1:]...)
+	// the selection follows its tower down the list, or goes with it
+	switch {
+	case g.selected == i:
+		g.selected = -1
+	case g.selected > i:
+		g.selected--
 	}
-	idx := relY / 40
-	switch idx {
-	case 0:
-		cost := 50 * (1 + g.upDamageLevel)
-		if g.playerGold >= cost {
-			g.playerGold -= cost
-			g.upDamageLevel++
-		}
-	case 1:
-		cost := 40 * (1 + g.upSpeedLevel)
-		if g.playerGold >= cost {
-			g.playerGold -= cost
-			g.upSpeedLevel++
+}
+
+// towerAt returns the index of the tower near a map position, or -1
+func (g *Game) towerAt(x, y float64) int {
+	for i, tw := range g.towers {
+		if math.Hypot(tw.X-x, tw.Y-y) < 18 {
+			return i
 		}
-	case 2:
-		cost := 60 * (1 + g.upPenLevel)
-		if g.playerGold >= cost {
-			g.playerGold -= cost
-			g.upPenLevel++
+	}
+	return -1
+}
+
+// purchase spends gold on an upgrade. In math-gated mode the purchase is held
+// behind a challenge whose difficulty scales with the cost; gold is only taken
+// once the answer is correct.
+func (g *Game) purchase(cost int, c Command) {
+	if g.playerGold < cost {
+		return
+	}
+	if !g.mathGated {
+		g.do(c)
+		return
+	}
+	g.shopActive = false
+	g.openChallenge(g.newQuestion(purchaseDifficulty(cost)), func() { g.do(c) })
+}
+
+// startChallenge opens the regular build/upgrade challenge (C key or the Challenge button)
+func (g *Game) startChallenge() {
+	level := g.level
+	if g.mathGated {
+		// building/upgrading is priced in question difficulty
+		level = purchaseDifficulty(g.buildCost())
+	}
+	g.openChallenge(g.newQuestion(level), nil)
+	g.offerHeal()
+}
+
+// openChallenge shows the math overlay for q; onCorrect runs if the player answers it (nil means applyReward)
+func (g *Game) openChallenge(q *Question, onCorrect func()) {
+	g.question = q
+	g.inputBuf = ""
+	g.challengeReward = onCorrect
+	g.challengeElapsed = 0
+	g.challengeActive = true
+	g.readQuestion()
+}
+
+// readAnswerInput edits inputBuf from the keyboard (digits, minus, decimal point, backspace)
+// or the on-screen keypad
+// and reports whether the answer was submitted (Enter) or cancelled (Escape or right-click)
+//
+// Typed characters come from ebiten.AppendInputChars, so the numpad and any
+// keyboard layout work; Backspace auto-repeats while held.
+func (g *Game) readAnswerInput() (submitted, cancelled bool) {
+	g.inputChars = ebiten.AppendInputChars(g.inputChars[:0])
+	for _, r := range g.inputChars {
+		g.typeAnswerRune(r)
+	}
+	if repeatingKey(ebiten.KeyBackspace) {
+		g.backspaceAnswer()
+	}
+	submitted = inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyKPEnter)
+	cancelled = inpututil.IsKeyJustPressed(ebiten.KeyEscape) || cancelJustPressed()
+	// on-screen keypad (mouse / touch)
+	padSubmit, padCancel := g.readKeypad()
+	return submitted || padSubmit, cancelled || padCancel
+}
+
+// answer entry limits and Backspace key-repeat timing (ms)
+const (
+	maxAnswerLen        = 12
+	keyRepeatDelayMS    = 400
+	keyRepeatIntervalMS = 50
+)
+
+// repeatingKey is true on the first tick of a press and then periodically while held
+func repeatingKey(k ebiten.Key) bool {
+	d := inpututil.KeyPressDuration(k)
+	delay, interval := ticksFor(keyRepeatDelayMS), ticksFor(keyRepeatIntervalMS)
+	return d == 1 || (d >= delay && (d-delay)%interval == 0)
+}
+
+// typeAnswerRune appends one typed character to inputBuf: digits, a leading
+// minus sign and a single decimal point (comma is accepted as a decimal separator)
+func (g *Game) typeAnswerRune(r rune) {
+	if len(g.inputBuf) >= maxAnswerLen {
+		return
+	}
+	switch {
+	case r >= '0' && r <= '9':
+		g.inputBuf += string(r)
+	case r == '-' || r == '\u2212':
+		if len(g.inputBuf) == 0 {
+			g.inputBuf = "-"
 		}
-	case 3:
-		cost := 80 * (1 + g.upAOELevel)
-		if g.playerGold >= cost {
-			g.playerGold -= cost
-			g.upAOELevel++
+	case r == '.' || r == ',':
+		if !strings.Contains(g.inputBuf, ".") {
+			g.inputBuf += "."
 		}
 	}
 }
 
-// handleInterLevelClick checks clicks on the inter-level Start Now button
-func (g *Game) handleInterLevelClick(x, y float64) {
-	if !g.interLevelActive {
-		return
+func (g *Game) backspaceAnswer() {
+	if len(g.inputBuf) > 0 {
+		g.inputBuf = g.inputBuf[:len(g.inputBuf)-1]
 	}
-	w := 360.0
-	h := 80.0
-	bx := float64((ScreenW-int(w))/2 + int(w) - 120)
-	by := float64((ScreenH-int(h))/2 + int(h) - 36)
-	bw := 100.0
-	bh := 28.0
-	if x >= bx && x <= bx+bw && y >= by && y <= by+bh {
-		// start immediately
-		g.interLevelActive = false
-		g.interLevelTimer = 0
-		g.enemiesSpawned = 0
-		g.lastSpawn = 0
+}
+
+// checkAnswer grades inputBuf against the current question and records the result in the profile
+func (g *Game) checkAnswer() bool {
+	correct := g.question.Check(g.inputBuf)
+	g.recordHistory(correct)
+	g.profile.Record(g.question, correct, g.challengeElapsed)
+	g.profile.Save()
+	g.reviews.Answered(g.question, correct, g.level)
+	g.answerCue(correct)
+	return correct
+}
+
+// newQuestion picks the next challenge question: a missed question that is due
+// for review takes priority over a fresh one at the requested level
+func (g *Game) newQuestion(level int) *Question {
+	return g.questionFrom(&g.reviews, level)
+}
+
+// questionFrom is newQuestion taking reviews from rq, so each co-op player
+// gets their own misses back
+func (g *Game) questionFrom(rq *ReviewQueue, level int) *Question {
+	if q := rq.Due(g.level); q != nil && g.teacher.Allowed(q.Op) {
+		return q
+	}
+	if b := g.activeBank(); b != nil {
+		return b.question(g.qrand, level)
+	}
+	level = g.questionLevel(level)
+	return g.focusFacts(g.filterTopic(genQuestion(g.qrand, level), level))
+}
+
+func (g *Game) closeChallenge() {
+	g.challengeActive = false
+	g.inputBuf = ""
+	g.challengeReward = nil
+	g.healOffer, g.healChosen = false, false
+}
+
+// buildCost is the notional price of the next challenge reward: upgrading the
+// selected tower or placing a new one. Used to scale math-gated difficulty.
+func (g *Game) buildCost() int {
+	if g.selected >= 0 {
+		return 40 * (1 + g.towers[g.selected].Upgrades)
+	}
+	return 50 * len(g.towers)
+}
+
+// purchaseDifficulty maps a gold cost to the question level passed to genQuestion
+func purchaseDifficulty(cost int) int {
+	lvl := 1 + cost/50
+	if lvl > 12 {
+		lvl = 12
+	}
+	return lvl
+}
+
+// startButton is the inter-level Start Now button, disabled while the teacher's answer gate is closed
+func (g *Game) startButton() Button {
+	return Button{Rect: g.startButtonRect(), Text: tr("Start level now"), Disabled: g.waveGateRemaining() > 0,
+		OnClick: func() { g.do(Command{Kind: "start"}) }}
+}
+
+func (g *Game) challengeButton() Button {
+	return Button{Rect: g.challengeButtonRect(), Text: tr("Challenge"), OnClick: g.startChallenge}
+}
+
+// shopButton opens and closes the shop without a keyboard; it shows once the
+// screen has been touched
+func (g *Game) shopButton() Button {
+	return Button{Rect: g.shopButtonRect(), Text: tr("Shop"), Active: g.shopActive, OnClick: func() {
+		g.shopActive = !g.shopActive
+		if g.shopActive {
+			g.closeChallenge()
+		}
+	}}
+}
+
+// buttons lists every clickable button currently on screen, topmost first.
+// The slice is reused by the next call, so use it before asking again.
+func (g *Game) buttons() []Button {
+	btns := g.buttonBuf[:0]
+	if g.shopActive {
+		btns = append(btns, g.shopButtons()...)
+		btns = append(btns, g.shopTabButtons()...)
+	}
+	if g.interLevelActive {
+		btns = append(btns, g.startButton())
+	}
+	if b, ok := g.sellButton(); ok {
+		btns = append(btns, b)
 	}
+	btns = append(btns, g.speedButtons()...)
+	if !g.challengeActive {
+		btns = append(btns, g.challengeButton())
+		btns = append(btns, g.itemButtons()...)
+	}
+	if g.challengeActive && g.healOffer {
+		btns = append(btns, g.healButton())
+	}
+	if touch.used {
+		btns = append(btns, g.shopButton())
+	}
+	g.buttonBuf = btns
+	return btns
+}
+
+// startWave ends the inter-level pause and begins spawning the level's enemies
+func (g *Game) startWave() {
+	g.interLevelActive = false
+	g.interLevelTimer = 0
+	// reset spawn counters for the level
+	g.enemiesSpawned = 0
+	g.lastSpawn = 0
+	g.waveAnswered = 0
 }
 
 // applyDamageAt applies damage to an enemy index or AoE around a point, considering penetration and enemy armor
-func (g *Game) applyDamageAt(x, y, baseDamage float64, penetration float64, aoeRadius float64) {
+func (g *Game) applyDamageAt(x, y, baseDamage float64, penetration float64, aoeRadius float64, src *Tower) {
 	if aoeRadius <= 0 {
 		// find nearest enemy at point
-		best := -1
-		bestD := 1e9
-		for i, e := range g.enemies {
-			p := g.posAlongPath(e.T)
-			d := math.Hypot(p.X-x, p.Y-y)
-			if d < bestD {
-				bestD = d
-				best = i
-			}
-		}
-		if best >= 0 && bestD < 18 {
-			e := g.enemies[best]
+		if e, d := g.grid.Nearest(x, y, 18); e != nil && d < 18 {
 			// effective armor after penetration
 			effArmor := math.Max(0, e.Armor-penetration)
 			dmg := baseDamage - effArmor
 			if dmg < 1 {
 				dmg = 1
 			}
-			e.HP -= dmg
+			e.TakeDamage(dmg, src)
+			from := Vec{x, y}
+			if src != nil {
+				from = Vec{src.X, src.Y}
+			}
+			e.knockFrom(from, e.Pos)
 		}
 		return
 	}
-	// AoE: damage all enemies within radius
-	for _, e := range g.enemies {
-		p := g.posAlongPath(e.T)
-		if math.Hypot(p.X-x, p.Y-y) <= aoeRadius {
-			effArmor := math.Max(0, e.Armor-penetration)
-			dmg := baseDamage - effArmor
-			if dmg < 1 {
-				dmg = 1
-			}
-			e.HP -= dmg
+	// AoE: damage all enemies within radius, and show the blast
+	g.blasts = append(g.blasts, Blast{X: x, Y: y, R: aoeRadius})
+	g.nearBuf = g.grid.Near(g.nearBuf[:0], x, y, aoeRadius)
+	for _, e := range g.nearBuf {
+		effArmor := math.Max(0, e.Armor-penetration)
+		dmg := baseDamage - effArmor
+		if dmg < 1 {
+			dmg = 1
 		}
+		e.TakeDamage(dmg, src)
+		e.knockFrom(Vec{x, y}, e.Pos)
+	}
+}
+
+// TakeDamage applies damage unless a boss shield absorbs it, crediting src
+// (which may be nil) with the damage that landed and the last hit
+func (e *Enemy) TakeDamage(dmg float64, src *Tower) {
+	if e.Shield > 0 || e.HP <= 0 {
+		return
 	}
+	if src != nil {
+		src.DamageDealt += math.Min(dmg, e.HP)
+		e.LastHit = src
+	}
+	e.HP -= dmg
+	e.HitFlash = hitFlashMS
 }
 
-func (g *Game) posAlongPath(t float64) Vec {
-	i := int(math.Floor(t))
-	frac := t - float64(i)
-	if i >= len(g.path)-1 {
-		p := g.path[len(g.path)-1]
-		return p
+// hit reaction tuning
+const (
+	hitFlashMS = 90.0
+	knockPx    = 3.0
+	knockDecay = 0.02 // fraction of the nudge left after one second
+)
+
+// knockFrom nudges an enemy at p away from a shot that came from `from`;
+// bosses barely move. The nudge is visual only and does not change T.
+func (e *Enemy) knockFrom(from, p Vec) {
+	if e.Shield > 0 {
+		return
+	}
+	dx, dy := p.X-from.X, p.Y-from.Y
+	d := math.Hypot(dx, dy)
+	if d == 0 {
+		return
+	}
+	k := knockPx
+	if e.Boss {
+		k /= 3
+	}
+	e.Knock = Vec{e.Knock.X + dx/d*k, e.Knock.Y + dy/d*k}
+	// repeated hits don't push an enemy off the road
+	if n := math.Hypot(e.Knock.X, e.Knock.Y); n > 2*knockPx {
+		e.Knock = Vec{e.Knock.X / n * 2 * knockPx, e.Knock.Y / n * 2 * knockPx}
 	}
-	a := g.path[i]
-	b := g.path[i+1]
-	return Vec{a.X + (b.X-a.X)*frac, a.Y + (b.Y-a.Y)*frac}
+}
+
+// updateStatus runs an enemy's status timers and animation for sim ms. It only
+// touches e, so it is safe to run for many enemies at once; burn ticks that
+// fall due are left in BurnDue for the caller to apply.
+func (e *Enemy) updateStatus(sim float64) {
+	// burn: deal damage per tick (1000ms tick) scaled by level
+	if e.BurnTime > 0 {
+		e.BurnTick += sim
+		for e.BurnTick >= 1000 {
+			e.BurnDue++
+			e.BurnTick -= 1000
+		}
+		e.BurnTime -= sim
+		if e.BurnTime < 0 {
+			e.BurnTime = 0
+		}
+	}
+	// slow: decrement timer
+	if e.SlowTime > 0 {
+		e.SlowTime -= sim
+		if e.SlowTime < 0 {
+			e.SlowTime = 0
+			e.SlowFactor = 1.0
+		}
+	}
+	// freeze: the enemy stands still until it thaws
+	e.FreezeTime = math.Max(0, e.FreezeTime-sim)
+	// walk cycle keeps pace with the enemy, so slowed enemies step slower
+	// and frozen ones not at all
+	step := sim
+	if e.SlowTime > 0 {
+		step *= e.SlowFactor
+	}
+	if e.FreezeTime > 0 {
+		step = 0
+	}
+	e.Anim.Update(step)
+	e.updateHitReaction(sim)
+}
+
+// updateHitReaction fades the hit flash and eases the knock back to zero
+func (e *Enemy) updateHitReaction(dt float64) {
+	e.HitFlash = math.Max(0, e.HitFlash-dt)
+	f := math.Pow(knockDecay, dt/1000)
+	e.Knock = Vec{e.Knock.X * f, e.Knock.Y * f}
 }
 
 func (g *Game) applyReward() {
-	reward := g.rand.Float64()
 	if g.selected >= 0 {
 		tw := g.towers[g.selected]
-		if reward < 0.33 {
-			tw.Damage += 1
-		} else if reward < 0.66 {
-			tw.Range += 20
-		} else {
-			tw.Fire = math.Max(150, tw.Fire-100)
-		}
+		g.do(Command{Kind: "upgrade", X: tw.X, Y: tw.Y})
+		return
+	}
+	pos := g.lastClick
+	if pos.X == 0 && pos.Y == 0 {
+		pos = Vec{100, 250}
+	}
+	// apply would turn the build down; say why rather than dropping it quietly
+	if !g.canPlaceTower(pos, -1) {
+		g.levelMsg = tr("A tower can't go there")
+		g.levelMsgTimer = 3000
+		return
+	}
+	g.do(Command{Kind: "build", Type: towerTypes[g.buildType], X: pos.X, Y: pos.Y})
+	if !g.keepBuilding {
+		g.lastClick = Vec{}
+	}
+}
+
+// upgradeTower improves one of tw's stats, picked by reward (0-1): damage,
+// range or fire rate, by 1+bonus times the usual step
+func upgradeTower(tw *Tower, reward, bonus float64) {
+	tw.Upgrades++
+	if reward < 0.33 {
+		tw.Damage += 1 + bonus
+	} else if reward < 0.66 {
+		tw.Range += 20 * (1 + bonus)
 	} else {
-		pos := g.lastClick
-		if pos.X == 0 && pos.Y == 0 {
-			pos = Vec{100, 250}
-		}
-		g.towers = append(g.towers, &Tower{X: pos.X, Y: pos.Y, Range: 120, Damage: 2, Fire: 700, Cd: 0})
+		tw.Fire = math.Max(150, tw.Fire-100*(1+bonus))
 	}
 }
 
 func (g *Game) newLevel() {
+	g.finishWave()
 	g.level++
+	g.wave.Level = g.level
+	g.replay.Levels = append(g.replay.Levels, g.runMS)
 	g.killCount = 0
-	g.nextLevelThreshold = 20 + g.rand.Intn(11)
+	g.nextLevelThreshold = g.killsToAdvance()
 	// set new per-level spawn target
-	g.enemiesToSpawn = EnemiesPerLevelMin + g.rand.Intn(EnemiesPerLevelMax-EnemiesPerLevelMin+1)
+	g.enemiesToSpawn = g.enemiesPerLevel()
 	g.enemiesSpawned = 0
+	g.startHordeLevel()
 	// generate a new random path with 5-7 waypoints across the screen
 	wp := 3 + g.rand.Intn(5) // 3..7 segments
 	newPath := make([]Vec, 0, wp+2)
 	// start at left edge
-	newPath = append(newPath, Vec{0, 300})
+	newPath = append(newPath, Vec{0, MapH / 2})
 	for i := 0; i < wp; i++ {
-		x := float64(100 + g.rand.Intn(ScreenW-200))
-		y := float64(80 + g.rand.Intn(ScreenH-160))
+		x := float64(100 + g.rand.Intn(MapW-200))
+		y := float64(80 + g.rand.Intn(MapH-160))
 		newPath = append(newPath, Vec{x, y})
 	}
 	// end at right edge
-	newPath = append(newPath, Vec{ScreenW, 300})
-	g.path = newPath
+	newPath = append(newPath, Vec{MapW, MapH / 2})
+	g.setPath(newPath)
 	// reduce spawn interval slightly to increase challenge
-	if g.spawnInt > SpawnIntervalMin {
-		g.spawnInt -= SpawnIntervalDecay
-		if g.spawnInt < SpawnIntervalMin {
-			g.spawnInt = SpawnIntervalMin
+	if w := &waveTuning; g.spawnInt > w.SpawnIntervalMin {
+		g.spawnInt -= w.SpawnIntervalDecay
+		if g.spawnInt < w.SpawnIntervalMin {
+			g.spawnInt = w.SpawnIntervalMin
 		}
 	}
+	g.runLevelScript()
 	// set a temporary level message
-	g.levelMsg = fmt.Sprintf("Level %d - New path generated! Next threshold: %d kills", g.level, g.nextLevelThreshold)
+	g.levelMsg = trf("Level %d - New path generated! Next threshold: %d kills", g.level, g.nextLevelThreshold)
 	g.levelMsgTimer = 3000 // show for 3s
 	// start inter-level pause for subsequent levels (skip at initial startup)
 	if g.level > 1 {
+		// interest on the gold saved through the level just cleared
+		if n := g.payInterest(); n > 0 {
+			g.lastWave.Interest = n
+			g.waves[len(g.waves)-1].Interest = n
+			g.levelMsg += "  " + trf("Interest: +%d gold", n)
+		}
+		if n := g.scoreLevel(); n > 0 {
+			g.levelMsg += "  " + trf("No leaks: +%d score", n)
+		}
 		g.interLevelActive = true
-		g.interLevelTimer = InterLevelPauseMS
+		g.interLevelTimer = waveTuning.InterLevelPauseMS
 	} else {
 		g.interLevelActive = false
 		g.interLevelTimer = 0
 	}
 }
 
-func genQuestion(r *rand.Rand, level int) *Question {
-	// difficulty scales with level. We'll pick an operation set and operand ranges.
-	// level 1-2: small add/sub (1..12)
-	// level 3-5: larger add/sub and small mul (1..20)
-	// level 6-9: multiplication up to 12..20 and two-digit add/sub
-	// level 10+: introduce integer division and larger operands
-	var a, b int
-	var op string
-	var ans int
-	if level <= 2 {
-		a = 1 + r.Intn(12)
-		b = 1 + r.Intn(12)
-		if r.Intn(2) == 0 {
-			op = "+"
-			ans = a + b
-		} else {
-			op = "-"
-			ans = a - b
-		}
-	} else if level <= 5 {
-		a = 1 + r.Intn(20)
-		b = 1 + r.Intn(20)
-		oi := r.Intn(3)
-		if oi == 0 {
-			op = "+"
-			ans = a + b
-		} else if oi == 1 {
-			op = "-"
-			ans = a - b
-		} else {
-			op = "*"
-			ans = a * b
-		}
-	} else if level <= 9 {
-		a = 2 + r.Intn(18) // 2..19
-		b = 2 + r.Intn(18)
-		oi := r.Intn(3)
-		if oi == 0 {
-			op = "+"
-			ans = a + b
-		} else if oi == 1 {
-			op = "-"
-			ans = a - b
-		} else {
-			op = "*"
-			ans = a * b
-		}
-	} else {
-		// include integer division: ensure divisible
-		ops := []int{0, 1, 2, 3} // 0:+,1:-,2:*,3:/
-		oi := ops[r.Intn(len(ops))]
-		if oi == 3 {
-			b = 2 + r.Intn(18)
-			q := 2 + r.Intn(12)
-			a = b * q
-			op = "/"
-			ans = a / b
-		} else {
-			a = 5 + r.Intn(45)
-			b = 5 + r.Intn(45)
-			if oi == 0 {
-				op = "+"
-				ans = a + b
-			} else if oi == 1 {
-				op = "-"
-				ans = a - b
-			} else {
-				op = "*"
-				ans = a * b
-			}
-		}
-	}
-	return &Question{Text: fmt.Sprintf("%d %s %d", a, op, b), Ans: ans}
-}
-
 // --- minimal drawing helpers (avoid additional deps) ---
 
+// whitePixel is a 1x1 white image that rect and the line helper scale and tint,
+// so drawing shapes never allocates images. It is cut from the middle of a 3x3
+// image so filtering at its edges doesn't sample the atlas around it.
+var whitePixel = func() *ebiten.Image {
+	img := ebiten.NewImage(3, 3)
+	img.Fill(color.White)
+	return img.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
+}()
+
+// shapeOp is reused by the shape helpers; Draw runs on one goroutine
+var shapeOp ebiten.DrawImageOptions
+
 func rect(img *ebiten.Image, x, y, w, h float64, c color.Color) {
-	r := ebiten.NewImage(int(w), int(h))
-	r.Fill(c)
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Translate(x, y)
-	img.DrawImage(r, op)
-}
-
-func circleFill(img *ebiten.Image, cx, cy, r float64, c color.Color) {
-	// crude: draw many small rects along radial steps
-	steps := int(math.Max(8, r/2))
-	for i := 0; i < steps; i++ {
-		ang := 2 * math.Pi * float64(i) / float64(steps)
-		x := cx + math.Cos(ang)*r
-		y := cy + math.Sin(ang)*r
-		rect(img, x-1, y-1, 2, 2, c)
-	}
-}
-
-// tiny util functions to avoid ebitenutil dependency for lines/circles
-func ebitenutilDrawLine(img *ebiten.Image, x1, y1, x2, y2 float64, c color.Color) {
-	// draw a thin rectangle approximating a line
-	dx := x2 - x1
-	dy := y2 - y1
-	len := math.Hypot(dx, dy)
-	if len == 0 {
+	if w <= 0 || h <= 0 {
 		return
 	}
-	ang := math.Atan2(dy, dx)
-	line := ebiten.NewImage(int(len), 6)
-	line.Fill(c)
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Translate(-float64(int(len))/2, -3)
-	op.GeoM.Rotate(ang)
-	op.GeoM.Translate((x1+x2)/2, (y1+y2)/2)
-	img.DrawImage(line, op)
+	shapeOp.GeoM.Reset()
+	shapeOp.GeoM.Scale(w*pixelScale, h*pixelScale)
+	shapeOp.GeoM.Translate(x*pixelScale, y*pixelScale)
+	shapeOp.ColorScale.Reset()
+	shapeOp.ColorScale.ScaleWithColor(c)
+	img.DrawImage(whitePixel, &shapeOp)
 }
 
-func ebitenutilFillCircle(img *ebiten.Image, cx, cy, r float64, c color.Color) {
-	// draw simple filled circle using many rects
-	steps := int(math.Max(12, r))
-	for i := 0; i < steps; i++ {
-		ang := 2 * math.Pi * float64(i) / float64(steps)
-		x := cx + math.Cos(ang)*r
-		y := cy + math.Sin(ang)*r
-		rect(img, x-2, y-2, 4, 4, c)
-	}
+// line strokes an antialiased segment of the given width
+func line(img *ebiten.Image, x1, y1, x2, y2, width float64, c color.Color) {
+	s := pixelScale
+	vector.StrokeLine(img, float32(x1*s), float32(y1*s), float32(x2*s), float32(y2*s), float32(width*s), c, true)
+}
+
+// ring strokes an antialiased circle outline of the given width
+func ring(img *ebiten.Image, cx, cy, r, width float64, c color.Color) {
+	s := pixelScale
+	vector.StrokeCircle(img, float32(cx*s), float32(cy*s), float32(r*s), float32(width*s), c, true)
+}
+
+// disc fills an antialiased circle
+func disc(img *ebiten.Image, cx, cy, r float64, c color.Color) {
+	s := pixelScale
+	vector.DrawFilledCircle(img, float32(cx*s), float32(cy*s), float32(r*s), c, true)
 }
 
 func dist(a, b Vec) float64 { return math.Hypot(a.X-b.X, a.Y-b.Y) }
 
-func main() {
-	g := NewGame()
+// Run parses the command-line flags and plays the game in a window, or on the
+// page in a browser; cmd/datagame's main is just a call to it
+func Run() {
+	defer crashExit()
+	flag.Parse()
+	if err := checkLaunchFlags(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	game := newSession()
+	ebiten.SetFullscreen(*launchFullscreen)
 	ebiten.SetWindowSize(ScreenW, ScreenH)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 	ebiten.SetWindowTitle("DataGame — Math Tower Defense (Go/Ebiten)")
-	if err := ebiten.RunGame(g); err != nil {
+	if err := ebiten.RunGame(game); err != nil {
 		panic(err)
 	}
 }
+
+// newSession starts the sound and the game, connecting whatever the flags ask for
+func newSession() ebiten.Game {
+	if *pprofAddr != "" {
+		startPprof(*pprofAddr)
+	}
+	sound = newAudio()
+	modErr := loadBalanceMods()
+	problems := startContent(loadSettings())
+	g := NewGame()
+	if problems > 0 {
+		g.levelMsg = trf("%d content files didn't load - press I to see why", problems)
+		g.levelMsgTimer = 6000
+	}
+	if modErr != nil {
+		fmt.Fprintln(os.Stderr, modErr)
+		g.levelMsg = tr("Mod not loaded: ") + modErr.Error()
+		g.levelMsgTimer = 8000
+	}
+	if *ghostFrom != "" {
+		g.startGhostRace(*ghostFrom)
+	}
+	g.versus = versusFromFlags()
+	g.lockstep = g.lockstepFromFlags()
+	g.student, g.dashboard = classroomFromFlags()
+	g.spectators, g.watch = spectateFromFlags()
+	if *benchFrames > 0 {
+		return &crashGuard{game: newBenchGame(*benchFrames)}
+	}
+	return &crashGuard{game: g}
+}
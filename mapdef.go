@@ -0,0 +1,226 @@
+package datagame
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+//go:embed maps/*.json
+var mapFS embed.FS
+
+// MapDef is a map definition from maps/<name>.json: the enemy path plus the
+// background layers (grass tiles, the road along the path and decorations)
+type MapDef struct {
+	Name        string       `json:"name"`
+	Tile        int          `json:"tile"`       // grass tile size in map pixels
+	RoadWidth   float64      `json:"road_width"` // drawn width of the road along the path
+	Path        [][2]float64 `json:"path"`
+	Decorations []Decoration `json:"decorations"`
+	Ambient     []string     `json:"ambient"` // background loops: "wind", "birds"
+	Stars       [3]int       `json:"stars"`   // scores for one, two and three stars; unset means defaultStars
+	File        string       `json:"-"`       // the name it was loaded by, e.g. "meadow"
+}
+
+// Decoration is a purely visual prop on the background
+type Decoration struct {
+	Kind string  `json:"kind"` // "tree", "rock", "bush" or "flowers"
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	R    float64 `json:"r"` // drawn radius; 0 means decorRadius
+}
+
+const decorRadius = 14.0
+
+// defaultMap is the map runs are played on unless -map picks another
+const defaultMap = "meadow"
+
+// mapNames lists the embedded maps by name
+func mapNames() []string {
+	entries, _ := mapFS.ReadDir("maps")
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	return names
+}
+
+// loadMap reads a map definition by name, one of the game's own or else one
+// from the content folder
+func loadMap(name string) (*MapDef, error) {
+	data, err := readData(mapFS, "maps/"+name+".json")
+	if errors.Is(err, fs.ErrNotExist) {
+		data, err = storage.ReadFile(contentDirs[contentMap] + "/" + name + ".json")
+		if err != nil {
+			return nil, err
+		}
+		return parseContentMap(name, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseMap(name, data)
+}
+
+// parseContentMap is parseMap for a content folder map, which can't take a
+// built-in map's name: score boards list maps by name
+func parseContentMap(name string, data []byte) (*MapDef, error) {
+	m, err := parseMap(name, data)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range mapNames() {
+		if strings.EqualFold(mustLoadMap(b).Name, m.Name) {
+			return nil, fmt.Errorf("map %s: one of the game's own maps is called %q; pick another name", name, m.Name)
+		}
+	}
+	return m, nil
+}
+
+// parseMap reads and checks a map definition
+func parseMap(name string, data []byte) (*MapDef, error) {
+	m := &MapDef{File: name}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("map %s: %w", name, err)
+	}
+	if len(m.Path) < 2 {
+		return nil, fmt.Errorf("map %s: path needs at least two points", name)
+	}
+	if m.Tile <= 0 {
+		m.Tile = 40
+	}
+	for _, a := range m.Ambient {
+		if _, ok := ambienceNames[a]; !ok {
+			return nil, fmt.Errorf("map %s: unknown ambience %q", name, a)
+		}
+	}
+	if s := m.Stars; s != [3]int{} && !(0 < s[0] && s[0] < s[1] && s[1] < s[2]) {
+		return nil, fmt.Errorf("map %s: stars need three rising scores", name)
+	}
+	return m, nil
+}
+
+// mustLoadMap is loadMap for the built-in maps, which are known to be valid
+func mustLoadMap(name string) *MapDef {
+	m, err := loadMap(name)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Waypoints converts the definition's path into the enemy route
+func (m *MapDef) Waypoints() []Vec {
+	path := make([]Vec, len(m.Path))
+	for i, p := range m.Path {
+		path[i] = Vec{p[0], p[1]}
+	}
+	return path
+}
+
+// Ambience lists the map's ambient loops
+func (m *MapDef) Ambience() []Ambience {
+	var out []Ambience
+	for _, a := range m.Ambient {
+		out = append(out, ambienceNames[a])
+	}
+	return out
+}
+
+// decorArt picks the sheet frame and tint for a decoration kind
+func decorArt(kind string) (int, color.RGBA) {
+	switch kind {
+	case "rock":
+		return 1, pal.Rock
+	case "bush":
+		return 2, pal.Foliage
+	case "flowers":
+		return 3, pal.Flower
+	}
+	return 0, pal.Foliage
+}
+
+// background caches the static map layers; it is redrawn only when the theme,
+// the pixel scale or the path (a new one is rolled each level) changes
+type background struct {
+	img   *ebiten.Image
+	pal   *Palette
+	scale float64
+	path  []Vec
+}
+
+// drawBackground draws grass tiles, the road and decorations under everything else
+func (g *Game) drawBackground(screen *ebiten.Image) {
+	bg := &g.background
+	if bg.img == nil || bg.pal != pal || bg.scale != pixelScale || !slices.Equal(bg.path, g.path) {
+		g.renderBackground()
+	}
+	screen.DrawImage(g.background.img, nil)
+}
+
+func (g *Game) renderBackground() {
+	g.background.img = scaledImage(g.background.img, MapW, MapH)
+	img := g.background.img
+	img.Clear()
+	m := g.mapDef
+	// grass, with the variant picked by a hash of the tile position so the pattern doesn't repeat
+	op := &ebiten.DrawImageOptions{}
+	scale := float64(m.Tile) / spriteCell
+	for row := 0; row*m.Tile < MapH; row++ {
+		for col := 0; col*m.Tile < MapW; col++ {
+			variant := int(uint32(col*73856093^row*19349663) % spriteFrames)
+			op.GeoM.Reset()
+			op.GeoM.Scale(scale, scale)
+			op.GeoM.Translate(float64(col*m.Tile), float64(row*m.Tile))
+			op.GeoM.Scale(pixelScale, pixelScale)
+			op.ColorScale.Reset()
+			op.ColorScale.ScaleWithColor(pal.Grass)
+			img.DrawImage(sprites[SpriteGrass][variant], op)
+		}
+	}
+	// road along the current path: a darker verge under the surface, with round joints at the corners
+	path := g.path
+	for _, layer := range []struct {
+		w   float64
+		col color.RGBA
+	}{{m.RoadWidth + 6, pal.RoadEdge}, {m.RoadWidth, pal.Road}} {
+		for i := 0; i < len(path)-1; i++ {
+			line(img, path[i].X, path[i].Y, path[i+1].X, path[i+1].Y, layer.w, layer.col)
+			if i > 0 {
+				disc(img, path[i].X, path[i].Y, layer.w/2, layer.col)
+			}
+		}
+	}
+	for _, d := range m.Decorations {
+		frame, tint := decorArt(d.Kind)
+		r := d.R
+		if r == 0 {
+			r = decorRadius
+		}
+		if g.onRoad(Vec{d.X, d.Y}, r) {
+			continue
+		}
+		drawSprite(img, SpriteDecor, frame, d.X, d.Y, 0, r, tint)
+	}
+	g.background.pal = pal
+	g.background.scale = pixelScale
+	g.background.path = g.path
+}
+
+// onRoad reports whether a circle of radius r at p overlaps the road, so
+// decorations aren't drawn on top of a path rolled after the map was made
+func (g *Game) onRoad(p Vec, r float64) bool {
+	for i := 0; i < len(g.path)-1; i++ {
+		if segmentDist(p, g.path[i], g.path[i+1]) < g.mapDef.RoadWidth/2+3+r {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package datagame
+
+import (
+	"fmt"
+	"image/color"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Times-table mastery: every answered a*b question with both factors in 1..12
+// updates that fact's score, an exponential moving average of correctness.
+// Slow answers count for half so facts must become fluent, not just known.
+const (
+	FactMax         = 12
+	factScoreWeight = 0.35   // weight of the newest answer in the moving average
+	factSlowMS      = 8000.0 // answers slower than this earn half credit
+	factCellSize    = 26     // heat-grid cell size (px)
+)
+
+type FactStat struct {
+	Asked   int     `json:"asked"`
+	Correct int     `json:"correct"`
+	Score   float64 `json:"score"` // 0..1
+}
+
+// recordFact updates the a*b fact (and its mirror b*a) from an answer
+func (p *Profile) recordFact(q *Question, correct bool, ms float64) {
+	if q.Op != "*" || q.A < 1 || q.A > FactMax || q.B < 1 || q.B > FactMax {
+		return
+	}
+	credit := 0.0
+	if correct {
+		credit = 1
+		if ms > factSlowMS {
+			credit = 0.5
+		}
+	}
+	update := func(a, b int) {
+		f := &p.Facts[a-1][b-1]
+		if f.Asked == 0 {
+			f.Score = credit
+		} else {
+			f.Score = f.Score*(1-factScoreWeight) + credit*factScoreWeight
+		}
+		f.Asked++
+		if correct {
+			f.Correct++
+		}
+	}
+	update(q.A, q.B)
+	if q.A != q.B {
+		update(q.B, q.A)
+	}
+}
+
+// weakFact picks a times-table fact, weighted toward low scores and unseen facts
+func (p *Profile) weakFact(r *rand.Rand) (int, int) {
+	total := 0.0
+	var weights [FactMax * FactMax]float64
+	for i := 0; i < FactMax; i++ {
+		for j := 0; j < FactMax; j++ {
+			f := p.Facts[i][j]
+			w := 1.0 - f.Score
+			if f.Asked == 0 {
+				w = 0.6
+			}
+			w = w*w + 0.02 // mastered facts still come up occasionally
+			weights[i*FactMax+j] = w
+			total += w
+		}
+	}
+	x := r.Float64() * total
+	for k, w := range weights {
+		if x < w {
+			return k/FactMax + 1, k%FactMax + 1
+		}
+		x -= w
+	}
+	return FactMax, FactMax
+}
+
+// focusFacts replaces a multiplication question with a weak times-table fact when
+// the "focus facts" setting is on
+func (g *Game) focusFacts(q *Question) *Question {
+	if !g.settings.FocusFacts || q.Op != "*" || g.reviews.Contains(q) {
+		return q
+	}
+	a, b := g.profile.weakFact(g.qrand)
+	return &Question{Text: fmt.Sprintf("%d * %d", a, b), Ans: a * b, Op: "*", Range: operandRange(a, b), A: a, B: b}
+}
+
+// factColor shades a mastery score from bad (0) through warning to good (1)
+func factColor(score float64) color.RGBA {
+	if score < 0.5 {
+		return lerpRGBA(pal.Bad, pal.Warn, score*2)
+	}
+	return lerpRGBA(pal.Warn, pal.Good, (score-0.5)*2)
+}
+
+// drawMastery renders the 12x12 heat-grid of times-table mastery
+func (g *Game) drawMastery(screen *ebiten.Image, x0, y0 int) {
+	cs := factCellSize
+	for i := 1; i <= FactMax; i++ {
+		drawText(screen, fmt.Sprintf("%2d", i), x0+i*cs+5, y0+16, pal.Text)
+		drawText(screen, fmt.Sprintf("%2d", i), x0+4, y0+i*cs+16, pal.Text)
+	}
+	drawText(screen, "x", x0+10, y0+16, pal.TextDim)
+	for i := 0; i < FactMax; i++ {
+		for j := 0; j < FactMax; j++ {
+			f := g.profile.Facts[i][j]
+			col := pal.TrackLight
+			if f.Asked > 0 {
+				col = factColor(f.Score)
+			}
+			rect(screen, float64(x0+(j+1)*cs+1), float64(y0+(i+1)*cs+1), float64(cs-2), float64(cs-2), col)
+		}
+	}
+	lx := x0 + (FactMax+1)*cs + 16
+	drawText(screen, tr("Legend:"), lx, y0+40, pal.Text)
+	rect(screen, float64(lx), float64(y0+50), 14, 14, pal.TrackLight)
+	drawText(screen, tr("not seen"), lx+20, y0+62, pal.Text)
+	rect(screen, float64(lx), float64(y0+72), 14, 14, factColor(0))
+	drawText(screen, tr("weak"), lx+20, y0+84, pal.Text)
+	rect(screen, float64(lx), float64(y0+94), 14, 14, factColor(0.5))
+	drawText(screen, tr("learning"), lx+20, y0+106, pal.Text)
+	rect(screen, float64(lx), float64(y0+116), 14, 14, factColor(1))
+	drawText(screen, tr("mastered"), lx+20, y0+128, pal.Text)
+}
@@ -0,0 +1,45 @@
+package datagame
+
+// textMemo holds a string formatted from game state until the values it was
+// built from (or the language) change, so HUD text isn't formatted, and
+// allocated, again every frame
+type textMemo struct {
+	key  [3]float64
+	gen  int
+	s    string
+	used bool
+}
+
+// get returns the memoised string for the key values, calling format to
+// rebuild it when they differ from last time; format is not kept, so the
+// closure passed in stays on the stack
+func (m *textMemo) get(a, b, c float64, format func() string) string {
+	key := [3]float64{a, b, c}
+	if !m.used || key != m.key || m.gen != langGen {
+		m.key, m.gen, m.s, m.used = key, langGen, format(), true
+	}
+	return m.s
+}
+
+// hudMemos are the memoised HUD strings, one per place they are drawn
+type hudMemos struct {
+	hp, lives, armor, gold, level, remaining textMemo
+	place, nextTower                         textMemo
+	towerTitle, damage, rng, fire            textMemo
+	upgrades, sell, keys, gated              textMemo
+	score, combo                             textMemo
+	// the panels for player 2, a versus opponent, a ghost and a watched game
+	coopTitle, coopBuild   textMemo
+	coopChoices            [4]textMemo
+	versusLobby, opponent  textMemo
+	ghostTitle, ghostLevel textMemo
+	watchTitle, watchOver  textMemo
+}
+
+// b2f turns a flag into a textMemo key value
+func b2f(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
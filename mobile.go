@@ -0,0 +1,35 @@
+package datagame
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// MobileGame is the game for the Android and iOS builds (see mobile/). They
+// have no command line, so every flag keeps its default, and the game is only
+// set up on its first tick, once the app has had the chance to say where its
+// files go.
+func MobileGame() ebiten.Game {
+	return &mobileGame{}
+}
+
+type mobileGame struct {
+	game ebiten.Game
+}
+
+func (m *mobileGame) Update() error {
+	if m.game == nil {
+		m.game = newSession()
+	}
+	return m.game.Update()
+}
+
+func (m *mobileGame) Draw(screen *ebiten.Image) {
+	if m.game != nil {
+		m.game.Draw(screen)
+	}
+}
+
+func (m *mobileGame) Layout(outsideWidth, outsideHeight int) (int, int) {
+	if m.game == nil {
+		return outsideWidth, outsideHeight
+	}
+	return m.game.Layout(outsideWidth, outsideHeight)
+}
@@ -0,0 +1,27 @@
+//go:build !js
+
+// Package mobile is DataGame for Android and iOS, built into a library for
+// an app with ebitenmobile:
+//
+//	ebitenmobile bind -target android -javapkg org.datagame -o datagame.aar ./mobile
+//	ebitenmobile bind -target ios -o Mobile.xcframework ./mobile
+//
+// The app shows the game in an EbitenView, and should call SetDataDir with
+// a folder of its own before the view first appears.
+package mobile
+
+import (
+	"datagame"
+
+	ebitenmobile "github.com/hajimehoshi/ebiten/v2/mobile"
+)
+
+func init() {
+	ebitenmobile.SetGame(datagame.MobileGame())
+}
+
+// SetDataDir sets the folder the game keeps its settings, profile and
+// replays in, such as Android's Context.getFilesDir()
+func SetDataDir(dir string) {
+	datagame.SetStorageDir(dir)
+}
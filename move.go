@@ -0,0 +1,156 @@
+package datagame
+
+import (
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// tower dragging: press on a tower and drag to move it, for a fee
+const (
+	// dragStartPx is how far (view px) the cursor must travel with the button
+	// down before a press on a tower becomes a drag rather than a click
+	dragStartPx = 6.0
+	// towerRadius is the drawn size of a tower; towers may not overlap each
+	// other or the road
+	towerRadius = 14.0
+)
+
+// towerDrag is a tower being dragged to a new spot
+type towerDrag struct {
+	tower        *Tower
+	fromX, fromY float64 // view position of the press
+	moving       bool    // the cursor has left the press point
+}
+
+// moveCost is what moving a tower costs; upgraded towers cost more to move
+func moveCost(tw *Tower) int { return 15 + 5*tw.Upgrades }
+
+// canPlaceTower reports whether a tower fits at p: on the map, off the road
+// and clear of every tower but skip (-1 for none)
+func (g *Game) canPlaceTower(p Vec, skip int) bool {
+	if p.X < towerRadius || p.Y < towerRadius || p.X > MapW-towerRadius || p.Y > MapH-towerRadius {
+		return false
+	}
+	if g.onRoad(p, towerRadius-3) {
+		return false
+	}
+	for i, tw := range g.towers {
+		if i != skip && math.Hypot(tw.X-p.X, tw.Y-p.Y) < 2*towerRadius {
+			return false
+		}
+	}
+	return true
+}
+
+// updateTowerDrag picks up a tower on press and drops it on release; it
+// reports whether the release ended a drag, so it isn't also taken as a click
+func (g *Game) updateTowerDrag() bool {
+	ux, uy := cursor()
+	if pointerJustPressed() && !g.challengeActive && !g.overUI(ux, uy) {
+		if i := g.towerAt(g.toWorld(ux, uy)); i >= 0 {
+			g.drag = &towerDrag{tower: g.towers[i], fromX: ux, fromY: uy}
+		}
+	}
+	d := g.drag
+	if d == nil {
+		return false
+	}
+	i := slices.Index(g.towers, d.tower)
+	if i < 0 || g.challengeActive {
+		// sold, or a challenge took over the mouse
+		g.drag = nil
+		return false
+	}
+	if !d.moving && math.Hypot(ux-d.fromX, uy-d.fromY) >= dragStartPx {
+		d.moving = true
+		g.selected = i
+	}
+	if pointerDown() {
+		return false
+	}
+	g.drag = nil
+	if !d.moving {
+		return false
+	}
+	x, y := g.toWorld(ux, uy)
+	g.dropTower(i, Vec{x, y})
+	return true
+}
+
+// overUI reports whether a view position is on a button or the open shop,
+// where presses don't pick up the tower underneath
+func (g *Game) overUI(x, y float64) bool {
+	if g.shopActive && g.shopRect().Contains(x, y) {
+		return true
+	}
+	for _, b := range g.buttons() {
+		if b.Contains(x, y) {
+			return true
+		}
+	}
+	return false
+}
+
+// dropTower moves tower i to p if the spot is free and the player can pay
+func (g *Game) dropTower(i int, p Vec) {
+	tw := g.towers[i]
+	cost := moveCost(tw)
+	switch {
+	case math.Hypot(p.X-tw.X, p.Y-tw.Y) < towerRadius:
+		// put back where it was
+		return
+	case !g.canPlaceTower(p, i):
+		g.levelMsg = tr("A tower can't go there")
+	case g.playerGold < cost:
+		g.levelMsg = trf("Moving this tower costs %d gold", cost)
+	default:
+		g.purchase(cost, Command{Kind: "move", X: tw.X, Y: tw.Y, To: p})
+		return
+	}
+	g.levelMsgTimer = 2000
+}
+
+// drawTowerDrag draws the tower being dragged as a ghost under the cursor,
+// with its range and price, green where it can go and red where it can't
+func (g *Game) drawTowerDrag(screen *ebiten.Image) {
+	d := g.drag
+	if d == nil || !d.moving {
+		return
+	}
+	i := slices.Index(g.towers, d.tower)
+	if i < 0 {
+		return
+	}
+	x, y := g.toWorld(cursor())
+	col := pal.Good
+	if !g.canPlaceTower(Vec{x, y}, i) || g.playerGold < moveCost(d.tower) {
+		col = pal.Bad
+	}
+	line(screen, d.tower.X, d.tower.Y, x, y, 1, fade(col, 0x80))
+	drawTowerGhost(screen, d.tower, x, y, col)
+	label := trf("Move: %d gold", moveCost(d.tower))
+	drawText(screen, label, int(x-textWidth(label)/2), int(y-towerRadius-8), pal.Text)
+}
+
+// rightClick cancels one thing per click, most recent first, as in most
+// strategy games: a tower drag, then any open panels, then the selection or
+// the placement point
+func (g *Game) rightClick() {
+	switch {
+	case g.drag != nil:
+		g.drag = nil
+	case g.shopActive || g.settingsActive || g.historyActive || g.reportActive || g.showDamage || g.online.active:
+		g.shopActive, g.settingsActive, g.historyActive, g.reportActive, g.showDamage = false, false, false, false, false
+		g.online.active = false
+		g.rebinding = false
+		g.endShopHold()
+	case g.aiming:
+		g.aiming = false
+	case g.selected >= 0:
+		g.selected = -1
+	default:
+		g.lastClick = Vec{}
+	}
+}
@@ -0,0 +1,187 @@
+package datagame
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Music is synthesised at runtime from short song descriptions, so the game
+// ships no audio files. Each song renders into a seamless stereo loop.
+
+// Chord is one bar of a progression: a root in semitones above the song's key,
+// and whether the third is minor
+type Chord struct {
+	Root  int
+	Minor bool
+}
+
+// Song describes a music loop. Lead is a melody of one note per eighth, in
+// semitones above the key (an octave up), with rest for silence.
+type Song struct {
+	BPM    float64
+	Key    int // MIDI note of the tonic
+	Chords []Chord
+	Lead   []int
+	// arrangement
+	BassSteps int  // bass notes per bar (0 mutes the bass)
+	ArpSteps  int  // arpeggio notes per bar (0 mutes the arpeggio)
+	Drums     bool // kick on the beat, hats between
+	LeadGain  float64
+}
+
+// rest is a silent eighth in a Song's lead
+const rest = -100
+
+var songs = [musicCount]Song{
+	MusicMenu: {
+		BPM: 84, Key: 60,
+		Chords:    []Chord{{0, false}, {9, true}, {5, false}, {7, false}},
+		Lead:      []int{7, rest, 9, 7, 4, rest, rest, rest, 9, rest, 12, 9, 5, rest, rest, rest, 9, rest, 5, 4, 2, rest, 4, rest, 2, rest, rest, rest, 7, rest, rest, rest},
+		BassSteps: 2, ArpSteps: 8, LeadGain: 0.10,
+	},
+	MusicWave: {
+		BPM: 116, Key: 57,
+		Chords:    []Chord{{0, true}, {8, false}, {3, false}, {10, false}},
+		Lead:      []int{12, rest, 10, 12, 15, rest, 12, rest, 8, rest, 7, 8, 12, rest, rest, rest, 7, rest, 10, 7, 3, rest, 5, 7, 10, rest, 12, 10, 7, rest, rest, rest},
+		BassSteps: 8, ArpSteps: 16, Drums: true, LeadGain: 0.08,
+	},
+	MusicBoss: {
+		BPM: 138, Key: 50,
+		Chords:    []Chord{{0, true}, {0, true}, {8, false}, {7, false}},
+		Lead:      []int{12, 12, 15, 12, 17, rest, 15, 12, 12, 12, 15, 12, 10, rest, 8, 7, 8, 8, 12, 8, 15, rest, 12, 8, 7, 7, 11, 7, 14, rest, 11, rest},
+		BassSteps: 16, ArpSteps: 16, Drums: true, LeadGain: 0.07,
+	},
+	MusicPause: {
+		BPM: 72, Key: 53,
+		Chords:    []Chord{{0, false}, {4, true}, {5, false}, {0, false}},
+		Lead:      []int{4, rest, rest, rest, 7, rest, rest, rest, 9, rest, rest, rest, 7, rest, rest, rest, 5, rest, rest, rest, 4, rest, 2, rest, 4, rest, rest, rest, rest, rest, rest, rest},
+		BassSteps: 1, ArpSteps: 4, LeadGain: 0.08,
+	},
+}
+
+// noteFreq is the frequency of a MIDI note
+func noteFreq(n int) float64 { return 440 * math.Pow(2, float64(n-69)/12) }
+
+// synth accumulates a stereo loop; writes past the end wrap to the start so
+// note tails carry over the loop point
+type synth struct {
+	l, r []float32
+}
+
+func (s *synth) tone(start, dur, freq, gain, pan float64, wave func(phase float64) float64) {
+	n := len(s.l)
+	i0 := int(start * sampleRate)
+	samples := int(dur * sampleRate)
+	gl, gr := gain*(1-pan), gain*(1+pan) // pan -1 (left) to 1 (right)
+	for k := 0; k < samples; k++ {
+		t := float64(k) / sampleRate
+		// short attack, exponential decay
+		env := math.Min(1, t/0.005) * math.Exp(-3*t/dur)
+		v := wave(math.Mod(freq*t, 1)) * env
+		idx := (i0 + k) % n
+		s.l[idx] += float32(v * gl)
+		s.r[idx] += float32(v * gr)
+	}
+}
+
+func sine(p float64) float64     { return math.Sin(2 * math.Pi * p) }
+func triangle(p float64) float64 { return 4*math.Abs(p-0.5) - 1 }
+func pulse(p float64) float64 {
+	if p < 0.25 {
+		return 1
+	}
+	return -0.35
+}
+
+// kick is a sine sweeping down in pitch; hat is a short burst of noise
+func (s *synth) kick(start float64) {
+	n := len(s.l)
+	i0 := int(start * sampleRate)
+	phase := 0.0
+	for k := 0; k < int(0.18*sampleRate); k++ {
+		t := float64(k) / sampleRate
+		phase += (50 + 90*math.Exp(-t*30)) / sampleRate
+		v := float32(math.Sin(2*math.Pi*phase) * math.Exp(-t*18) * 0.35)
+		s.l[(i0+k)%n] += v
+		s.r[(i0+k)%n] += v
+	}
+}
+
+func (s *synth) hat(start float64, seed *uint32) {
+	n := len(s.l)
+	i0 := int(start * sampleRate)
+	for k := 0; k < int(0.04*sampleRate); k++ {
+		*seed = *seed*1664525 + 1013904223
+		noise := float64(*seed>>8)/float64(1<<24)*2 - 1
+		v := float32(noise * math.Exp(-float64(k)/sampleRate*90) * 0.05)
+		s.l[(i0+k)%n] += v * 0.8
+		s.r[(i0+k)%n] += v
+	}
+}
+
+// render synthesises two passes of the progression as 16-bit stereo PCM
+func (song Song) render() []byte {
+	bar := 4 * 60 / song.BPM
+	bars := 2 * len(song.Chords)
+	s := newSynth(float64(bars) * bar)
+	seed := uint32(1)
+	for b := 0; b < bars; b++ {
+		c := song.Chords[b%len(song.Chords)]
+		third := 4
+		if c.Minor {
+			third = 3
+		}
+		chord := [3]int{song.Key + c.Root, song.Key + c.Root + third, song.Key + c.Root + 7}
+		t0 := float64(b) * bar
+		for i := 0; i < song.BassSteps; i++ {
+			step := bar / float64(song.BassSteps)
+			s.tone(t0+float64(i)*step, step*0.9, noteFreq(chord[0]-24), 0.22, 0, triangle)
+		}
+		for i := 0; i < song.ArpSteps; i++ {
+			step := bar / float64(song.ArpSteps)
+			note := chord[i%3]
+			if i/3%2 == 1 {
+				note += 12
+			}
+			// the arpeggio sways across the stereo field
+			s.tone(t0+float64(i)*step, step*1.5, noteFreq(note), 0.05, 0.4*math.Sin(float64(i)), pulse)
+		}
+		if song.Drums {
+			for beat := 0; beat < 4; beat++ {
+				s.kick(t0 + float64(beat)*bar/4)
+				s.hat(t0+(float64(beat)+0.5)*bar/4, &seed)
+			}
+		}
+	}
+	// the lead plays over the second pass only, so the loop breathes
+	eighth := bar / 8
+	leadStart := float64(len(song.Chords)) * bar
+	for i, note := range song.Lead {
+		if note == rest {
+			continue
+		}
+		s.tone(leadStart+float64(i)*eighth, eighth*1.8, noteFreq(song.Key+12+note), song.LeadGain, 0, sine)
+	}
+
+	return s.pcm()
+}
+
+func newSynth(seconds float64) *synth {
+	n := int(seconds * sampleRate)
+	return &synth{make([]float32, n), make([]float32, n)}
+}
+
+// pcm converts the mix to 16-bit little-endian stereo
+func (s *synth) pcm() []byte {
+	pcm := make([]byte, 4*len(s.l))
+	for i := range s.l {
+		binary.LittleEndian.PutUint16(pcm[4*i:], uint16(int16(softClip(s.l[i])*0x7FFF)))
+		binary.LittleEndian.PutUint16(pcm[4*i+2:], uint16(int16(softClip(s.r[i])*0x7FFF)))
+	}
+	return pcm
+}
+
+// softClip keeps loud passages from wrapping around
+func softClip(v float32) float32 {
+	return float32(math.Tanh(float64(v)))
+}
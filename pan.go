@@ -0,0 +1,49 @@
+package datagame
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// maxPan keeps fully off-centre sounds audible in both ears
+const maxPan = 0.8
+
+// panReader streams 16-bit stereo PCM with a left/right balance applied, so
+// one cached effect can be played from anywhere without copying it
+type panReader struct {
+	src  io.ReadSeeker
+	l, r float64
+}
+
+// newPanReader balances pcm by pan, from -1 (left) to 1 (right); the centre
+// plays both channels at full level
+func newPanReader(src io.ReadSeeker, pan float64) *panReader {
+	return &panReader{src: src, l: min(1, 1-pan), r: min(1, 1+pan)}
+}
+
+func (p *panReader) Read(b []byte) (int, error) {
+	// whole frames only, so the channels never swap
+	n, err := p.src.Read(b[:len(b)-len(b)%4])
+	for i := 0; i+4 <= n; i += 4 {
+		scale16(b[i:], p.l)
+		scale16(b[i+2:], p.r)
+	}
+	return n, err
+}
+
+func (p *panReader) Seek(offset int64, whence int) (int64, error) {
+	return p.src.Seek(offset, whence)
+}
+
+func scale16(b []byte, g float64) {
+	v := int16(binary.LittleEndian.Uint16(b))
+	binary.LittleEndian.PutUint16(b, uint16(int16(float64(v)*g)))
+}
+
+// panAt is the stereo position of a map point as seen through the camera:
+// -maxPan at the left edge of the view to maxPan at the right
+func (g *Game) panAt(x, y float64) float64 {
+	sx, _ := g.camera.ToScreen(x, y, g.viewW, g.viewH)
+	pan := 2*sx/float64(g.viewW) - 1
+	return max(-maxPan, min(maxPan, pan*maxPan))
+}
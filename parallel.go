@@ -0,0 +1,39 @@
+package datagame
+
+import (
+	"runtime"
+	"sync"
+)
+
+// parallelMinEnemies is the smallest wave worth splitting across cores; below
+// it starting the workers costs more than it saves
+const parallelMinEnemies = 512
+
+// forEnemies runs fn on every enemy. Big waves are split into one slice per
+// core and run at once when the "Multi-core updates" setting is on, so fn
+// must touch nothing but its own enemy; everything is done when it returns.
+// Each enemy's result doesn't depend on the split, but modes that must
+// replay bit-for-bit can turn the setting off to rule the workers out.
+func (g *Game) forEnemies(fn func(e *Enemy)) {
+	n := len(g.enemies)
+	workers := runtime.GOMAXPROCS(0)
+	if !g.settings.ParallelUpdate || n < parallelMinEnemies || workers < 2 {
+		for _, e := range g.enemies {
+			fn(e)
+		}
+		return
+	}
+	chunk := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for lo := 0; lo < n; lo += chunk {
+		part := g.enemies[lo:min(lo+chunk, n)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, e := range part {
+				fn(e)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,206 @@
+package datagame
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// maxParticles caps the pool; emits beyond it are dropped
+const maxParticles = 2048
+
+// Particle is one short-lived square that drifts and blends from From to To
+// over its lifetime (To is usually transparent, so it fades out)
+type Particle struct {
+	X, Y    float64
+	VX, VY  float64 // px/sec
+	Gravity float64 // px/sec² added to VY
+	Life    float64 // ms left
+	MaxLife float64
+	Size    float64
+	From    color.RGBA
+	To      color.RGBA
+}
+
+// Particles is a fixed pool: live particles are packed at the front and dead
+// ones are swapped out, so emitting and updating never allocate
+type Particles struct {
+	pool [maxParticles]Particle
+	n    int
+	// visual randomness stays off Game.rand so effects don't change the run's seed sequence
+	rand *rand.Rand
+}
+
+func newParticles() *Particles {
+	return &Particles{rand: rand.New(rand.NewSource(1))}
+}
+
+func (ps *Particles) Emit(p Particle) {
+	if ps.n == len(ps.pool) {
+		return
+	}
+	p.MaxLife = p.Life
+	ps.pool[ps.n] = p
+	ps.n++
+}
+
+// Burst emits n particles from x,y in random directions at up to speed px/sec
+func (ps *Particles) Burst(x, y float64, n int, speed, life, size float64, from, to color.RGBA) {
+	for i := 0; i < n; i++ {
+		a := ps.rand.Float64() * 2 * math.Pi
+		v := speed * (0.3 + 0.7*ps.rand.Float64())
+		ps.Emit(Particle{
+			X: x, Y: y,
+			VX: math.Cos(a) * v, VY: math.Sin(a) * v,
+			Life: life * (0.6 + 0.4*ps.rand.Float64()),
+			Size: size, From: from, To: to,
+		})
+	}
+}
+
+// Update moves every particle by dt ms of game time and drops expired ones
+func (ps *Particles) Update(dt float64) {
+	s := dt / 1000
+	for i := 0; i < ps.n; {
+		p := &ps.pool[i]
+		p.Life -= dt
+		if p.Life <= 0 {
+			ps.n--
+			ps.pool[i] = ps.pool[ps.n]
+			continue
+		}
+		p.VY += p.Gravity * s
+		p.X += p.VX * s
+		p.Y += p.VY * s
+		i++
+	}
+}
+
+// Draw draws the particles inside the visible world area
+func (ps *Particles) Draw(screen *ebiten.Image, view Rect) {
+	for i := 0; i < ps.n; i++ {
+		p := &ps.pool[i]
+		if !view.Contains(p.X, p.Y) {
+			continue
+		}
+		col := lerpRGBA(p.From, p.To, 1-p.Life/p.MaxLife)
+		rect(screen, p.X-p.Size/2, p.Y-p.Size/2, p.Size, p.Size, col)
+	}
+}
+
+// blastMS is how long an AoE explosion ring takes to expand and fade
+const blastMS = 300.0
+
+// Blast is the expanding ring drawn where an AoE shot lands, sized to its radius
+type Blast struct {
+	X, Y, R float64
+	Age     float64 // ms
+}
+
+// updateBlasts ages the explosion rings, compacting finished ones in place
+func (g *Game) updateBlasts(dt float64) {
+	live := g.blasts[:0]
+	for _, b := range g.blasts {
+		b.Age += dt
+		if b.Age < blastMS {
+			live = append(live, b)
+		}
+	}
+	g.blasts = live
+}
+
+// drawBlasts draws each ring growing out to its AoE radius over a fading flash;
+// with reduced motion the ring just fades at full size
+func (g *Game) drawBlasts(screen *ebiten.Image) {
+	for _, b := range g.blasts {
+		if !g.onScreen(b.X, b.Y, b.R) {
+			continue
+		}
+		t := b.Age / blastMS
+		a := uint8(0xFF * (1 - t))
+		if g.reducedMotion() {
+			ring(screen, b.X, b.Y, b.R, 2, fade(pal.Fire, a))
+			continue
+		}
+		if t < 0.3 {
+			disc(screen, b.X, b.Y, b.R*t/0.3, fade(pal.FireCore, a/3))
+		}
+		ring(screen, b.X, b.Y, math.Max(1, b.R*math.Sqrt(t)), 2, fade(pal.Fire, a))
+	}
+}
+
+// --- game effects ---
+
+// emitFlame sprays fire from a flame tower toward its target
+func (g *Game) emitFlame(tw *Tower, tx, ty float64) {
+	if g.reducedMotion() {
+		return
+	}
+	ps := g.particles
+	dx, dy := tx-tw.X, ty-tw.Y
+	d := math.Hypot(dx, dy)
+	if d == 0 {
+		return
+	}
+	for i := 0; i < 10; i++ {
+		spread := (ps.rand.Float64() - 0.5) * 0.5
+		a := math.Atan2(dy, dx) + spread
+		v := d * (2 + 2*ps.rand.Float64()) // reach the target in roughly 250-500ms
+		ps.Emit(Particle{
+			X: tw.X + dx/d*14, Y: tw.Y + dy/d*14,
+			VX: math.Cos(a) * v, VY: math.Sin(a) * v,
+			Life: 250 + 150*ps.rand.Float64(), Size: 4,
+			From: pal.FireCore, To: fade(pal.Fire, 0),
+		})
+	}
+}
+
+// emitBurning rises a few embers from a burning enemy, about 20 per second
+func (g *Game) emitBurning(x, y, dt float64) {
+	if g.reducedMotion() {
+		return
+	}
+	ps := g.particles
+	if ps.rand.Float64() >= dt/50 {
+		return
+	}
+	ps.Emit(Particle{
+		X: x + (ps.rand.Float64()-0.5)*16, Y: y - 4,
+		VX: (ps.rand.Float64() - 0.5) * 20, VY: -30 - 30*ps.rand.Float64(),
+		Life: 400 + 200*ps.rand.Float64(), Size: 3,
+		From: pal.Fire, To: fade(pal.FireCore, 0),
+	})
+}
+
+// emitImpact throws sparks where a bullet lands
+func (g *Game) emitImpact(x, y float64) {
+	if g.reducedMotion() {
+		return
+	}
+	g.particles.Burst(x, y, 6, 90, 200, 2, pal.Metal, fade(pal.Metal, 0))
+}
+
+// emitDeath bursts an enemy into gibs of its colour that fall as they fade
+func (g *Game) emitDeath(e *Enemy, x, y float64) {
+	if g.reducedMotion() {
+		return
+	}
+	ps := g.particles
+	col, n := pal.Enemy, 16
+	if e.Boss {
+		col, n = pal.Boss, 32
+	}
+	for i := 0; i < n; i++ {
+		a := ps.rand.Float64() * 2 * math.Pi
+		v := 60 + 100*ps.rand.Float64()
+		ps.Emit(Particle{
+			X: x, Y: y,
+			VX: math.Cos(a) * v, VY: math.Sin(a)*v - 60,
+			Gravity: 240,
+			Life:    350 + 200*ps.rand.Float64(), Size: 3 + 2*ps.rand.Float64(),
+			From: col, To: fade(col, 0),
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package datagame
+
+import "sort"
+
+// setPath switches to a new enemy route and precomputes its metrics, so
+// position lookups don't re-measure the path every frame
+func (g *Game) setPath(p []Vec) {
+	g.path = p
+	g.pathCum = make([]float64, len(p))
+	for i := 1; i < len(p); i++ {
+		g.pathCum[i] = g.pathCum[i-1] + dist(p[i-1], p[i])
+	}
+}
+
+// pathLength is the total length of the current path
+func (g *Game) pathLength() float64 {
+	return g.pathCum[len(g.pathCum)-1]
+}
+
+// pathProgress is how far along the path distance d lies, 0-1
+func (g *Game) pathProgress(d float64) float64 {
+	total := g.pathLength()
+	if total == 0 {
+		return 0
+	}
+	return d / total
+}
+
+// posAlongPath is the map position at distance d along the path, found by
+// binary search over the cumulative segment lengths
+func (g *Game) posAlongPath(d float64) Vec {
+	last := len(g.path) - 1
+	switch {
+	case d <= 0:
+		return g.path[0]
+	case d >= g.pathCum[last]:
+		return g.path[last]
+	}
+	// pathCum[i] < d <= pathCum[i+1], so segment i has non-zero length
+	i := sort.SearchFloat64s(g.pathCum, d) - 1
+	a, b := g.path[i], g.path[i+1]
+	frac := (d - g.pathCum[i]) / (g.pathCum[i+1] - g.pathCum[i])
+	return Vec{a.X + (b.X-a.X)*frac, a.Y + (b.Y-a.Y)*frac}
+}
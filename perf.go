@@ -0,0 +1,90 @@
+package datagame
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	_ "net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// profiling and benchmarking, from the command line
+var (
+	pprofAddr   = flag.String("pprof", "", "serve net/http/pprof on this address, e.g. localhost:6060")
+	benchFrames = flag.Int("bench", 0, "play this many frames of a horde level at top speed, print Update and Draw timings and allocations, then exit")
+)
+
+// startPprof serves the profiler in the background; see go tool pprof
+func startPprof(addr string) {
+	go func() {
+		log.Println("pprof:", http.ListenAndServe(addr, nil))
+	}()
+	log.Printf("pprof: serving on http://%s/debug/pprof/", addr)
+}
+
+// benchStat totals the time and heap allocations of one phase of a frame
+type benchStat struct {
+	d      time.Duration
+	allocs uint64
+	n      int
+}
+
+func (s *benchStat) measure(fn func()) {
+	var m0, m1 runtime.MemStats
+	runtime.ReadMemStats(&m0)
+	t := time.Now()
+	fn()
+	d := time.Since(t)
+	runtime.ReadMemStats(&m1)
+	s.d += d
+	s.allocs += m1.Mallocs - m0.Mallocs
+	s.n++
+}
+
+func (s benchStat) String() string {
+	if s.n == 0 {
+		return "no frames"
+	}
+	return fmt.Sprintf("%8.3f ms/frame  %8.1f allocs/frame", float64(s.d.Microseconds())/1000/float64(s.n), float64(s.allocs)/float64(s.n))
+}
+
+// benchGame runs a Game for a fixed number of frames, timing Update and
+// Draw separately, so changes to the hot path can be measured
+type benchGame struct {
+	*Game
+	left         int
+	update, draw benchStat
+	peakEnemies  int
+}
+
+// newBenchGame starts a horde level at the top speed, the heaviest load the
+// game makes
+func newBenchGame(frames int) *benchGame {
+	g := NewGame()
+	g.horde = true
+	g.startHordeLevel()
+	g.speedIdx = len(gameSpeeds) - 1
+	return &benchGame{Game: g, left: frames}
+}
+
+func (b *benchGame) Update() error {
+	if b.left == 0 {
+		fmt.Printf("bench: %d frames, up to %d enemies\n", b.update.n, b.peakEnemies)
+		fmt.Printf("  Update %v\n", b.update)
+		fmt.Printf("  Draw   %v\n", b.draw)
+		return ebiten.Termination
+	}
+	b.left--
+	var err error
+	b.update.measure(func() { err = b.Game.Update() })
+	b.peakEnemies = max(b.peakEnemies, len(b.enemies))
+	return err
+}
+
+func (b *benchGame) Draw(screen *ebiten.Image) {
+	b.draw.measure(func() { b.Game.Draw(screen) })
+}
@@ -0,0 +1,88 @@
+package datagame
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// PhotoMode hides the HUD and pauses the game so the player can frame the
+// battlefield with the camera and save it as a PNG
+type PhotoMode struct {
+	wasPaused bool // restored on exit
+	watermark bool // seed and level in the corner of the shot
+	shoot     bool // capture the next frame
+	msg       string
+}
+
+func (g *Game) openPhoto() {
+	g.photo = &PhotoMode{wasPaused: g.paused, watermark: true}
+	g.paused = true
+}
+
+func (g *Game) closePhoto() {
+	g.paused = g.photo.wasPaused
+	g.photo = nil
+}
+
+// updatePhoto handles framing (the usual camera controls), M for the watermark,
+// Enter to save and Esc or the photo key to leave
+func (g *Game) updatePhoto(dt float64) {
+	g.updateCamera(dt)
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape), inpututil.IsKeyJustPressed(g.settings.Keys.Photo):
+		g.closePhoto()
+	case inpututil.IsKeyJustPressed(ebiten.KeyM):
+		g.photo.watermark = !g.photo.watermark
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter), inpututil.IsKeyJustPressed(ebiten.KeyNumpadEnter):
+		g.photo.shoot = true
+	}
+}
+
+// drawPhoto draws the watermark, takes the shot if one was asked for, then
+// draws the hint bar, which stays out of the saved image
+func (g *Game) drawPhoto(screen *ebiten.Image) {
+	if g.photo.watermark {
+		mark := trf("Math TD - seed %d, level %d", g.seed, g.level)
+		w := textWidth(mark) + 16
+		r := g.place(AnchorBottomRight, w, 24, hudMargin)
+		rect(screen, r.X, r.Y, r.W, r.H, fade(pal.Scrim, 0x80))
+		drawText(screen, mark, int(r.X)+8, int(r.Y)+16, pal.Text)
+	}
+	if g.photo.shoot {
+		g.photo.shoot = false
+		if path, err := saveScreenshot(screen); err != nil {
+			g.photo.msg = trf("Could not save screenshot: %v", err)
+		} else {
+			g.photo.msg = trf("Saved %s", path)
+		}
+	}
+	hint := tr("Photo mode: WASD, drag or wheel to frame - Enter saves, M watermark, Esc exits")
+	if g.photo.msg != "" {
+		hint = g.photo.msg
+	}
+	w := textWidth(hint) + 16
+	r := g.place(AnchorTop, w, 24, hudMargin)
+	p := Panel{Rect: r}
+	p.Draw(screen)
+	drawText(screen, hint, int(r.X)+8, int(r.Y)+16, pal.Text)
+}
+
+// saveScreenshot exports the screen, at full screen resolution, as a
+// timestamped PNG in the screenshots folder (or as a download in a browser)
+// and returns where it went
+func saveScreenshot(screen *ebiten.Image) (string, error) {
+	b := screen.Bounds()
+	img := image.NewRGBA(b)
+	screen.ReadPixels(img.Pix)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return storage.Export(fmt.Sprintf("screenshots/battle-%s.png", time.Now().Format("20060102-150405")), buf.Bytes())
+}
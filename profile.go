@@ -0,0 +1,105 @@
+package datagame
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// OpStats accumulates answers for one operation/operand-range bucket
+type OpStats struct {
+	Op      string  `json:"op"`
+	Range   string  `json:"range"`
+	Asked   int     `json:"asked"`
+	Correct int     `json:"correct"`
+	TotalMS float64 `json:"total_ms"` // summed response time
+}
+
+func (s *OpStats) Accuracy() float64 {
+	if s.Asked == 0 {
+		return 0
+	}
+	return float64(s.Correct) / float64(s.Asked)
+}
+
+func (s *OpStats) AvgMS() float64 {
+	if s.Asked == 0 {
+		return 0
+	}
+	return s.TotalMS / float64(s.Asked)
+}
+
+// Profile is the learner's answer history, persisted between runs
+type Profile struct {
+	Stats map[string]*OpStats        `json:"stats"` // keyed by op + "|" + range
+	Facts [FactMax][FactMax]FactStat `json:"facts"` // times-table mastery, [a-1][b-1]
+}
+
+// loadProfile reads the saved profile; a missing or unreadable file gives an empty one
+func loadProfile() *Profile {
+	p := &Profile{Stats: map[string]*OpStats{}}
+	data, err := storage.ReadFile("profile.json")
+	if err != nil {
+		return p
+	}
+	if err := json.Unmarshal(data, p); err != nil || p.Stats == nil {
+		p.Stats = map[string]*OpStats{}
+	}
+	return p
+}
+
+func (p *Profile) Save() error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile("profile.json", data, 0o644)
+}
+
+// Record adds one answered question to its bucket
+func (p *Profile) Record(q *Question, correct bool, ms float64) {
+	key := q.Op + "|" + q.Range
+	s := p.Stats[key]
+	if s == nil {
+		s = &OpStats{Op: q.Op, Range: q.Range}
+		p.Stats[key] = s
+	}
+	s.Asked++
+	if correct {
+		s.Correct++
+	}
+	s.TotalMS += ms
+	p.recordFact(q, correct, ms)
+}
+
+// Sorted returns the buckets ordered by operation then range for stable display
+func (p *Profile) Sorted() []*OpStats {
+	out := make([]*OpStats, 0, len(p.Stats))
+	for _, s := range p.Stats {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Op != out[j].Op {
+			return out[i].Op < out[j].Op
+		}
+		return out[i].Range < out[j].Range
+	})
+	return out
+}
+
+// operandRange buckets a question by its largest operand so results are comparable across levels
+func operandRange(a, b int) string {
+	m := a
+	if b > m {
+		m = b
+	}
+	switch {
+	case m < 10:
+		return "1-9"
+	case m < 20:
+		return "10-19"
+	case m < 50:
+		return "20-49"
+	default:
+		return "50+"
+	}
+}
@@ -0,0 +1,96 @@
+package datagame
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// bulletTrailLen is how many past positions a bullet's trail remembers (one per tick)
+const bulletTrailLen = 6
+
+func (b *Bullet) pushTrail() {
+	b.trail[b.trailN%bulletTrailLen] = Vec{b.X, b.Y}
+	b.trailN++
+}
+
+// bulletBoundsPx is how far past the map edge a bullet may fly before it is dropped
+const bulletBoundsPx = 100.0
+
+// bulletMaxMS is how long a bullet may fly before it is dropped; a shot
+// crosses the whole map well within it
+const bulletMaxMS = 4000.0
+
+// follow steers the bullet at its target's current position while the target
+// lives, so shots don't land where the enemy used to be; once it is gone the
+// bullet carries on to where it was last seen
+func (b *Bullet) follow() {
+	e := b.Target
+	if e == nil {
+		return
+	}
+	if e.Serial != b.TargetSerial || e.HP <= 0 {
+		b.Target = nil
+		return
+	}
+	b.Tx, b.Ty = e.Pos.X, e.Pos.Y
+}
+
+// outOfBounds reports whether a bullet has left the map for good
+func (b *Bullet) outOfBounds() bool {
+	return b.X < -bulletBoundsPx || b.Y < -bulletBoundsPx ||
+		b.X > MapW+bulletBoundsPx || b.Y > MapH+bulletBoundsPx
+}
+
+// kind is the kind of tower that fired the bullet
+func (b *Bullet) kind() string {
+	if b.Src == nil {
+		return "normal"
+	}
+	return towerKind(b.Src.Type)
+}
+
+// renderPos is where the bullet is drawn, alpha of the way from where it
+// started this tick (the newest trail point) to where it is now
+func (b *Bullet) renderPos(alpha float64) Vec {
+	if b.trailN == 0 {
+		return Vec{b.X, b.Y}
+	}
+	p := b.trail[(b.trailN-1)%bulletTrailLen]
+	return Vec{p.X + (b.X-p.X)*alpha, p.Y + (b.Y-p.Y)*alpha}
+}
+
+// draw renders the bullet by tower type: a fireball for flame towers, a frost
+// bolt for slow towers and a tracer round otherwise, each behind a fading trail
+func (b *Bullet) draw(screen *ebiten.Image, alpha float64) {
+	var trail color.RGBA
+	width := 3.0
+	switch b.kind() {
+	case "flame":
+		trail, width = pal.Fire, 5
+	case "slow":
+		trail = pal.Ice
+	default:
+		trail, width = pal.Tracer, 2
+	}
+	// from the head back along the trail, thinning and fading toward the tail
+	n := min(b.trailN, bulletTrailLen)
+	head := b.renderPos(alpha)
+	prev := head
+	for i := 0; i < n; i++ {
+		p := b.trail[(b.trailN-1-i)%bulletTrailLen]
+		t := 1 - float64(i)/float64(n)
+		line(screen, prev.X, prev.Y, p.X, p.Y, width*t, fade(trail, uint8(0xC0*t)))
+		prev = p
+	}
+	switch b.kind() {
+	case "flame":
+		disc(screen, head.X, head.Y, 5, fade(pal.Fire, 0xC0))
+		disc(screen, head.X, head.Y, 3, pal.FireCore)
+	case "slow":
+		disc(screen, head.X, head.Y, 4, pal.Ice)
+		disc(screen, head.X, head.Y, 2, pal.IceCore)
+	default:
+		drawSprite(screen, SpriteBullet, 0, head.X, head.Y, 0, 4, pal.Bullet)
+	}
+}
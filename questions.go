@@ -0,0 +1,453 @@
+package datagame
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Signed-integer questions use their own operand range, independent of the
+// regular subtraction operands: |operand| <= SignedOperandMax, widening to
+// SignedOperandMaxHigh from SignedHighLevel. Multiplication joins at SignedMulLevel.
+const (
+	SignedMinLevel       = 8
+	SignedOperandMax     = 12
+	SignedOperandMaxHigh = 25
+	SignedHighLevel      = 12
+	SignedMulLevel       = 12
+)
+
+type Question struct {
+	Text   string
+	Ans    int    // answer scaled by 10^Places (e.g. 4.25 is stored as 425 with Places 2)
+	Places int    // decimal places in the answer; 0 for integer questions
+	Op     string // "+", "-", "*", "/", "mixed" for multi-term expressions, "dec", "money", "eq" or "int" (signed)
+	Range  string // operand bucket, see operandRange
+	A, B   int    // operands of a single binary question (times-table tracking)
+}
+
+// Check parses a typed answer and compares it to Ans. Decimal answers accept
+// any equivalent form ("4.5" and "4.50"), and money answers may start with "$".
+func (q *Question) Check(input string) bool {
+	input = strings.TrimPrefix(strings.TrimSpace(input), "$")
+	if input == "" || input == "-" {
+		return false
+	}
+	v, ok := parseScaled(input, q.Places)
+	return ok && v == q.Ans
+}
+
+// AnswerText formats Ans for display
+func (q *Question) AnswerText() string {
+	if q.Places == 0 {
+		return strconv.Itoa(q.Ans)
+	}
+	s := formatScaled(q.Ans, q.Places)
+	if q.Op == "money" {
+		return "$" + s
+	}
+	return s
+}
+
+// parseScaled converts a decimal string to an integer scaled by 10^places without
+// going through floats. Inputs with more significant decimals than places fail.
+func parseScaled(s string, places int) (int, bool) {
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	intPart, frac, _ := strings.Cut(s, ".")
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > places || (intPart == "" && frac == "") {
+		return 0, false
+	}
+	frac += strings.Repeat("0", places-len(frac))
+	if intPart == "" {
+		intPart = "0"
+	}
+	v, err := strconv.Atoi(intPart + frac)
+	if err != nil {
+		return 0, false
+	}
+	if neg {
+		v = -v
+	}
+	return v, true
+}
+
+func formatScaled(v, places int) string {
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	pow := 1
+	for i := 0; i < places; i++ {
+		pow *= 10
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, v/pow, places, v%pow)
+}
+
+func genQuestion(r *rand.Rand, level int) *Question {
+	// difficulty scales with level. We'll pick an operation set and operand ranges.
+	// level 1-2: small add/sub (1..12)
+	// level 3-5: larger add/sub and small mul (1..20)
+	// level 6-9: multiplication up to 12..20 and two-digit add/sub
+	// level 10+: introduce integer division and larger operands
+	// level 7+: one question in three is a multi-term expression (order of operations),
+	// with parenthesised groups from level 12
+	// level 5+: one question in four uses decimals or money
+	// level 8+: one question in five works with negative integers
+	// level 10+: one question in four is a linear equation to solve for x
+	if level >= SignedMinLevel && r.Intn(5) == 0 {
+		return genSignedQuestion(r, level)
+	}
+	if level >= 10 && r.Intn(4) == 0 {
+		return genEquationQuestion(r, level)
+	}
+	if level >= 7 && r.Intn(3) == 0 {
+		return genExprQuestion(r, level)
+	}
+	if level >= 5 && r.Intn(4) == 0 {
+		return genDecimalQuestion(r, level)
+	}
+	var a, b int
+	var op string
+	var ans int
+	if level <= 2 {
+		a = 1 + r.Intn(12)
+		b = 1 + r.Intn(12)
+		if r.Intn(2) == 0 {
+			op = "+"
+			ans = a + b
+		} else {
+			op = "-"
+			ans = a - b
+		}
+	} else if level <= 5 {
+		a = 1 + r.Intn(20)
+		b = 1 + r.Intn(20)
+		oi := r.Intn(3)
+		if oi == 0 {
+			op = "+"
+			ans = a + b
+		} else if oi == 1 {
+			op = "-"
+			ans = a - b
+		} else {
+			op = "*"
+			ans = a * b
+		}
+	} else if level <= 9 {
+		a = 2 + r.Intn(18) // 2..19
+		b = 2 + r.Intn(18)
+		oi := r.Intn(3)
+		if oi == 0 {
+			op = "+"
+			ans = a + b
+		} else if oi == 1 {
+			op = "-"
+			ans = a - b
+		} else {
+			op = "*"
+			ans = a * b
+		}
+	} else {
+		// include integer division: ensure divisible
+		ops := []int{0, 1, 2, 3} // 0:+,1:-,2:*,3:/
+		oi := ops[r.Intn(len(ops))]
+		if oi == 3 {
+			b = 2 + r.Intn(18)
+			q := 2 + r.Intn(12)
+			a = b * q
+			op = "/"
+			ans = a / b
+		} else {
+			a = 5 + r.Intn(45)
+			b = 5 + r.Intn(45)
+			if oi == 0 {
+				op = "+"
+				ans = a + b
+			} else if oi == 1 {
+				op = "-"
+				ans = a - b
+			} else {
+				op = "*"
+				ans = a * b
+			}
+		}
+	}
+	return &Question{Text: fmt.Sprintf("%d %s %d", a, op, b), Ans: ans, Op: op, Range: operandRange(a, b), A: a, B: b}
+}
+
+// Expr is a small arithmetic expression tree. A node with Op == 0 is a number leaf.
+type Expr struct {
+	Op   byte // '+', '-', '*' or 0 for a leaf
+	Val  int
+	L, R *Expr
+}
+
+func num(v int) *Expr { return &Expr{Val: v} }
+
+func (e *Expr) Eval() int {
+	switch e.Op {
+	case '+':
+		return e.L.Eval() + e.R.Eval()
+	case '-':
+		return e.L.Eval() - e.R.Eval()
+	case '*':
+		return e.L.Eval() * e.R.Eval()
+	}
+	return e.Val
+}
+
+func precedence(op byte) int {
+	if op == '*' {
+		return 2
+	}
+	return 1
+}
+
+// String prints the expression with only the parentheses precedence requires
+func (e *Expr) String() string {
+	if e.Op == 0 {
+		return fmt.Sprint(e.Val)
+	}
+	l := e.L.String()
+	if e.L.Op != 0 && precedence(e.L.Op) < precedence(e.Op) {
+		l = "(" + l + ")"
+	}
+	r := e.R.String()
+	// right operand also needs parens at equal precedence for non-associative "-" (a - (b + c))
+	if e.R.Op != 0 && (precedence(e.R.Op) < precedence(e.Op) || (precedence(e.R.Op) == precedence(e.Op) && e.Op == '-')) {
+		r = "(" + r + ")"
+	}
+	return fmt.Sprintf("%s %c %s", l, e.Op, r)
+}
+
+// maxLeaf returns the largest operand in the tree, used for range bucketing
+func (e *Expr) maxLeaf() int {
+	if e.Op == 0 {
+		return e.Val
+	}
+	l, r := e.L.maxLeaf(), e.R.maxLeaf()
+	if l > r {
+		return l
+	}
+	return r
+}
+
+// genExprQuestion builds a three-term expression such as "3 + 4 * 2". Below level 12
+// the tree is shaped so the text reads without parentheses and the player must apply
+// precedence; from level 12 either grouping may be chosen, producing "(3 + 4) * 2".
+func genExprQuestion(r *rand.Rand, level int) *Question {
+	ops := []byte{'+', '-', '*'}
+	op1 := ops[r.Intn(len(ops))]
+	op2 := ops[r.Intn(len(ops))]
+	// keep at least one multiplication so precedence actually matters
+	if op1 != '*' && op2 != '*' {
+		if r.Intn(2) == 0 {
+			op1 = '*'
+		} else {
+			op2 = '*'
+		}
+	}
+	max := 9
+	if level >= 12 {
+		max = 12
+	}
+	a := num(2 + r.Intn(max-1))
+	b := num(2 + r.Intn(max-1))
+	c := num(2 + r.Intn(max-1))
+	var e *Expr
+	if level >= 12 && r.Intn(2) == 0 {
+		// explicit grouping against natural precedence where possible
+		if precedence(op1) <= precedence(op2) {
+			e = &Expr{Op: op2, L: &Expr{Op: op1, L: a, R: b}, R: c}
+		} else {
+			e = &Expr{Op: op1, L: a, R: &Expr{Op: op2, L: b, R: c}}
+		}
+	} else if precedence(op2) > precedence(op1) {
+		// a op1 (b * c) prints as "a op1 b * c"
+		e = &Expr{Op: op1, L: a, R: &Expr{Op: op2, L: b, R: c}}
+	} else {
+		// left to right: (a op1 b) op2 c prints without parens
+		e = &Expr{Op: op2, L: &Expr{Op: op1, L: a, R: b}, R: c}
+	}
+	m := e.maxLeaf()
+	return &Question{Text: e.String(), Ans: e.Eval(), Op: "mixed", Range: operandRange(m, m)}
+}
+
+// genDecimalQuestion makes a decimal add/subtract ("2.5 + 1.75") or a money word
+// problem ("Price of 3 items at $1.20?"). Values are built in hundredths so the
+// answer is exact.
+func genDecimalQuestion(r *rand.Rand, level int) *Question {
+	if r.Intn(2) == 0 {
+		n := 2 + r.Intn(5)                  // 2..6 items
+		price := (50 + r.Intn(450)) / 5 * 5 // 0.50..4.95 in steps of 5 cents
+		if level >= 10 {
+			price = (100 + r.Intn(1900)) / 5 * 5
+		}
+		text := trf("Price of %d items at $%s?", n, formatScaled(price, 2))
+		return &Question{Text: text, Ans: n * price, Places: 2, Op: "money", Range: operandRange(n, price/100)}
+	}
+	// one or two decimal places per operand; the answer uses hundredths
+	max := 1000 // up to 9.99
+	if level >= 10 {
+		max = 5000
+	}
+	a := 10 + r.Intn(max)
+	b := 10 + r.Intn(max)
+	if r.Intn(2) == 0 {
+		// tenths only for a gentler mix, e.g. "2.5"
+		a = a / 10 * 10
+	}
+	fa, fb := trimScaled(a), trimScaled(b)
+	if r.Intn(2) == 0 {
+		return &Question{Text: fmt.Sprintf("%s + %s", fa, fb), Ans: a + b, Places: 2, Op: "dec", Range: operandRange(a/100, b/100)}
+	}
+	if a < b {
+		a, b = b, a
+		fa, fb = fb, fa
+	}
+	return &Question{Text: fmt.Sprintf("%s - %s", fa, fb), Ans: a - b, Places: 2, Op: "dec", Range: operandRange(a/100, b/100)}
+}
+
+// trimScaled formats hundredths without trailing zeros ("2.50" -> "2.5", "3.00" -> "3")
+func trimScaled(v int) string {
+	s := strings.TrimRight(formatScaled(v, 2), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// genEquationQuestion builds a linear equation backwards from an integer solution:
+// "x + 7 = 15", "x - 4 = 9", "3x = 21", and from level 14 two-step "3x + 2 = 23".
+func genEquationQuestion(r *rand.Rand, level int) *Question {
+	x := 1 + r.Intn(12)
+	kinds := 3
+	if level >= 14 {
+		kinds = 4
+	}
+	var text string
+	var m int // largest number shown, for range bucketing
+	switch r.Intn(kinds) {
+	case 0:
+		a := 1 + r.Intn(20)
+		text = fmt.Sprintf("x + %d = %d", a, x+a)
+		m = x + a
+	case 1:
+		a := 1 + r.Intn(20)
+		text = fmt.Sprintf("x - %d = %d", a, x-a)
+		m = a
+	case 2:
+		a := 2 + r.Intn(11)
+		text = fmt.Sprintf("%dx = %d", a, a*x)
+		m = a * x
+	default:
+		a := 2 + r.Intn(8)
+		b := 1 + r.Intn(15)
+		text = fmt.Sprintf("%dx + %d = %d", a, b, a*x+b)
+		m = a*x + b
+	}
+	return &Question{Text: tr("Solve for x: ") + text, Ans: x, Op: "eq", Range: operandRange(m, m)}
+}
+
+// Topics are the question categories a teacher can allow, matching Question.Op
+var Topics = []string{"+", "-", "*", "/", "int", "mixed", "dec", "money", "eq"}
+
+// genTopicQuestion forces a question of one topic, with operands scaled to level
+func genTopicQuestion(r *rand.Rand, topic string, level int) *Question {
+	switch topic {
+	case "mixed":
+		return genExprQuestion(r, level)
+	case "eq":
+		return genEquationQuestion(r, level)
+	case "int":
+		return genSignedQuestion(r, level)
+	case "dec", "money":
+		for {
+			if q := genDecimalQuestion(r, level); q.Op == topic {
+				return q
+			}
+		}
+	}
+	lo, hi := 1, 12
+	switch {
+	case level >= 10:
+		lo, hi = 5, 49
+	case level >= 6:
+		lo, hi = 2, 19
+	case level >= 3:
+		lo, hi = 1, 20
+	}
+	a := lo + r.Intn(hi-lo+1)
+	b := lo + r.Intn(hi-lo+1)
+	var ans int
+	switch topic {
+	case "+":
+		ans = a + b
+	case "-":
+		ans = a - b
+	case "*":
+		ans = a * b
+	case "/":
+		b = 2 + r.Intn(8)
+		if level >= 10 {
+			b = 2 + r.Intn(18)
+		}
+		q := 2 + r.Intn(11)
+		a = b * q
+		ans = q
+	}
+	return &Question{Text: fmt.Sprintf("%d %s %d", a, topic, b), Ans: ans, Op: topic, Range: operandRange(a, b), A: a, B: b}
+}
+
+// genSignedQuestion makes an integer question with at least one negative operand,
+// e.g. "-7 + 12" or "4 - (-3)". Negative right-hand operands are parenthesised.
+func genSignedQuestion(r *rand.Rand, level int) *Question {
+	max := SignedOperandMax
+	if level >= SignedHighLevel {
+		max = SignedOperandMaxHigh
+	}
+	signed := func() int {
+		v := 1 + r.Intn(max)
+		if r.Intn(2) == 0 {
+			return -v
+		}
+		return v
+	}
+	a, b := signed(), signed()
+	// make sure the question really involves a negative number
+	if a > 0 && b > 0 {
+		if r.Intn(2) == 0 {
+			a = -a
+		} else {
+			b = -b
+		}
+	}
+	ops := []string{"+", "-"}
+	if level >= SignedMulLevel {
+		ops = append(ops, "*")
+	}
+	op := ops[r.Intn(len(ops))]
+	var ans int
+	switch op {
+	case "+":
+		ans = a + b
+	case "-":
+		ans = a - b
+	default:
+		// keep products friendly
+		a, b = clampInt(a, -12, 12), clampInt(b, -12, 12)
+		ans = a * b
+	}
+	rhs := fmt.Sprint(b)
+	if b < 0 {
+		rhs = "(" + rhs + ")"
+	}
+	abs := func(v int) int {
+		if v < 0 {
+			return -v
+		}
+		return v
+	}
+	return &Question{Text: fmt.Sprintf("%d %s %s", a, op, rhs), Ans: ans, Op: "int", Range: operandRange(abs(a), abs(b))}
+}
@@ -0,0 +1,206 @@
+package datagame
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Layer is one pass of drawWorld; later layers always draw over earlier ones,
+// whatever order entities were added in. The UI is drawn after the world by
+// drawUI, in view coordinates.
+type Layer int
+
+const (
+	LayerTerrain     Layer = iota // cached grass, road and decorations
+	LayerGround                   // overlays painted on the ground: coverage heatmap, path chevrons
+	LayerEnemies                  // corpses, then living enemies from back to front
+	LayerTowers                   // tower bases and turrets
+	LayerRanges                   // range of the selected or hovered tower
+	LayerProjectiles              // bullets and their trails
+	LayerEffects                  // blasts and particles
+	LayerBars                     // health, shield and status markers, kept readable over everything
+	layerCount
+)
+
+// drawnEnemy is an enemy with its position for this frame
+type drawnEnemy struct {
+	e *Enemy
+	p Vec
+}
+
+// cullMarginPx pads the visible area so things straddling the edge, and the bars
+// and rings drawn around them, are not cut off
+const cullMarginPx = 32.0
+
+// drawWorld draws the map contents layer by layer in map coordinates, skipping
+// whatever the camera can't see
+func (g *Game) drawWorld(screen *ebiten.Image) {
+	v := g.camera.Visible(g.viewW, g.viewH)
+	g.cull = Rect{v.X - cullMarginPx, v.Y - cullMarginPx, v.W + 2*cullMarginPx, v.H + 2*cullMarginPx}
+	setShaderUniforms(g.fxMS)
+	g.collectEnemies()
+	for l := range layerCount {
+		g.drawLayer(screen, l)
+	}
+}
+
+// collectEnemies works out where each enemy is drawn this frame, between its
+// last two ticks so motion stays smooth when FPS is above TPS, and sorts them by Y so
+// nearer (lower) enemies overlap the ones behind
+func (g *Game) collectEnemies() {
+	alpha := g.tickAlpha()
+	g.drawnEnemies = g.drawnEnemies[:0]
+	for _, e := range g.enemies {
+		p := g.renderPos(e, alpha)
+		if !g.reducedMotion() {
+			p = Vec{p.X + e.Knock.X, p.Y + e.Knock.Y}
+		}
+		if !g.onScreen(p.X, p.Y, enemyRadius(e)) {
+			continue
+		}
+		g.drawnEnemies = append(g.drawnEnemies, drawnEnemy{e, p})
+	}
+	slices.SortStableFunc(g.drawnEnemies, func(a, b drawnEnemy) int { return cmp.Compare(a.p.Y, b.p.Y) })
+}
+
+func (g *Game) drawLayer(screen *ebiten.Image, l Layer) {
+	switch l {
+	case LayerTerrain:
+		g.drawBackground(screen)
+	case LayerGround:
+		if g.showCoverage {
+			g.drawCoverage(screen)
+		}
+		g.drawChevrons(screen)
+	case LayerEnemies:
+		g.drawCorpses(screen)
+		if g.crowded() {
+			g.drawCrowd(screen)
+			break
+		}
+		for _, d := range g.drawnEnemies {
+			g.drawEnemy(screen, d.e, d.p)
+		}
+	case LayerTowers:
+		for i, tw := range g.towers {
+			if !g.onScreen(tw.X, tw.Y, 14) {
+				continue
+			}
+			c := pal.Tower
+			if g.selected == i {
+				c = pal.TowerSelected
+			}
+			drawSprite(screen, SpriteTowerBase, 0, tw.X, tw.Y, 0, 14, c)
+			turret, tint := turretArt(tw.Type)
+			drawSprite(screen, turret, tw.Anim.Frame(), tw.X, tw.Y, tw.Angle, 14, tint)
+			// player 2's towers are ringed in their cursor colour
+			if tw.Owner == 1 {
+				ring(screen, tw.X, tw.Y, 16, 2, pal.Warn)
+			}
+		}
+		g.drawGhostTowers(screen)
+		g.drawBuildGhost(screen)
+		g.drawTowerDrag(screen)
+		g.drawCoopCursor(screen)
+	case LayerRanges:
+		for i, tw := range g.towers {
+			if g.showRange(i) && g.onScreen(tw.X, tw.Y, tw.Range) {
+				disc(screen, tw.X, tw.Y, tw.Range, fade(pal.Tower, 0x20))
+				ring(screen, tw.X, tw.Y, tw.Range, 2, fade(pal.Tower, 0x60))
+			}
+		}
+	case LayerProjectiles:
+		alpha := g.tickAlpha()
+		for _, b := range g.bullets {
+			if !g.onScreen(b.X, b.Y, 0) {
+				continue
+			}
+			b.draw(screen, alpha)
+		}
+	case LayerEffects:
+		g.drawExitWarning(screen)
+		g.drawBlasts(screen)
+		g.drawAirstrikeAim(screen)
+		g.particles.Draw(screen, g.cull)
+		g.drawGoldPopups(screen)
+	case LayerBars:
+		if g.crowded() {
+			g.drawCrowdBars(screen)
+			break
+		}
+		for _, d := range g.drawnEnemies {
+			drawEnemyBars(screen, d.e, d.p)
+		}
+	}
+}
+
+// onScreen reports whether a circle of radius r at x,y overlaps the visible world area
+func (g *Game) onScreen(x, y, r float64) bool {
+	c := g.cull
+	return x+r >= c.X && x-r <= c.X+c.W && y+r >= c.Y && y-r <= c.Y+c.H
+}
+
+func enemyRadius(e *Enemy) float64 {
+	if e.Boss {
+		return 20
+	}
+	return 12
+}
+
+// drawEnemy draws an enemy's body with its status tint and rings, plus shape
+// markers for its statuses in colorblind mode
+func (g *Game) drawEnemy(screen *ebiten.Image, e *Enemy, p Vec) {
+	// visual tinting: burning -> reddish, slowed -> bluish
+	col := pal.Enemy
+	if e.BurnTime > 0 {
+		// stronger red when burn active
+		col = pal.EnemyBurning
+	}
+	if e.SlowTime > 0 || e.FreezeTime > 0 {
+		// mix with blue tint when slowed or frozen
+		col = pal.EnemySlow
+	}
+	sprite := SpriteEnemy
+	if e.Boss {
+		sprite, col = SpriteBoss, pal.Boss
+	}
+	if e.HitFlash > 0 && !g.reducedMotion() {
+		col = lerpRGBA(col, pal.Hit, 0.8)
+	}
+	radius := enemyRadius(e)
+	if sh := g.statusShader(e); sh != nil {
+		drawSpriteShader(screen, sh, sprite, e.Anim.Frame(), p.X, p.Y, 0, radius, col)
+	} else {
+		drawSprite(screen, sprite, e.Anim.Frame(), p.X, p.Y, 0, radius, col)
+	}
+
+	// slow ring indicator
+	if e.SlowTime > 0 {
+		ringR := radius + 3 + (e.SlowTime/1000.0)*3.0
+		ring(screen, p.X, p.Y, ringR, 2, fade(pal.EnemySlow, 0x80))
+	}
+	// boss shield ring
+	if e.Boss && e.Shield > 0 {
+		ring(screen, p.X, p.Y, radius+6, 3, fade(pal.Shield, 0xC0))
+	}
+	if g.settings.Colorblind {
+		drawStatusMarks(screen, e, p, radius)
+	}
+}
+
+// drawEnemyBars draws the health and shield bars and status markers above an enemy
+func drawEnemyBars(screen *ebiten.Image, e *Enemy, p Vec) {
+	barW := 30.0
+	healthW := barW * (e.HP / e.MaxHP)
+	rect(screen, p.X-barW/2, p.Y-20, barW, 5, pal.BarBack)
+	rect(screen, p.X-barW/2, p.Y-20, healthW, 5, pal.Good)
+	// status effect icons and stack counts to the right of the bar
+	drawStatuses(screen, e, p.X+barW/2+3, p.Y-18)
+	if e.Boss && e.Shield > 0 {
+		if sw := barW * e.Shield / e.MaxShield; sw >= 1 {
+			rect(screen, p.X-barW/2, p.Y-27, sw, 5, pal.Shield)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package datagame
+
+// Spaced repetition: questions answered wrongly are queued and asked again in
+// later waves. Each correct review doubles the wait (1, 2, 4 waves); a miss resets
+// it. An item is retired after ReviewRetireAfter correct reviews in a row.
+const ReviewRetireAfter = 3
+
+type ReviewItem struct {
+	Q        *Question
+	DueLevel int // first level at which the question may be asked again
+	Interval int // waves to wait after the next correct answer
+	Streak   int // correct reviews in a row
+}
+
+type ReviewQueue struct {
+	items []*ReviewItem
+}
+
+// Due returns the most overdue question for this level, or nil
+func (rq *ReviewQueue) Due(level int) *Question {
+	var best *ReviewItem
+	for _, it := range rq.items {
+		if it.DueLevel <= level && (best == nil || it.DueLevel < best.DueLevel) {
+			best = it
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return best.Q
+}
+
+// Contains reports whether q is a queued review question
+func (rq *ReviewQueue) Contains(q *Question) bool {
+	return rq.find(q) >= 0
+}
+
+func (rq *ReviewQueue) find(q *Question) int {
+	for i, it := range rq.items {
+		if it.Q == q {
+			return i
+		}
+	}
+	return -1
+}
+
+// Answered updates the schedule after q was answered at the given level. New
+// misses are queued for the next wave; reviews are rescheduled or retired.
+func (rq *ReviewQueue) Answered(q *Question, correct bool, level int) {
+	i := rq.find(q)
+	if i < 0 {
+		if !correct {
+			rq.items = append(rq.items, &ReviewItem{Q: q, DueLevel: level + 1, Interval: 1})
+		}
+		return
+	}
+	it := rq.items[i]
+	if !correct {
+		it.Streak = 0
+		it.Interval = 1
+		it.DueLevel = level + 1
+		return
+	}
+	it.Streak++
+	if it.Streak >= ReviewRetireAfter {
+		rq.items = append(rq.items[:i], rq.items[i+1:]...)
+		return
+	}
+	it.Interval *= 2
+	it.DueLevel = level + it.Interval
+}
+
+func (rq *ReviewQueue) Len() int { return len(rq.items) }
@@ -0,0 +1,290 @@
+package datagame
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Online scores are optional: with score_server set in settings.json, each
+// finished run is posted there and the U board lists the best scores per mode.
+// The backend is two JSON endpoints (see "Online scores" in the README):
+//
+//	POST {server}/scores                   body: a Score; any 2xx is success
+//	GET  {server}/scores?mode=M&limit=N    a JSON array of Scores, best first
+//
+// The daily board only lists today's challenge: its requests add &day=D.
+
+// Score is one finished run as the score server stores it
+type Score struct {
+	Name  string    `json:"name"`
+	Mode  string    `json:"mode"` // one of scoreModes
+	Score int       `json:"score"`
+	Level int       `json:"level"` // level reached
+	Map   string    `json:"map"`
+	Date  time.Time `json:"date"`
+	Day   string    `json:"day,omitempty"` // the daily challenge's date, on the daily board
+}
+
+// scoreModes are the boards, in the order Tab cycles through them
+var scoreModes = []string{"endless", "math-gated", "horde", "daily"}
+
+// scoreModeNames are the boards' titles, by scoreModes index
+var scoreModeNames = []string{"Endless", "Math-gated", "Horde", "Today's daily"}
+
+const (
+	scoreBoardRows = 10
+	scoreTimeout   = 10 * time.Second
+)
+
+// scoreClient talks to a score server at base (no trailing slash)
+type scoreClient struct {
+	base string
+	http *http.Client
+}
+
+func newScoreClient(base string) *scoreClient {
+	return &scoreClient{base: strings.TrimRight(base, "/"), http: &http.Client{Timeout: scoreTimeout}}
+}
+
+func (c *scoreClient) submit(s Score) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Post(c.base+"/scores", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("score server: %s", resp.Status)
+	}
+	return nil
+}
+
+func (c *scoreClient) top(mode string, n int) ([]Score, error) {
+	q := url.Values{"mode": {mode}, "limit": {fmt.Sprint(n)}}
+	if mode == "daily" {
+		q.Set("day", time.Now().UTC().Format(time.DateOnly))
+	}
+	resp, err := c.http.Get(c.base + "/scores?" + q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("score server: %s", resp.Status)
+	}
+	var scores []Score
+	if err := json.NewDecoder(resp.Body).Decode(&scores); err != nil {
+		return nil, err
+	}
+	return scores[:min(len(scores), n)], nil
+}
+
+// onlineScores holds what the score server last answered. Requests run on
+// their own goroutines so a slow network never stalls a frame; mu guards the
+// fields they fill in.
+type onlineScores struct {
+	mu      sync.Mutex
+	top     map[string][]Score // by mode
+	loading map[string]bool
+	err     map[string]error
+	sent    string // outcome of this run's submission, for the game-over screen
+	// board overlay (U) state, only touched on the game goroutine
+	active bool
+	mode   int // index into scoreModes
+}
+
+func newOnlineScores() *onlineScores {
+	return &onlineScores{top: map[string][]Score{}, loading: map[string]bool{}, err: map[string]error{}}
+}
+
+// scoreClient is nil when online scores are off
+func (g *Game) scoreClient() *scoreClient {
+	if g.settings.ScoreServer == "" {
+		return nil
+	}
+	return newScoreClient(g.settings.ScoreServer)
+}
+
+// runMode is the board a run counts for: the daily board for daily runs,
+// else by the modes on when it ended
+func (g *Game) runMode() string {
+	switch {
+	case g.daily != nil:
+		return "daily"
+	case g.horde:
+		return "horde"
+	case g.mathGated:
+		return "math-gated"
+	}
+	return "endless"
+}
+
+// runScore rates a run: the score it has built up (see scoring.go)
+func (g *Game) runScore() int {
+	return g.score
+}
+
+// submitScore posts the finished run to the score server, if there is one
+func (g *Game) submitScore() {
+	c := g.scoreClient()
+	if c == nil {
+		return
+	}
+	if why := g.unranked(); why != "" {
+		g.online.setSent(tr("Not sent: ") + why)
+		return
+	}
+	name := g.settings.PlayerName
+	if name == "" {
+		name = "Player"
+	}
+	s := Score{Name: name, Mode: g.runMode(), Score: g.runScore(), Level: g.level, Map: g.mapDef.Name, Date: time.Now().UTC()}
+	if g.daily != nil {
+		s.Day = g.daily.Date
+	}
+	o := g.online
+	o.setSent(tr("Sending score..."))
+	go func() {
+		err := c.submit(s)
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if err != nil {
+			o.sent = tr("Couldn't send your score: ") + err.Error()
+			return
+		}
+		o.sent = trf("Score %d sent to the %s board", s.Score, tr(scoreModeNames[modeIndex(s.Mode)]))
+		// the board refetches next time it opens
+		delete(o.top, s.Mode)
+	}()
+}
+
+// unranked says why the run can't go on the score boards, "" if it can
+func (g *Game) unranked() string {
+	switch {
+	case g.startLevel > 1:
+		// a run that skipped levels would top the board unfairly
+		return tr("the run started past level 1")
+	case g.usedTech():
+		return tr("the run used tech tree unlocks")
+	case g.customContent:
+		return tr("the run used waves or questions from the content folder")
+	case g.versus != nil:
+		return tr("versus matches don't count")
+	case g.lockstep != nil:
+		return tr("networked games don't count")
+	}
+	return ""
+}
+
+// modeIndex is mode's place in scoreModes, the first board if it isn't one
+func modeIndex(mode string) int {
+	for i, m := range scoreModes {
+		if m == mode {
+			return i
+		}
+	}
+	return 0
+}
+
+// fetchScores loads the top scores for mode in the background
+func (g *Game) fetchScores(mode string) {
+	c := g.scoreClient()
+	o := g.online
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if c == nil || o.loading[mode] {
+		return
+	}
+	o.loading[mode] = true
+	go func() {
+		scores, err := c.top(mode, scoreBoardRows)
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.loading[mode] = false
+		o.err[mode] = err
+		if err == nil {
+			o.top[mode] = scores
+		}
+	}()
+}
+
+// updateScoreBoard opens and closes the board (U) and flips modes with Tab;
+// each opening or flip fetches fresh scores
+func (g *Game) updateScoreBoard() {
+	o := g.online
+	if inpututil.IsKeyJustPressed(ebiten.KeyU) && !g.challengeActive {
+		o.active = !o.active
+		if o.active {
+			o.mode = modeIndex(g.runMode())
+			g.fetchScores(scoreModes[o.mode])
+		}
+	}
+	if o.active && inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		o.mode = (o.mode + 1) % len(scoreModes)
+		g.fetchScores(scoreModes[o.mode])
+	}
+}
+
+// drawScoreBoard lists the shown mode's top scores, or why there are none
+func (g *Game) drawScoreBoard(screen *ebiten.Image) {
+	o := g.online
+	mode := scoreModes[o.mode]
+	r := g.centered(560, 60+float64(scoreBoardRows*leaderboardRowH))
+	p := Panel{Rect: r, Title: trf("Top scores: %s (press U to close)", tr(scoreModeNames[o.mode]))}
+	p.Draw(screen)
+	x, y := p.Content()
+	drawText(screen, tr("Tab: next mode"), int(r.X+r.W)-int(textWidth(tr("Tab: next mode")))-10, int(r.Y)+16, pal.TextDim)
+	if g.settings.ScoreServer == "" {
+		drawText(screen, tr("Online scores are off. Set score_server in settings.json to turn them on."), x, y, pal.TextDim)
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	scores, loaded := o.top[mode]
+	switch {
+	case o.loading[mode] && !loaded:
+		drawText(screen, tr("Loading..."), x, y, pal.TextDim)
+		return
+	case o.err[mode] != nil:
+		drawText(screen, tr("Couldn't reach the score server: ")+o.err[mode].Error(), x, y, pal.Bad)
+		return
+	case len(scores) == 0:
+		drawText(screen, tr("No scores yet"), x, y, pal.TextDim)
+		return
+	}
+	for i, s := range scores {
+		yy := y + i*leaderboardRowH
+		col := pal.Text
+		if s.Name == g.settings.PlayerName {
+			col = pal.Warn
+		}
+		drawText(screen, fmt.Sprintf("%2d. %s", i+1, s.Name), x, yy, col)
+		drawText(screen, trf("level %d", s.Level), x+220, yy, pal.TextDim)
+		drawText(screen, fmt.Sprint(s.Score), x+320, yy, col)
+	}
+}
+
+// sentStatus is this run's submission outcome, "" if nothing was sent
+func (o *onlineScores) sentStatus() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.sent
+}
+
+func (o *onlineScores) setSent(s string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.sent = s
+}
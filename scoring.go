@@ -0,0 +1,150 @@
+package datagame
+
+import (
+	"encoding/json"
+)
+
+// A run's score is apart from its gold: kills, levels cleared without a leak
+// and right answers (more for quick ones) score points, times the combo
+// multiplier. Right answers and clean levels build the combo, and a wrong
+// answer or a leak breaks it. The final score is what the score boards and
+// ghosts compare, and rates the run on its map with up to three stars; the
+// best score and stars on each map are kept in records.json.
+
+const (
+	scoreKill      = 10
+	scoreBossKill  = 250
+	scoreAnswer    = 50
+	scoreQuick     = 50 // more for an answer within quickAnswerMS
+	quickAnswerMS  = 5000.0
+	scoreCleanWave = 100 // times the level, for a level cleared without a leak
+	// the combo: a right answer adds a point, a quick one two, a clean
+	// level comboCleanWave; each point is comboStep more multiplier, up to
+	// comboMax points
+	comboCleanWave = 3
+	comboStep      = 0.1
+	comboMax       = 40
+)
+
+// defaultStars are the scores for one, two and three stars on a map that
+// doesn't set its own
+var defaultStars = [3]int{2000, 8000, 20000}
+
+// comboMult is what points are multiplied by
+func (g *Game) comboMult() float64 {
+	return 1 + comboStep*float64(g.combo)
+}
+
+// addScore scores base points at the current multiplier
+func (g *Game) addScore(base int) {
+	g.score += int(float64(base) * g.comboMult())
+}
+
+// addCombo raises the combo by n points
+func (g *Game) addCombo(n int) {
+	g.combo = min(g.combo+n, comboMax)
+	g.bestCombo = max(g.bestCombo, g.combo)
+}
+
+// scoreKill scores a kill
+func (g *Game) scoreKill(e *Enemy) {
+	if e.Boss {
+		g.addScore(scoreBossKill)
+		return
+	}
+	g.addScore(scoreKill)
+}
+
+// scoreAnswer scores an answer given after ms: a right one raises the combo
+// and then scores, a wrong one breaks it
+func (g *Game) scoreAnswer(correct bool, ms float64) {
+	if !correct {
+		g.combo = 0
+		return
+	}
+	if ms <= quickAnswerMS {
+		g.addCombo(2)
+		g.addScore(scoreAnswer + scoreQuick)
+		return
+	}
+	g.addCombo(1)
+	g.addScore(scoreAnswer)
+}
+
+// scoreLevel rewards the level just filed in lastWave if nothing leaked,
+// returning the points it scored
+func (g *Game) scoreLevel() int {
+	if g.lastWave.Leaks > 0 {
+		return 0
+	}
+	g.addCombo(comboCleanWave)
+	before := g.score
+	g.addScore(scoreCleanWave * g.lastWave.Level)
+	return g.score - before
+}
+
+// stars rates score on the map, 0 to 3
+func (m *MapDef) stars(score int) int {
+	need := defaultStars
+	if m.Stars != [3]int{} {
+		need = m.Stars
+	}
+	n := 0
+	for _, s := range need {
+		if score >= s {
+			n++
+		}
+	}
+	return n
+}
+
+// MapRecord is the best run on one map
+type MapRecord struct {
+	Best  int `json:"best"`
+	Stars int `json:"stars"`
+}
+
+// Records are the best runs by map file name, kept in records.json
+type Records map[string]*MapRecord
+
+// loadRecords reads the saved records; a missing or unreadable file gives none
+func loadRecords() Records {
+	r := Records{}
+	data, err := storage.ReadFile("records.json")
+	if err != nil {
+		return r
+	}
+	if err := json.Unmarshal(data, &r); err != nil || r == nil {
+		return Records{}
+	}
+	return r
+}
+
+func (r Records) Save() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile("records.json", data, 0o644)
+}
+
+// recordScore rates the finished run and keeps it if it is the map's best.
+// Like the score boards, it leaves out runs that unranked turns away.
+func (g *Game) recordScore() {
+	score := g.runScore()
+	g.runStars = g.mapDef.stars(score)
+	if g.unranked() != "" {
+		return
+	}
+	rec := g.records[g.mapDef.File]
+	if rec == nil {
+		rec = &MapRecord{}
+		g.records[g.mapDef.File] = rec
+	}
+	if score <= rec.Best {
+		return
+	}
+	g.newBest = true
+	rec.Best, rec.Stars = score, max(rec.Stars, g.runStars)
+	g.records.Save()
+}
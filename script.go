@@ -0,0 +1,445 @@
+package datagame
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Scripts let modders change how towers and waves behave without rebuilding
+// the game. They are written in a small expression language, not a general
+// one, so a script can only read and change what the game hands it and
+// always finishes: there are no loops, files or network.
+//
+// A script is a list of statements, one per line or separated by ';', and
+// '#' starts a comment. A statement is either an assignment, name = expr, or
+// an expression run for its effect, usually a call such as burn(2000).
+// Expressions have numbers, + - * / %, comparisons (< <= > >= == !=), && ||
+// and !, and the conditional c ? a : b. True is 1 and false is 0, and
+// dividing by zero gives 0. Assigning to a new name makes a local variable.
+//
+// Every script can call min(a, b), max(a, b), abs(x), floor(x), ceil(x),
+// sqrt(x) and clamp(x, lo, hi), and chance(p), true with probability p, and
+// random(lo, hi), a number between lo and hi, which draw on the run's random
+// numbers so a seed still replays the same. What else a script sees depends
+// on where it runs; see towerHitAPI, levelScriptAPI and spawnScriptAPI.
+
+// scriptAPI is what one kind of script may use: variables it can read, the
+// ones among them it can change, and functions
+type scriptAPI struct {
+	vars  []string
+	sets  []string
+	funcs map[string]scriptFunc
+}
+
+// scriptFunc is a function a script can call; it finds what it acts on, e.g.
+// the tower hit, in r.ctx
+type scriptFunc struct {
+	args int
+	call func(r *scriptRun, a []float64) float64
+}
+
+// scriptMath are the functions every script has
+var scriptMath = map[string]scriptFunc{
+	"min":    {2, func(_ *scriptRun, a []float64) float64 { return math.Min(a[0], a[1]) }},
+	"max":    {2, func(_ *scriptRun, a []float64) float64 { return math.Max(a[0], a[1]) }},
+	"abs":    {1, func(_ *scriptRun, a []float64) float64 { return math.Abs(a[0]) }},
+	"floor":  {1, func(_ *scriptRun, a []float64) float64 { return math.Floor(a[0]) }},
+	"ceil":   {1, func(_ *scriptRun, a []float64) float64 { return math.Ceil(a[0]) }},
+	"sqrt":   {1, func(_ *scriptRun, a []float64) float64 { return math.Sqrt(math.Max(0, a[0])) }},
+	"clamp":  {3, func(_ *scriptRun, a []float64) float64 { return math.Max(a[1], math.Min(a[2], a[0])) }},
+	"chance": {1, func(r *scriptRun, a []float64) float64 { return truth(r.rand.Float64() < a[0]) }},
+	"random": {2, func(r *scriptRun, a []float64) float64 { return a[0] + r.rand.Float64()*(a[1]-a[0]) }},
+}
+
+// script is a compiled script
+type script struct {
+	api   *scriptAPI
+	stmts []scriptStmt
+	slots map[string]int // variable name to index into a run's values
+}
+
+type scriptStmt struct {
+	set  int // slot assigned, -1 for an expression statement
+	expr scriptExpr
+}
+
+// scriptRun is one running of a script: its variables' values, the context
+// the API's functions act on and the run's random numbers
+type scriptRun struct {
+	vals []float64
+	ctx  any
+	rand *rand.Rand
+}
+
+type scriptExpr interface {
+	eval(r *scriptRun) float64
+}
+
+type (
+	scriptNum  float64
+	scriptVar  int // slot
+	scriptNot  struct{ x scriptExpr }
+	scriptNeg  struct{ x scriptExpr }
+	scriptCond struct{ c, a, b scriptExpr }
+	scriptOp   struct {
+		op   string
+		a, b scriptExpr
+	}
+	scriptCall struct {
+		fn   scriptFunc
+		args []scriptExpr
+	}
+)
+
+func (n scriptNum) eval(*scriptRun) float64   { return float64(n) }
+func (v scriptVar) eval(r *scriptRun) float64 { return r.vals[v] }
+func (n scriptNot) eval(r *scriptRun) float64 { return truth(n.x.eval(r) == 0) }
+func (n scriptNeg) eval(r *scriptRun) float64 { return -n.x.eval(r) }
+
+func (c scriptCond) eval(r *scriptRun) float64 {
+	if c.c.eval(r) != 0 {
+		return c.a.eval(r)
+	}
+	return c.b.eval(r)
+}
+
+func (o scriptOp) eval(r *scriptRun) float64 {
+	a := o.a.eval(r)
+	switch o.op {
+	case "&&":
+		return truth(a != 0 && o.b.eval(r) != 0)
+	case "||":
+		return truth(a != 0 || o.b.eval(r) != 0)
+	}
+	b := o.b.eval(r)
+	switch o.op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	case "/":
+		if b == 0 {
+			return 0
+		}
+		return a / b
+	case "%":
+		if b == 0 {
+			return 0
+		}
+		return math.Mod(a, b)
+	case "<":
+		return truth(a < b)
+	case "<=":
+		return truth(a <= b)
+	case ">":
+		return truth(a > b)
+	case ">=":
+		return truth(a >= b)
+	case "==":
+		return truth(a == b)
+	}
+	return truth(a != b) // "!="
+}
+
+func (c scriptCall) eval(r *scriptRun) float64 {
+	var buf [3]float64
+	args := buf[:0]
+	for _, a := range c.args {
+		args = append(args, a.eval(r))
+	}
+	v := c.fn.call(r, args)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return v
+}
+
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// run runs the script on ctx with the API's variables set to vars, in the
+// order of api.vars, leaving their values afterwards in vars
+func (s *script) run(ctx any, rng *rand.Rand, vars []float64) {
+	r := scriptRun{vals: make([]float64, len(s.slots)), ctx: ctx, rand: rng}
+	copy(r.vals, vars)
+	for _, st := range s.stmts {
+		v := st.expr.eval(&r)
+		if st.set >= 0 {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				v = 0
+			}
+			r.vals[st.set] = v
+		}
+	}
+	copy(vars, r.vals)
+}
+
+// compileScript parses src against api; "" compiles to nil, no script
+func compileScript(src string, api *scriptAPI) (*script, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, nil
+	}
+	toks, err := lexScript(src)
+	if err != nil {
+		return nil, err
+	}
+	p := scriptParser{toks: toks, s: &script{api: api, slots: map[string]int{}}}
+	for i, name := range api.vars {
+		p.s.slots[name] = i
+	}
+	for !p.at("") {
+		if p.at(";") {
+			p.next()
+			continue
+		}
+		st, err := p.stmt()
+		if err != nil {
+			return nil, err
+		}
+		p.s.stmts = append(p.s.stmts, st)
+		if !p.at(";") && !p.at("") {
+			return nil, p.errorf("expected the end of the statement, found %q", p.peek().text)
+		}
+	}
+	return p.s, nil
+}
+
+// scriptTok is a token: an operator, "num", "name", ";" for a statement
+// break, or "" at the end
+type scriptTok struct {
+	kind, text string
+	line       int
+}
+
+func lexScript(src string) ([]scriptTok, error) {
+	var toks []scriptTok
+	line := 1
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		c := rs[i]
+		switch {
+		case c == '\n':
+			toks = append(toks, scriptTok{";", "\\n", line})
+			line++
+			i++
+		case c == '#':
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			toks = append(toks, scriptTok{"num", string(rs[i:j]), line})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_') {
+				j++
+			}
+			toks = append(toks, scriptTok{"name", string(rs[i:j]), line})
+			i = j
+		default:
+			op := string(c)
+			if i+1 < len(rs) {
+				if two := string(rs[i : i+2]); strings.Contains(" <= >= == != && || ", " "+two+" ") {
+					op = two
+				}
+			}
+			if !strings.Contains("+-*/%()<>=!?:,;", op) && len(op) == 1 {
+				return nil, fmt.Errorf("line %d: unexpected %q", line, op)
+			}
+			toks = append(toks, scriptTok{op, op, line})
+			i += len([]rune(op))
+		}
+	}
+	return append(toks, scriptTok{"", "end of script", line}), nil
+}
+
+type scriptParser struct {
+	toks []scriptTok
+	pos  int
+	s    *script
+}
+
+func (p *scriptParser) peek() scriptTok { return p.toks[p.pos] }
+func (p *scriptParser) at(kind string) bool {
+	return p.toks[p.pos].kind == kind
+}
+
+func (p *scriptParser) next() scriptTok {
+	t := p.toks[p.pos]
+	if t.kind != "" {
+		p.pos++
+	}
+	return t
+}
+
+func (p *scriptParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.peek().line, fmt.Sprintf(format, args...))
+}
+
+func (p *scriptParser) expect(kind string) error {
+	if !p.at(kind) {
+		return p.errorf("expected %q, found %q", kind, p.peek().text)
+	}
+	p.next()
+	return nil
+}
+
+// stmt parses an assignment or an expression statement
+func (p *scriptParser) stmt() (scriptStmt, error) {
+	if p.at("name") && p.toks[p.pos+1].kind == "=" {
+		name := p.next().text
+		p.next()
+		x, err := p.expr()
+		if err != nil {
+			return scriptStmt{}, err
+		}
+		slot, ok := p.s.slots[name]
+		switch {
+		case ok && slot < len(p.s.api.vars) && !slices.Contains(p.s.api.sets, name):
+			return scriptStmt{}, p.errorf("%s can't be changed", name)
+		case !ok:
+			if _, fn := p.function(name); fn {
+				return scriptStmt{}, p.errorf("%s is a function", name)
+			}
+			slot = len(p.s.slots)
+			p.s.slots[name] = slot
+		}
+		return scriptStmt{set: slot, expr: x}, nil
+	}
+	x, err := p.expr()
+	return scriptStmt{set: -1, expr: x}, err
+}
+
+// function looks a function up in the script's API, then the maths
+func (p *scriptParser) function(name string) (scriptFunc, bool) {
+	if f, ok := p.s.api.funcs[name]; ok {
+		return f, true
+	}
+	f, ok := scriptMath[name]
+	return f, ok
+}
+
+// scriptLevels are the binary operators by precedence, loosest first
+var scriptLevels = [][]string{{"||"}, {"&&"}, {"==", "!=", "<", "<=", ">", ">="}, {"+", "-"}, {"*", "/", "%"}}
+
+func (p *scriptParser) expr() (scriptExpr, error) {
+	c, err := p.binary(0)
+	if err != nil || !p.at("?") {
+		return c, err
+	}
+	p.next()
+	a, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	b, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return scriptCond{c, a, b}, nil
+}
+
+func (p *scriptParser) binary(level int) (scriptExpr, error) {
+	if level == len(scriptLevels) {
+		return p.unary()
+	}
+	a, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for slices.Contains(scriptLevels[level], p.peek().kind) {
+		op := p.next().kind
+		b, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		a = scriptOp{op, a, b}
+	}
+	return a, nil
+}
+
+func (p *scriptParser) unary() (scriptExpr, error) {
+	switch {
+	case p.at("!"), p.at("-"):
+		op := p.next().kind
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if op == "!" {
+			return scriptNot{x}, nil
+		}
+		return scriptNeg{x}, nil
+	case p.at("("):
+		p.next()
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	case p.at("num"):
+		t := p.next()
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad number %q", t.line, t.text)
+		}
+		return scriptNum(v), nil
+	case p.at("name"):
+		name := p.next().text
+		if p.at("(") {
+			return p.call(name)
+		}
+		slot, ok := p.s.slots[name]
+		if !ok {
+			return nil, p.errorf("unknown variable %s", name)
+		}
+		return scriptVar(slot), nil
+	}
+	return nil, p.errorf("unexpected %q", p.peek().text)
+}
+
+func (p *scriptParser) call(name string) (scriptExpr, error) {
+	fn, ok := p.function(name)
+	if !ok {
+		return nil, p.errorf("unknown function %s", name)
+	}
+	p.next() // (
+	var args []scriptExpr
+	for !p.at(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		a, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+	}
+	p.next()
+	if len(args) != fn.args {
+		return nil, p.errorf("%s takes %d arguments, not %d", name, fn.args, len(args))
+	}
+	return scriptCall{fn, args}, nil
+}
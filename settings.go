@@ -0,0 +1,370 @@
+package datagame
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// GradeBand clamps the level passed to genQuestion so question difficulty can be
+// fixed independently of how far the tower defense has progressed
+type GradeBand struct {
+	Name               string
+	MinLevel, MaxLevel int
+}
+
+// GradeBands index 0 follows the game level; the rest map school grades onto genQuestion's tiers
+var GradeBands = []GradeBand{
+	{"Auto (follows level)", 1, 1 << 30},
+	{"Grades 1-2: add/subtract", 1, 2},
+	{"Grade 3: + multiplication", 3, 4},
+	{"Grade 4: + decimals", 5, 6},
+	{"Grade 5: + order of operations", 7, 9},
+	{"Grade 6+: division, equations", 10, 14},
+}
+
+// Settings are player options saved between sessions
+type Settings struct {
+	Version       int         `json:"version"`        // settingsVersion when saved
+	GradeBand     int         `json:"grade_band"`     // index into GradeBands
+	ReadQuestions bool        `json:"read_questions"` // speak each question when it appears
+	VoiceCallouts bool        `json:"voice_callouts"` // read questions from recorded clips where a voice pack has them
+	Language      string      `json:"language"`       // translation code, "en" by default
+	FocusFacts    bool        `json:"focus_facts"`    // bias multiplication toward weak times-table facts
+	Keys          KeyBindings `json:"keys"`
+	Theme         string      `json:"theme"`          // Palette ID, "default" by default
+	Colorblind    bool        `json:"colorblind"`     // mark status effects with shapes as well as colour
+	ReducedMotion bool        `json:"reduced_motion"` // no particles, flashing or other purely decorative motion
+	// ParallelUpdate spreads big waves' enemy updates over all cores
+	ParallelUpdate bool `json:"parallel_update"`
+	// LowTickRate runs the simulation at 30 ticks a second instead of 60, for slow machines
+	LowTickRate bool `json:"low_tick_rate"`
+	// volumes in percent, in steps of volumeStep; Muted silences everything (M)
+	MasterVolume int  `json:"master_volume"`
+	MusicVolume  int  `json:"music_volume"`
+	SFXVolume    int  `json:"sfx_volume"`
+	Muted        bool `json:"muted"`
+	// online scores (U board): the score server's base URL, "" for off, and the
+	// name runs are posted under; both are only set by editing settings.json
+	ScoreServer string `json:"score_server"`
+	PlayerName  string `json:"player_name"`
+	// DiscordStatus shows the mode and level on the player's Discord profile
+	DiscordStatus bool `json:"discord_status"`
+	// the content folder's wave set and question bank in use (I), "" for the
+	// game's own waves and generated questions
+	ContentWaves     string `json:"content_waves"`
+	ContentQuestions string `json:"content_questions"`
+	// newer is set when a newer game wrote settings.json: saving would drop
+	// what this one doesn't know, so the file is left as it is
+	newer bool
+	// the saved values -difficulty and -mute replaced, nil for none; see
+	// launchSettings
+	savedBand  *int
+	savedMuted *bool
+}
+
+// errNewerFile is Save's answer for a file a newer game wrote
+var errNewerFile = errors.New("saved by a newer version of the game; not overwritten")
+
+// volumeStep is how much Left/Right change a volume setting, in percent
+const volumeStep = 10
+
+func defaultSettings() *Settings {
+	return &Settings{Language: "en", Keys: defaultKeyBindings(), Theme: "default",
+		MasterVolume: 80, MusicVolume: 70, SFXVolume: 80, ParallelUpdate: true}
+}
+
+// settingsVersion is the layout of settings.json this game writes. When a
+// field is renamed or changes meaning, bump it and add the step that rewrites
+// the old layout to settingsMigrations.
+const settingsVersion = 1
+
+// settingsMigrations bring an old settings.json up to date, one version at a
+// time: step i turns a version i file into version i+1. Files from before
+// versioning are version 0.
+var settingsMigrations = []migration{
+	// 0 to 1: the unversioned layout is version 1's without the number; the
+	// fields older files lack (volumes, tick rate and so on) keep their defaults
+	func(map[string]json.RawMessage) {},
+}
+
+// migration rewrites a saved JSON object from one version's layout to the next
+type migration func(m map[string]json.RawMessage)
+
+// loadVersioned reads the JSON object saved as name over v, which holds the
+// defaults. The file is first brought up to version by migrations, then
+// decoded a field at a time, so one bad value keeps its default rather than
+// losing the rest of the file. A file from a newer game is read as far as
+// this one understands it, and shouldn't be saved over. It returns the
+// version the file was saved at.
+func loadVersioned(name string, v any, version int, migrations []migration) (int, error) {
+	data, err := storage.ReadFile(name)
+	if err != nil {
+		return 0, err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return 0, err
+	}
+	from := 0
+	json.Unmarshal(m["version"], &from) // missing: unversioned
+	for i := max(from, 0); i < min(version, len(migrations)); i++ {
+		migrations[i](m)
+	}
+	for k, raw := range m {
+		field, err := json.Marshal(map[string]json.RawMessage{k: raw})
+		if err == nil {
+			json.Unmarshal(field, v)
+		}
+	}
+	return from, nil
+}
+
+// loadSettings reads settings.json, upgrading it if an older game wrote it;
+// missing or invalid values fall back to defaults
+func loadSettings() *Settings {
+	s := defaultSettings()
+	from, err := loadVersioned("settings.json", s, settingsVersion, settingsMigrations)
+	if err != nil {
+		return defaultSettings()
+	}
+	if s.GradeBand < 0 || s.GradeBand >= len(GradeBands) {
+		s.GradeBand = 0
+	}
+	if languages[s.Language] == nil {
+		s.Language = "en"
+	}
+	s.Theme = themes[themeIndex(s.Theme)].ID
+	for _, v := range []*int{&s.MasterVolume, &s.MusicVolume, &s.SFXVolume} {
+		*v = max(0, min(100, *v))
+	}
+	s.newer = from > settingsVersion
+	if from < settingsVersion {
+		s.Save()
+	}
+	return s
+}
+
+func (s *Settings) Save() error {
+	if s.newer {
+		return errNewerFile
+	}
+	s.Version = settingsVersion
+	data, err := json.MarshalIndent(s.unlaunched(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile("settings.json", data, 0o644)
+}
+
+// questionLevel applies the configured grade band to a requested question level
+func (g *Game) questionLevel(level int) int {
+	b := GradeBands[g.settings.GradeBand]
+	if level < b.MinLevel {
+		return b.MinLevel
+	}
+	if level > b.MaxLevel {
+		return b.MaxLevel
+	}
+	return level
+}
+
+// mix converts the volume settings to the gains the audio manager applies
+func (s *Settings) mix() Mix {
+	if s.Muted {
+		return Mix{}
+	}
+	master := float64(s.MasterVolume) / 100
+	return Mix{Music: master * float64(s.MusicVolume) / 100, SFX: master * float64(s.SFXVolume) / 100}
+}
+
+// toggleMute flips the mute setting (M) and saves it
+func (g *Game) toggleMute() {
+	g.settings.Muted = !g.settings.Muted
+	g.settings.Save()
+	if g.settings.Muted {
+		g.levelMsg = tr("Sound muted (M)")
+	} else {
+		g.levelMsg = tr("Sound on")
+	}
+	g.levelMsgTimer = 1500
+}
+
+// reducedMotion reports whether decorative motion is turned off: particles,
+// hit flashes and knocks, heat shimmer, flying coins and blinking. Anything that
+// carries information (tints, status markers, bars, AoE rings) is still drawn,
+// just held still.
+func (g *Game) reducedMotion() bool { return g.settings.ReducedMotion }
+
+// settingRow is one line of the options overlay: a label and a handler for
+// Left/Right; rows with a value also show it as a slider
+type settingRow struct {
+	label  func() string
+	adjust func(dir int)
+	value  func() float64 // 0-1, or nil
+}
+
+// volumeRow is a slider row for one of the volume settings
+func volumeRow(name string, v *int) settingRow {
+	return settingRow{
+		label:  func() string { return tr(name) + fmt.Sprintf("%d%%", *v) },
+		adjust: func(dir int) { *v = max(0, min(100, *v+dir*volumeStep)) },
+		value:  func() float64 { return float64(*v) / 100 },
+	}
+}
+
+func (g *Game) settingRows() []settingRow {
+	s := g.settings
+	return []settingRow{
+		{
+			label: func() string { return tr("Question difficulty: ") + tr(GradeBands[s.GradeBand].Name) },
+			adjust: func(dir int) {
+				s.GradeBand = (s.GradeBand + dir + len(GradeBands)) % len(GradeBands)
+			},
+		},
+		{
+			label: func() string { return tr("Language: ") + languageName(s.Language) },
+			adjust: func(dir int) {
+				codes := languageCodes()
+				i := 0
+				for j, c := range codes {
+					if c == s.Language {
+						i = j
+					}
+				}
+				s.Language = codes[(i+dir+len(codes))%len(codes)]
+				setLanguage(s.Language)
+			},
+		},
+		{
+			label: func() string { return tr("Theme: ") + tr(pal.Name) },
+			adjust: func(dir int) {
+				i := (themeIndex(s.Theme) + dir + len(themes)) % len(themes)
+				s.Theme = themes[i].ID
+				setTheme(s.Theme)
+			},
+		},
+		{
+			label:  func() string { return tr("Colorblind mode (status patterns): ") + onOff(s.Colorblind) },
+			adjust: func(int) { s.Colorblind = !s.Colorblind },
+		},
+		{
+			label:  func() string { return tr("Reduced motion: ") + onOff(s.ReducedMotion) },
+			adjust: func(int) { s.ReducedMotion = !s.ReducedMotion },
+		},
+		{
+			label:  func() string { return tr("Multi-core updates for big waves: ") + onOff(s.ParallelUpdate) },
+			adjust: func(int) { s.ParallelUpdate = !s.ParallelUpdate },
+		},
+		{
+			label: func() string { return tr("Low tick rate (30/s) for slow computers: ") + onOff(s.LowTickRate) },
+			adjust: func(int) {
+				s.LowTickRate = !s.LowTickRate
+				applyTickRate(s)
+			},
+		},
+		{
+			label:  func() string { return tr("Focus on weak times-table facts: ") + onOff(s.FocusFacts) },
+			adjust: func(int) { s.FocusFacts = !s.FocusFacts },
+		},
+		{
+			label:  func() string { return tr("Read questions aloud: ") + onOff(s.ReadQuestions) },
+			adjust: func(int) { s.ReadQuestions = !s.ReadQuestions },
+		},
+		{
+			label:  func() string { return tr("Recorded voice callouts: ") + onOff(s.VoiceCallouts) },
+			adjust: func(int) { s.VoiceCallouts = !s.VoiceCallouts },
+		},
+		volumeRow("Master volume: ", &s.MasterVolume),
+		volumeRow("Music volume: ", &s.MusicVolume),
+		volumeRow("Sound effects volume: ", &s.SFXVolume),
+		{
+			label:  func() string { return tr("Mute all sound (M): ") + onOff(s.Muted) },
+			adjust: func(int) { s.Muted = !s.Muted },
+		},
+		{
+			label: func() string {
+				if discordAppID == "" {
+					return tr("Discord status: not set up in this build")
+				}
+				return tr("Discord status: ") + onOff(s.DiscordStatus)
+			},
+			adjust: func(int) { s.DiscordStatus = !s.DiscordStatus },
+		},
+	}
+}
+
+// updateSettings handles keyboard navigation of the options overlay; Tab flips to the controls page
+func (g *Game) updateSettings() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		g.settingsPage = 1 - g.settingsPage
+		g.settingsRow = 0
+		g.bindMsg = ""
+		return
+	}
+	if g.settingsPage == 1 {
+		g.updateControls()
+		return
+	}
+	rows := g.settingRows()
+	if inpututil.IsKeyJustPressed(ebiten.KeyUp) {
+		g.settingsRow = (g.settingsRow + len(rows) - 1) % len(rows)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+		g.settingsRow = (g.settingsRow + 1) % len(rows)
+	}
+	dir := 0
+	if inpututil.IsKeyJustPressed(ebiten.KeyLeft) {
+		dir = -1
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyRight) {
+		dir = 1
+	}
+	if dir != 0 {
+		rows[g.settingsRow].adjust(dir)
+		g.settings.Save()
+	}
+}
+
+func (g *Game) drawSettings(screen *ebiten.Image) {
+	rows := g.settingRows()
+	w := 480.0
+	h := 80.0 + float64(max(len(rows), len(keyActions)+1))*24
+	r := g.centered(w, h)
+	x0, y0 := int(r.X), int(r.Y)
+	rect(screen, float64(x0), float64(y0), w, h, fade(pal.Scrim, 0xD0))
+	if g.settingsPage == 1 {
+		drawText(screen, tr("Controls (press O to close)"), x0+10, y0+20, pal.Text)
+		g.drawControls(screen, x0, y0, h)
+		return
+	}
+	drawText(screen, tr("Settings (press O to close)"), x0+10, y0+20, pal.Text)
+	for i, r := range rows {
+		col := color.Color(pal.Text)
+		prefix := "  "
+		if i == g.settingsRow {
+			col = pal.Warn
+			prefix = "> "
+		}
+		drawText(screen, fmt.Sprintf("%s%s", prefix, r.label()), x0+10, y0+50+i*24, col)
+		if r.value != nil {
+			bar := Rect{float64(x0) + w - 150, float64(y0 + 50 + i*24 - 10), 130, 10}
+			ProgressBar(screen, bar, r.value(), pal.Good)
+		}
+	}
+	if g.settings.newer {
+		drawText(screen, tr("A newer version saved these settings, so changes last until you quit"), x0+10, y0+int(h)-32, pal.Warn)
+	}
+	drawText(screen, tr("Up/Down select, Left/Right change, Tab controls"), x0+10, y0+int(h)-12, pal.TextDim)
+}
+
+func onOff(b bool) string {
+	if b {
+		return tr("On")
+	}
+	return tr("Off")
+}
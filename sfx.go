@@ -0,0 +1,147 @@
+package datagame
+
+// Sfx is a synthesised sound effect
+type Sfx int
+
+const (
+	SfxCorrect Sfx = iota // chime for a right answer
+	SfxWrong              // soft error tone
+	SfxAlarm              // an enemy is about to leak
+	SfxLeak               // an enemy got through
+	SfxImpact             // a bullet lands
+	SfxBoss               // stinger announcing a boss
+	// streak jingles, each longer and higher than the last
+	SfxStreak5
+	SfxStreak10
+	SfxStreak15
+	SfxStreak20
+	sfxCount
+)
+
+// streakMilestone is how many right answers in a row earn a jingle
+const streakMilestone = 5
+
+// streakSfx is the jingle for a streak of n, or false if n isn't a milestone;
+// streaks past the last jingle keep playing it
+func streakSfx(n int) (Sfx, bool) {
+	if n == 0 || n%streakMilestone != 0 {
+		return 0, false
+	}
+	return min(SfxStreak5+Sfx(n/streakMilestone-1), SfxStreak20), true
+}
+
+// bell is a sine with a quieter octave partial, for chimes
+func bell(p float64) float64 {
+	return 0.75*sine(p) + 0.25*sine(2*p)
+}
+
+// render synthesises the effect as 16-bit stereo PCM
+func (s Sfx) render() []byte {
+	switch s {
+	case SfxCorrect:
+		sy := newSynth(0.7)
+		sy.tone(0, 0.5, noteFreq(76), 0.25, 0, bell)
+		sy.tone(0.08, 0.6, noteFreq(83), 0.25, 0, bell)
+		return sy.pcm()
+	case SfxWrong:
+		sy := newSynth(0.6)
+		sy.tone(0, 0.25, noteFreq(55), 0.22, 0, triangle)
+		sy.tone(0.18, 0.35, noteFreq(51), 0.22, 0, triangle)
+		return sy.pcm()
+	case SfxLeak:
+		// low thump with a falling tail
+		sy := newSynth(0.6)
+		sy.kick(0)
+		sy.tone(0.02, 0.4, noteFreq(40), 0.3, 0, triangle)
+		return sy.pcm()
+	case SfxImpact:
+		// short tick
+		sy := newSynth(0.12)
+		sy.tone(0, 0.05, noteFreq(64), 0.12, 0, pulse)
+		seed := uint32(7)
+		sy.hat(0, &seed)
+		return sy.pcm()
+	case SfxBoss:
+		// three low brass-like stabs rising a semitone, then a held minor chord over a drum roll
+		sy := newSynth(2.4)
+		for i, root := range []int{38, 39, 40} {
+			t := 0.22 * float64(i)
+			sy.kick(t)
+			for _, st := range []int{0, 7, 12} {
+				sy.tone(t, 0.2, noteFreq(root+st), 0.12, 0, pulse)
+			}
+		}
+		for i := 0; i < 8; i++ {
+			sy.kick(0.66 + 0.06*float64(i))
+		}
+		for _, st := range []int{0, 3, 7, 12} {
+			sy.tone(0.7, 1.6, noteFreq(38+st), 0.1, 0, triangle)
+			sy.tone(0.7, 1.6, noteFreq(50+st), 0.06, 0, pulse)
+		}
+		return sy.pcm()
+	case SfxAlarm:
+		// two high-low warning beeps
+		sy := newSynth(0.8)
+		for i := 0; i < 4; i++ {
+			note := 81
+			if i%2 == 1 {
+				note = 76
+			}
+			sy.tone(0.16*float64(i), 0.2, noteFreq(note), 0.16, 0, pulse)
+		}
+		return sy.pcm()
+	}
+	// streak jingle: a rising major arpeggio, one more note and a tone higher
+	// per milestone, ending on a held chord
+	tier := int(s - SfxStreak5)
+	root := 72 + 2*tier
+	steps := []int{0, 4, 7, 12, 16, 19, 24}[:3+tier]
+	sy := newSynth(0.07*float64(len(steps)) + 0.9)
+	for i, st := range steps {
+		sy.tone(0.07*float64(i), 0.3, noteFreq(root+st), 0.18, 0.5*float64(i%2*2-1), pulse)
+	}
+	end := 0.07 * float64(len(steps))
+	for _, st := range []int{0, 4, 7} {
+		sy.tone(end, 0.8, noteFreq(root+12+st), 0.12, 0, bell)
+	}
+	return sy.pcm()
+}
+
+// impactGapMS is the least time between impact sounds, so a busy wave doesn't drone
+const impactGapMS = 70.0
+
+// impactCue plays a bullet landing, from where it landed
+func (g *Game) impactCue(x, y float64) {
+	if g.impactCooldownMS > 0 {
+		return
+	}
+	g.impactCooldownMS = impactGapMS
+	sound.PlaySFXAt(SfxImpact, g.panAt(x, y))
+}
+
+// leakCue plays an enemy escaping, from the exit; a horde leaking at once
+// sounds like a few leaks rather than thousands
+func (g *Game) leakCue() {
+	if g.leakCooldownMS > 0 {
+		return
+	}
+	g.leakCooldownMS = impactGapMS
+	exit := g.path[len(g.path)-1]
+	sound.PlaySFXAt(SfxLeak, g.panAt(exit.X, exit.Y))
+}
+
+// answerCue plays the chime or error tone for an answer, and a jingle when a
+// run of right answers reaches a milestone
+func (g *Game) answerCue(correct bool) {
+	if !correct {
+		g.answerStreak = 0
+		sound.PlaySFX(SfxWrong)
+		return
+	}
+	g.answerStreak++
+	if s, ok := streakSfx(g.answerStreak); ok {
+		sound.PlaySFX(s)
+		return
+	}
+	sound.PlaySFX(SfxCorrect)
+}
@@ -0,0 +1,94 @@
+package datagame
+
+import (
+	_ "embed"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Kage shaders for status effects and the paused world
+var (
+	//go:embed shaders/heat.kage
+	heatKage []byte
+	//go:embed shaders/frost.kage
+	frostKage []byte
+	//go:embed shaders/desaturate.kage
+	desaturateKage []byte
+
+	heatShader, frostShader, desaturateShader *ebiten.Shader
+)
+
+func init() {
+	heatShader = mustShader(heatKage)
+	frostShader = mustShader(frostKage)
+	desaturateShader = mustShader(desaturateKage)
+}
+
+func mustShader(src []byte) *ebiten.Shader {
+	s, err := ebiten.NewShader(src)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// shaderUniforms is shared by the status shaders; setShaderUniforms refreshes
+// it once per frame so per-entity draws don't allocate
+var shaderUniforms = map[string]any{}
+
+// setShaderUniforms updates the per-frame shader inputs: the effect clock (ms)
+// and the active palette's frost colour
+func setShaderUniforms(fxMS float64) {
+	shaderUniforms["Time"] = float32(fxMS / 1000)
+	ice := pal.IceCore
+	shaderUniforms["Frost"] = []float32{float32(ice.R) / 0xFF, float32(ice.G) / 0xFF, float32(ice.B) / 0xFF, 1}
+}
+
+// shaderOp is reused between shader draws, like spriteOp
+var shaderOp ebiten.DrawRectShaderOptions
+
+// drawSpriteShader is drawSprite through a status shader
+func drawSpriteShader(dst *ebiten.Image, sh *ebiten.Shader, s Sprite, frame int, x, y, angle, radius float64, col color.Color) {
+	op := &shaderOp
+	op.GeoM.Reset()
+	op.ColorScale.Reset()
+	op.GeoM.Translate(-spriteCell/2, -spriteCell/2)
+	op.GeoM.Rotate(angle)
+	op.GeoM.Scale(radius/spriteRadius, radius/spriteRadius)
+	op.GeoM.Translate(x, y)
+	op.GeoM.Scale(pixelScale, pixelScale)
+	op.ColorScale.ScaleWithColor(col)
+	op.Images[0] = sprites[s][frame]
+	op.Uniforms = shaderUniforms
+	dst.DrawRectShader(spriteCell, spriteCell, sh, op)
+}
+
+// statusShader picks the shader for an enemy's status effects, or nil for a
+// plain sprite; slow wins over burn, matching the tint. The heat haze moves,
+// so it is left out with reduced motion.
+func (g *Game) statusShader(e *Enemy) *ebiten.Shader {
+	switch {
+	case e.SlowTime > 0, e.FreezeTime > 0:
+		return frostShader
+	case e.BurnTime > 0 && !g.reducedMotion():
+		return heatShader
+	}
+	return nil
+}
+
+// pausedWorld returns the rendered map, desaturated while the game is paused
+func (g *Game) pausedWorld() *ebiten.Image {
+	if !g.paused {
+		return g.worldImg
+	}
+	g.pausedImg = scaledImage(g.pausedImg, MapW, MapH)
+	op := &shaderOp
+	op.GeoM.Reset()
+	op.ColorScale.Reset()
+	op.Images[0] = g.worldImg
+	op.Uniforms = nil
+	b := g.worldImg.Bounds()
+	g.pausedImg.DrawRectShader(b.Dx(), b.Dy(), desaturateShader, op)
+	return g.pausedImg
+}
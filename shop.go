@@ -0,0 +1,281 @@
+package datagame
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// hold-to-repeat timing for shop Buy buttons (ms)
+const (
+	shopRepeatDelay    = 400.0
+	shopRepeatInterval = 150.0
+)
+
+// tower-type upgrade steps (ms of effect duration per level)
+const (
+	ShopFlameStepMS = 1000.0
+	ShopSlowStepMS  = 300.0
+)
+
+// shop layout: lines per page and their spacing
+const (
+	shopRows  = 4
+	shopLineH = 40
+)
+
+// shop tabs, indexed by shopItem.tab
+const (
+	shopTabGlobal = iota
+	shopTabTowers
+	shopTabConsumables
+)
+
+var shopTabs = []string{"Global Upgrades", "Towers", "Consumables"}
+
+// shopItem is one upgrade line in the shop
+type shopItem struct {
+	tab   int
+	icon  Icon
+	label string
+	desc  string // effect, shown in the tooltip
+	level int
+	base  int  // price of level n is base*(n+1)
+	cost  int  // price of the next level
+	full  bool // nothing to buy right now: HP already full, or lives at most
+	room  int  // most that are worth buying at once, 0 for no limit
+	buy   func()
+}
+
+func (g *Game) shopItems() []shopItem {
+	items := []shopItem{
+		{tab: shopTabGlobal, icon: IconDamage, label: "Damage +10%", desc: "All towers deal 10% more damage per level", level: g.upDamageLevel, base: 50, buy: func() { g.upDamageLevel++ }},
+		{tab: shopTabGlobal, icon: IconFireRate, label: "Fire Rate +10%", desc: "Normal towers fire 10% faster per level", level: g.upSpeedLevel, base: 40, buy: func() { g.upSpeedLevel++ }},
+		{tab: shopTabGlobal, icon: IconPierce, label: "Armor Penetration +1", desc: "Shots ignore 1 more point of enemy armor", level: g.upPenLevel, base: 60, buy: func() { g.upPenLevel++ }},
+		{tab: shopTabGlobal, icon: IconBlast, label: "AOE Radius +4px", desc: "Shots also hit enemies within 4px more", level: g.upAOELevel, base: 80, buy: func() { g.upAOELevel++ }},
+		{tab: shopTabTowers, icon: IconFlame, label: "Flame duration +1s", desc: "Flame towers set enemies burning 1s longer", level: g.upFlameLevel, base: 45, buy: func() { g.upFlameLevel++ }},
+		{tab: shopTabTowers, icon: IconSlow, label: "Slow duration +0.3s", desc: "Slow towers hold enemies 0.3s longer", level: g.upSlowLevel, base: 45, buy: func() { g.upSlowLevel++ }},
+		{tab: shopTabConsumables, icon: IconHP, label: "Repair 25 HP", desc: "Restores 25 HP; each repair costs more than the last", level: g.repairsBought, base: RepairBaseCost,
+			full: g.playerHP >= PlayerMaxHP, room: int(math.Ceil((PlayerMaxHP - g.playerHP) / RepairHP)), buy: func() { g.repairsBought++; g.heal(RepairHP) }},
+		{tab: shopTabConsumables, icon: IconHP, label: "Extra life", desc: "One more life, to refill HP when it runs out; each costs more than the last", level: g.livesBought, base: LifeBaseCost,
+			full: g.playerLives >= PlayerMaxLives, room: PlayerMaxLives - g.playerLives, buy: func() { g.livesBought++; g.playerLives++ }},
+	}
+	items = append(items, g.itemShopItems()...)
+	for i := range items {
+		items[i].cost = items[i].base * (1 + items[i].level)
+	}
+	return items
+}
+
+// shopItem finds the shop item with this label
+func (g *Game) shopItem(label string) (shopItem, bool) {
+	for _, it := range g.shopItems() {
+		if it.label == label {
+			return it, true
+		}
+	}
+	return shopItem{}, false
+}
+
+// tabItems returns the items of the selected tab
+func (g *Game) tabItems() []shopItem {
+	var out []shopItem
+	for _, it := range g.shopItems() {
+		if it.tab == g.shopTab {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// visibleShopItems is the scrolled window of the selected tab, in screen order
+func (g *Game) visibleShopItems() []shopItem {
+	items := g.tabItems()
+	g.shopScroll = clampInt(g.shopScroll, 0, max(0, len(items)-shopRows))
+	return items[g.shopScroll:min(len(items), g.shopScroll+shopRows)]
+}
+
+// shopLineY is the text baseline of visible line i
+func (g *Game) shopLineY(i int) float64 { return g.shopRect().Y + 86 + float64(i*shopLineH) }
+
+// shopLineAt returns the visible shop line under a view position, or -1
+func (g *Game) shopLineAt(x, y float64) int {
+	r := g.shopRect()
+	if !r.Contains(x, y) {
+		return -1
+	}
+	// each line is a band around its text baseline
+	relY := int(y - (g.shopLineY(0) - 20))
+	if relY < 0 || relY >= shopRows*shopLineH {
+		return -1
+	}
+	return relY / shopLineH
+}
+
+// shopButtons returns one Buy button per visible shop line, greyed out when unaffordable
+func (g *Game) shopButtons() []Button {
+	r := g.shopRect()
+	var btns []Button
+	text := tr("Buy")
+	if shiftHeld() {
+		text = tr("Buy max")
+	}
+	for i, it := range g.visibleShopItems() {
+		it := it
+		btns = append(btns, Button{
+			Rect:     Rect{r.X + r.W - 110, g.shopLineY(i) - 17, 100, 24},
+			Text:     text,
+			Disabled: g.playerGold < it.cost || it.full,
+			OnClick:  func() { g.buyShopItem(it) },
+		})
+	}
+	return btns
+}
+
+// shopTabButtons are the category tabs along the top of the shop
+func (g *Game) shopTabButtons() []Button {
+	r := g.shopRect()
+	w := (r.W - 20 - float64(len(shopTabs)-1)*4) / float64(len(shopTabs))
+	var btns []Button
+	for i, name := range shopTabs {
+		i := i
+		btns = append(btns, Button{
+			Rect:    Rect{r.X + 10 + float64(i)*(w+4), r.Y + 32, w, 24},
+			Text:    tr(name),
+			Active:  i == g.shopTab,
+			OnClick: func() { g.shopTab, g.shopScroll = i, 0 },
+		})
+	}
+	return btns
+}
+
+// scrollShop moves the item list with the mouse wheel while the cursor is over the shop
+func (g *Game) scrollShop() {
+	if !g.shopRect().Contains(cursor()) {
+		return
+	}
+	if _, wy := ebiten.Wheel(); wy > 0 {
+		g.shopScroll--
+	} else if wy < 0 {
+		g.shopScroll++
+	}
+}
+
+func (g *Game) drawShop(screen *ebiten.Image) {
+	r := g.shopRect()
+	x0, y0 := int(r.X), int(r.Y)
+	rect(screen, r.X, r.Y, r.W, r.H, fade(pal.Scrim, 0xC0))
+	drawText(screen, trf("Shop - Buy Upgrades (press %s to close)", g.settings.Keys.Shop), x0+10, y0+20, pal.Text)
+	drawText(screen, trf("Gold: %d", g.playerGold), x0+int(r.W)-110, y0+20, pal.Text)
+	for _, b := range g.shopTabButtons() {
+		b.Draw(screen)
+	}
+
+	items := g.visibleShopItems()
+	if len(items) == 0 {
+		drawText(screen, tr("Nothing for sale here yet."), x0+10, int(g.shopLineY(0)), pal.TextDim)
+	}
+	// each upgrade line: icon, label, level and cost
+	for i, l := range items {
+		yy := int(g.shopLineY(i))
+		drawIcon(screen, l.icon, float64(x0+10), float64(yy-11))
+		line := trf("%s (Lv %d) - Cost: %d", tr(l.label), l.level, l.cost)
+		// with Shift held, show what Buy max would do
+		if shiftHeld() {
+			n, total := l.maxBuy(g.playerGold)
+			line = trf("%s (Lv %d) - %d levels: %d", tr(l.label), l.level, n, total)
+		}
+		drawText(screen, line, x0+30, yy, pal.Text)
+	}
+	for _, b := range g.shopButtons() {
+		b.Draw(screen)
+	}
+	// scroll indicator when the tab has more lines than fit
+	if n := len(g.tabItems()); n > shopRows {
+		track := Rect{r.X + r.W - 6, g.shopLineY(0) - 20, 3, shopRows * shopLineH}
+		rect(screen, track.X, track.Y, track.W, track.H, pal.TrackLight)
+		th := track.H * shopRows / float64(n)
+		ty := track.Y + (track.H-th)*float64(g.shopScroll)/float64(n-shopRows)
+		rect(screen, track.X, ty, track.W, th, pal.TextDim)
+	}
+	drawText(screen, tr("Shift-click: buy max. Hold to repeat."), x0+10, y0+int(r.H)-12, pal.TextDim)
+	// what saving earns, to weigh against spending
+	drawText(screen, trf("Interest next level: +%d", interestOn(g.playerGold)), x0+10, y0+int(r.H)-32, pal.Gold)
+}
+
+// maxBuy returns how many consecutive levels of it the gold pays for, up to
+// its room, and their total price
+func (it shopItem) maxBuy(gold int) (n, total int) {
+	if it.full {
+		return 0, 0
+	}
+	for lvl := it.level; (it.room == 0 || n < it.room) && total+it.base*(1+lvl) <= gold; lvl++ {
+		total += it.base * (1 + lvl)
+		n++
+	}
+	return n, total
+}
+
+// shiftHeld reports whether either Shift key is down (shift-click buys max)
+func shiftHeld() bool {
+	return ebiten.IsKeyPressed(ebiten.KeyShift)
+}
+
+// buyShopItem is the Buy button action: one level, or as many as affordable with Shift
+func (g *Game) buyShopItem(it shopItem) {
+	if !shiftHeld() {
+		g.confirmPurchase(it)
+		return
+	}
+	n, total := it.maxBuy(g.playerGold)
+	if n == 0 {
+		return
+	}
+	buyAll := func() {
+		g.purchase(total, Command{Kind: "buy", Type: it.label, N: n})
+	}
+	if total < ConfirmCostThreshold {
+		buyAll()
+		return
+	}
+	g.ask(tr("Confirm purchase"), trf("Buy %d levels of %s for %d gold?", n, tr(it.label), total), buyAll)
+}
+
+// updateShopHold repeats a Buy while the mouse button is held on it. Expensive
+// items (which need confirmation) and math-gated purchases don't repeat.
+func (g *Game) updateShopHold(dt float64) {
+	x, y := cursor()
+	if pointerJustPressed() {
+		g.shopHoldIdx = -1
+		g.shopRepeats = 0
+		for i, b := range g.shopButtons() {
+			if b.Contains(x, y) {
+				g.shopHoldIdx, g.shopHoldMS = i, 0
+			}
+		}
+	}
+	if g.shopHoldIdx < 0 || !pointerDown() || g.mathGated {
+		return
+	}
+	btns, items := g.shopButtons(), g.visibleShopItems()
+	if g.shopHoldIdx >= len(btns) || !btns[g.shopHoldIdx].Contains(x, y) {
+		g.shopHoldIdx = -1
+		return
+	}
+	g.shopHoldMS += dt
+	it := items[g.shopHoldIdx]
+	if g.shopHoldMS < shopRepeatDelay+float64(g.shopRepeats)*shopRepeatInterval ||
+		it.cost >= ConfirmCostThreshold || g.playerGold < it.cost || it.full {
+		return
+	}
+	g.purchase(it.cost, Command{Kind: "buy", Type: it.label})
+	g.shopRepeats++
+}
+
+// endShopHold is called on mouse release; it reports whether the hold already
+// made purchases, in which case the release must not count as one more click
+func (g *Game) endShopHold() bool {
+	repeated := g.shopRepeats > 0
+	g.shopHoldIdx, g.shopRepeats = -1, 0
+	return repeated
+}
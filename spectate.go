@@ -0,0 +1,398 @@
+package datagame
+
+import (
+	"encoding/json"
+	"flag"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Spectator mode: a game run with -spectate-host lets others watch it. Anyone
+// running the game with -spectate pointing at it gets a view-only copy of the
+// battlefield: the watched game sends a snapshot of its state as a line of
+// JSON over TCP every spectateSnapshotMS, and the spectator draws each one
+// with the usual renderer instead of simulating anything itself. Useful for
+// projecting a student's game on the classroom screen.
+
+var (
+	spectateHost = flag.String("spectate-host", "", "let spectators watch this game, listening on this address, e.g. :7900")
+	spectateJoin = flag.String("spectate", "", "watch the game at this address without playing, e.g. 192.168.1.20:7900")
+)
+
+const (
+	spectateSnapshotMS = 50.0 // real time between snapshots
+	spectateInterval   = time.Duration(spectateSnapshotMS) * time.Millisecond
+	spectateRetry      = 3 * time.Second
+)
+
+// spectateSnapshot is everything a spectator needs to draw the watched game
+type spectateSnapshot struct {
+	Path       []Vec        `json:"path"`
+	Level      int          `json:"level"`
+	HP         float64      `json:"hp"`
+	Lives      int          `json:"lives"`
+	Armor      float64      `json:"armor"`
+	Gold       int          `json:"gold"`
+	Score      int          `json:"score"`
+	Combo      int          `json:"combo"`
+	Kills      int          `json:"kills"`
+	Threshold  int          `json:"threshold"`
+	ToSpawn    int          `json:"to_spawn"`
+	Spawned    int          `json:"spawned"`
+	Wave       WaveStats    `json:"wave"`
+	LastWave   WaveStats    `json:"last_wave"`
+	InterLevel float64      `json:"inter_level,omitempty"` // ms left of the inter-level pause, 0 outside it
+	Paused     bool         `json:"paused,omitempty"`
+	Speed      int          `json:"speed,omitempty"` // index into gameSpeeds
+	Message    string       `json:"message,omitempty"`
+	Question   string       `json:"question,omitempty"` // the open question, "" when none
+	Input      string       `json:"input,omitempty"`    // the answer typed so far
+	GameOver   string       `json:"game_over,omitempty"`
+	Towers     []towerView  `json:"towers"`
+	Enemies    []enemyView  `json:"enemies"`
+	Bullets    []bulletView `json:"bullets"`
+}
+
+// towerView, enemyView and bulletView are what is drawn of each. Their keys
+// are short as horde levels send thousands of enemies in every snapshot.
+type towerView struct {
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Type     string  `json:"t"`
+	Angle    float64 `json:"a"`
+	Range    float64 `json:"r"`
+	Upgrades int     `json:"u,omitempty"`
+	Owner    int     `json:"o,omitempty"`
+}
+
+type enemyView struct {
+	Serial    int     `json:"s"`
+	Dist      float64 `json:"d"`
+	HP        float64 `json:"hp"`
+	MaxHP     float64 `json:"max"`
+	Boss      bool    `json:"b,omitempty"`
+	Shield    float64 `json:"sh,omitempty"`
+	MaxShield float64 `json:"msh,omitempty"`
+	Burn      float64 `json:"burn,omitempty"`
+	BurnLevel int     `json:"bl,omitempty"`
+	Slow      float64 `json:"slow,omitempty"`
+	Freeze    float64 `json:"frz,omitempty"`
+	Flash     float64 `json:"f,omitempty"`
+}
+
+type bulletView struct {
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	Kind string  `json:"k"`
+}
+
+// spectateFromFlags sets up whichever side of spectator mode the flags ask for
+func spectateFromFlags() (*spectateServer, *spectateView) {
+	switch {
+	case *spectateHost != "":
+		return startSpectateServer(*spectateHost), nil
+	case *spectateJoin != "":
+		return nil, startSpectateView(*spectateJoin)
+	}
+	return nil, nil
+}
+
+// --- the watched game ---
+
+// spectateServer sends snapshots to every connected spectator. Each has a
+// one-snapshot buffer; a spectator that falls behind skips snapshots rather
+// than holding up the game. mu guards viewers and err.
+type spectateServer struct {
+	addr    string
+	mu      sync.Mutex
+	viewers map[chan []byte]bool
+	err     error
+	// game goroutine only
+	sendMS   float64
+	watched  int  // spectators last time round, to say when one comes or goes
+	errShown bool // the player has been told listening failed
+}
+
+func startSpectateServer(addr string) *spectateServer {
+	s := &spectateServer{addr: addr, viewers: map[chan []byte]bool{}}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		s.err = err
+		return s
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				s.mu.Lock()
+				s.err = err
+				s.mu.Unlock()
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// serve writes snapshots to one spectator until they disconnect
+func (s *spectateServer) serve(conn net.Conn) {
+	defer conn.Close()
+	out := make(chan []byte, 1)
+	s.mu.Lock()
+	s.viewers[out] = true
+	s.mu.Unlock()
+	for line := range out {
+		if _, err := conn.Write(line); err != nil {
+			break
+		}
+	}
+	s.mu.Lock()
+	delete(s.viewers, out)
+	s.mu.Unlock()
+}
+
+// updateSpectators sends a snapshot every spectateSnapshotMS while anyone is
+// watching, and says when spectators come and go
+func (g *Game) updateSpectators(dt float64) {
+	s := g.spectators
+	if s == nil {
+		return
+	}
+	s.sendMS -= dt
+	if s.sendMS > 0 {
+		return
+	}
+	s.sendMS = spectateSnapshotMS
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil && !s.errShown {
+		s.errShown = true
+		g.levelMsg = tr("Couldn't listen for spectators: ") + s.err.Error()
+		g.levelMsgTimer = 4000
+		return
+	}
+	if n := len(s.viewers); n != s.watched {
+		g.levelMsg = tr("A spectator stopped watching")
+		if n > s.watched {
+			g.levelMsg = tr("A spectator is watching your game")
+		}
+		g.levelMsgTimer = 2000
+		s.watched = n
+	}
+	if len(s.viewers) == 0 {
+		return
+	}
+	line, err := json.Marshal(g.snapshot())
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+	for out := range s.viewers {
+		select {
+		case out <- line:
+		default:
+		}
+	}
+}
+
+// snapshot captures what a spectator draws of the game right now
+func (g *Game) snapshot() *spectateSnapshot {
+	s := &spectateSnapshot{Path: g.path, Level: g.level, HP: g.playerHP, Lives: g.playerLives, Armor: g.playerArmor, Gold: g.playerGold,
+		Score: g.score, Combo: g.combo, Kills: g.killCount, Threshold: g.nextLevelThreshold, ToSpawn: g.enemiesToSpawn, Spawned: g.enemiesSpawned,
+		Wave: g.wave, LastWave: g.lastWave, Paused: g.paused, Speed: g.speedIdx}
+	if g.interLevelActive {
+		s.InterLevel = max(g.interLevelTimer, 1)
+	}
+	if g.levelMsgTimer > 0 {
+		s.Message = g.levelMsg
+	}
+	if g.challengeActive && g.question != nil {
+		s.Question, s.Input = g.question.Text, g.inputBuf
+	}
+	if g.gameOver {
+		s.GameOver = g.endReason
+	}
+	s.Towers = make([]towerView, len(g.towers))
+	for i, tw := range g.towers {
+		s.Towers[i] = towerView{X: tw.X, Y: tw.Y, Type: tw.Type, Angle: tw.Angle, Range: tw.Range, Upgrades: tw.Upgrades, Owner: tw.Owner}
+	}
+	s.Enemies = make([]enemyView, len(g.enemies))
+	for i, e := range g.enemies {
+		s.Enemies[i] = enemyView{Serial: e.Serial, Dist: e.Dist, HP: e.HP, MaxHP: e.MaxHP, Boss: e.Boss, Shield: e.Shield,
+			MaxShield: e.MaxShield, Burn: e.BurnTime, BurnLevel: e.BurnLevel, Slow: e.SlowTime, Freeze: e.FreezeTime, Flash: e.HitFlash}
+	}
+	s.Bullets = make([]bulletView, len(g.bullets))
+	for i, b := range g.bullets {
+		s.Bullets[i] = bulletView{X: b.X, Y: b.Y, Kind: b.kind()}
+	}
+	return s
+}
+
+// --- the spectator ---
+
+// spectateView receives snapshots, redialling when the connection drops. Only
+// the newest snapshot is kept; the game goroutine applies it when it changes.
+type spectateView struct {
+	addr      string
+	latest    atomic.Pointer[spectateSnapshot]
+	connected atomic.Bool
+	// game goroutine only
+	applied  *spectateSnapshot
+	gameOver string
+	overs    int            // times gameOver has changed, keying its text's memo
+	bySerial map[int]*Enemy // scratch map for matching enemies between snapshots
+	kinds    map[string]*Tower
+}
+
+func startSpectateView(addr string) *spectateView {
+	w := &spectateView{addr: addr, bySerial: map[int]*Enemy{}, kinds: map[string]*Tower{}}
+	go w.run()
+	return w
+}
+
+func (w *spectateView) run() {
+	for {
+		conn, err := net.DialTimeout("tcp", w.addr, spectateRetry)
+		if err != nil {
+			time.Sleep(spectateRetry)
+			continue
+		}
+		w.connected.Store(true)
+		dec := json.NewDecoder(conn)
+		for {
+			var s spectateSnapshot
+			if dec.Decode(&s) != nil {
+				break
+			}
+			w.latest.Store(&s)
+		}
+		conn.Close()
+		w.connected.Store(false)
+		time.Sleep(spectateRetry)
+	}
+}
+
+// updateWatch replaces the simulation on a spectator: it applies the newest
+// snapshot and otherwise only lets the camera move
+func (g *Game) updateWatch(dt float64) {
+	w := g.watch
+	if s := w.latest.Load(); s != w.applied {
+		g.applySnapshot(s)
+		w.applied = s
+	}
+	for _, e := range g.enemies {
+		e.Anim.Update(dt)
+	}
+	g.updateCamera(dt)
+	if inpututil.IsKeyJustPressed(ebiten.KeyF11) {
+		toggleFullscreen()
+	}
+}
+
+// applySnapshot makes the game look like s. Enemies are matched to the ones
+// already shown by serial, and glide from where they are drawn now to their
+// new place over the next snapshot interval (see tickAlpha).
+func (g *Game) applySnapshot(s *spectateSnapshot) {
+	w := g.watch
+	alpha := g.tickAlpha()
+	if len(s.Path) >= 2 && !slices.Equal(s.Path, g.path) {
+		g.setPath(s.Path)
+	}
+	g.level, g.playerHP, g.playerArmor, g.playerGold = s.Level, s.HP, s.Armor, s.Gold
+	g.playerLives = s.Lives
+	g.score, g.combo = s.Score, s.Combo
+	g.killCount, g.nextLevelThreshold = s.Kills, s.Threshold
+	g.enemiesToSpawn, g.enemiesSpawned = s.ToSpawn, s.Spawned
+	g.wave, g.lastWave = s.Wave, s.LastWave
+	g.interLevelActive, g.interLevelTimer = s.InterLevel > 0, s.InterLevel
+	g.paused, g.speedIdx = s.Paused, max(0, min(s.Speed, len(gameSpeeds)-1))
+	g.levelMsg, g.levelMsgTimer = s.Message, 0
+	if s.Message != "" {
+		g.levelMsgTimer = 1
+	}
+	g.challengeActive = s.Question != ""
+	g.question, g.inputBuf = nil, s.Input
+	if g.challengeActive {
+		g.question = &Question{Text: s.Question}
+	}
+	if s.GameOver != w.gameOver {
+		w.gameOver = s.GameOver
+		w.overs++
+	}
+
+	g.towers = g.towers[:0]
+	for _, v := range s.Towers {
+		g.towers = append(g.towers, &Tower{X: v.X, Y: v.Y, Type: v.Type, Angle: v.Angle, Range: v.Range, Upgrades: v.Upgrades, Owner: v.Owner})
+	}
+
+	clear(w.bySerial)
+	for _, e := range g.enemies {
+		w.bySerial[e.Serial] = e
+	}
+	live := g.enemies[:0]
+	for _, v := range s.Enemies {
+		e, ok := w.bySerial[v.Serial]
+		if ok {
+			delete(w.bySerial, v.Serial)
+			e.PrevDist += (e.Dist - e.PrevDist) * alpha
+		} else {
+			e = g.newEnemy(Enemy{PrevDist: v.Dist})
+			clip := animEnemyWalk
+			if v.Boss {
+				clip = animBossWalk
+			}
+			e.Anim.Play(clip)
+		}
+		e.Serial, e.Dist, e.HP, e.MaxHP, e.Boss = v.Serial, v.Dist, v.HP, v.MaxHP, v.Boss
+		e.Shield, e.MaxShield = v.Shield, v.MaxShield
+		e.BurnTime, e.BurnLevel, e.SlowTime, e.HitFlash = v.Burn, v.BurnLevel, v.Slow, v.Flash
+		e.FreezeTime = v.Freeze
+		e.Pos = g.posAlongPath(e.Dist)
+		live = append(live, e)
+	}
+	for _, e := range w.bySerial {
+		g.freeEnemy(e)
+	}
+	g.enemies = g.compactEnemies(live)
+
+	g.bullets = g.bullets[:0]
+	for _, v := range s.Bullets {
+		src := w.kinds[v.Kind]
+		if src == nil {
+			src = &Tower{Type: v.Kind}
+			w.kinds[v.Kind] = src
+		}
+		g.bullets = append(g.bullets, &Bullet{X: v.X, Y: v.Y, Src: src})
+	}
+	g.tickAt = time.Now()
+}
+
+// drawWatch shows a spectator what they are watching and how the link is doing
+func (g *Game) drawWatch(screen *ebiten.Image) {
+	w := g.watch
+	if w == nil {
+		return
+	}
+	r := g.place(AnchorBottom, 420, 44, hudMargin)
+	p := Panel{Rect: r, Title: g.hud.watchTitle.get(0, 0, 0, func() string { return trf("Watching %s", w.addr) })}
+	p.Draw(screen)
+	x, y := p.Content()
+	switch {
+	case !w.connected.Load() && w.applied == nil:
+		drawText(screen, tr("Connecting..."), x, y, pal.TextDim)
+	case !w.connected.Load():
+		drawText(screen, tr("Lost the connection; retrying"), x, y, pal.Bad)
+	case w.gameOver != "":
+		over := g.hud.watchOver.get(float64(w.overs), 0, 0, func() string { return trf("The run is over: %s", tr(w.gameOver)) })
+		drawText(screen, over, x, y, pal.Warn)
+	default:
+		drawText(screen, tr("View only. Wheel zooms, WASD pans."), x, y, pal.TextDim)
+	}
+}
@@ -0,0 +1,115 @@
+package datagame
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// gameSpeeds are the simulation multipliers offered by the speed buttons
+var gameSpeeds = []float64{1, 2, 4}
+
+// speedLabels are the speed buttons' captions, formatted once
+var speedLabels = func() []string {
+	labels := make([]string, len(gameSpeeds))
+	for i, s := range gameSpeeds {
+		labels[i] = fmt.Sprintf("%gx", s)
+	}
+	return labels
+}()
+
+const (
+	speedBtnW   = 44.0
+	speedBtnH   = 24.0
+	speedBtnGap = 4.0
+)
+
+// fast-forward while the pause key or a gamepad's right trigger is held
+const (
+	fastForwardSpeed = 3.0
+	// pauseTapMS is the longest press of the pause key that still counts as a
+	// tap (pause); held longer, it fast-forwards instead
+	pauseTapMS = 250.0
+)
+
+// simDT scales a real frame time to simulation time; 0 while paused unless
+// fast-forward is held
+func (g *Game) simDT(dt float64) float64 {
+	switch {
+	case g.fastForward:
+		return dt * fastForwardSpeed
+	case g.paused:
+		return 0
+	}
+	return dt * gameSpeeds[g.speedIdx]
+}
+
+// updateSpeedKeys handles the pause key, which pauses on release when tapped
+// and fast-forwards while held, and the speed key. The persistent speed
+// setting is left alone by fast-forward.
+func (g *Game) updateSpeedKeys(dt float64) {
+	switch {
+	case ebiten.IsKeyPressed(g.settings.Keys.Pause):
+		g.pauseHeldMS += dt
+	case g.pauseHeldMS > 0:
+		if g.pauseHeldMS < pauseTapMS {
+			g.do(Command{Kind: "pause"})
+		}
+		g.pauseHeldMS = 0
+	}
+	// fast-forward is this player's alone, so it is off under lockstep
+	g.fastForward = g.lockstep == nil && (g.pauseHeldMS >= pauseTapMS || g.triggerHeld())
+	if inpututil.IsKeyJustPressed(g.settings.Keys.Speed) {
+		g.do(Command{Kind: "speed", N: (g.speedIdx + 1) % len(gameSpeeds)})
+	}
+}
+
+// speedButtons returns the pause button followed by one button per speed,
+// in a row just above the Challenge button
+func (g *Game) speedButtons() []Button {
+	n := float64(len(gameSpeeds) + 1)
+	row := g.place(AnchorBottomRight, n*speedBtnW+(n-1)*speedBtnGap, speedBtnH, 10)
+	row.Y -= g.challengeButtonRect().H + 8
+	pause := "||"
+	if g.paused {
+		pause = ">"
+	}
+	btns := []Button{{Rect: Rect{row.X, row.Y, speedBtnW, speedBtnH}, Text: pause, Active: g.paused,
+		OnClick: func() { g.do(Command{Kind: "pause"}) }}}
+	for i := range gameSpeeds {
+		i := i
+		x := row.X + float64(i+1)*(speedBtnW+speedBtnGap)
+		btns = append(btns, Button{Rect: Rect{x, row.Y, speedBtnW, speedBtnH}, Text: speedLabels[i], Active: !g.paused && i == g.speedIdx,
+			OnClick: func() { g.do(Command{Kind: "speed", N: i}) }})
+	}
+	return btns
+}
+
+// triggerHeld reports whether the right trigger of any standard-layout gamepad is down
+func (g *Game) triggerHeld() bool {
+	g.gamepads = ebiten.AppendGamepadIDs(g.gamepads[:0])
+	for _, id := range g.gamepads {
+		if ebiten.IsStandardGamepadButtonPressed(id, ebiten.StandardGamepadButtonFrontBottomRight) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Game) drawSpeedButtons(screen *ebiten.Image) {
+	btns := g.speedButtons()
+	for _, b := range btns {
+		b.Draw(screen)
+	}
+	if g.fastForward {
+		drawText(screen, trf("Fast-forward %gx", fastForwardSpeed), int(btns[0].X), int(btns[0].Y)-6, pal.Warn)
+		return
+	}
+	if g.paused {
+		msg := trf("PAUSED - %s to resume", g.settings.Keys.Pause)
+		r := g.centered(textWidth(msg)+40, 30)
+		Panel{Rect: r}.Draw(screen)
+		drawText(screen, msg, int(r.X)+20, int(r.Y)+20, pal.Text)
+	}
+}
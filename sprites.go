@@ -0,0 +1,99 @@
+package datagame
+
+import (
+	"bytes"
+	_ "embed"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// sprites.png has one row of greyscale 32x32 cells per sprite, with animation
+// frames in the columns; sprites are tinted with palette colours when drawn so
+// they follow the active theme. Turret cells point right (angle 0).
+//
+//go:embed assets/sprites.png
+var spriteSheetPNG []byte
+
+// Sprite indexes a cell of the sprite sheet
+type Sprite int
+
+const (
+	SpriteTowerBase Sprite = iota
+	SpriteTurretNormal
+	SpriteTurretFlame
+	SpriteTurretSlow
+	SpriteEnemy
+	SpriteBoss
+	SpriteBullet
+	SpriteGrass // four tile variants
+	SpriteDecor // tree, rock, bush, flowers
+	spriteCount
+)
+
+const (
+	spriteCell   = 32
+	spriteFrames = 4 // columns in the sheet
+	// spriteRadius is the radius the artwork fills at scale 1
+	spriteRadius = 15.0
+)
+
+var sprites [spriteCount][spriteFrames]*ebiten.Image
+
+// spriteSheet is the whole sheet, for batched draws (see spriteBatch)
+var spriteSheet *ebiten.Image
+
+func init() {
+	img, err := png.Decode(bytes.NewReader(spriteSheetPNG))
+	if err != nil {
+		panic(err)
+	}
+	spriteSheet = ebiten.NewImageFromImage(img)
+	for s := range sprites {
+		for f := range sprites[s] {
+			sprites[s][f] = spriteSheet.SubImage(spriteCellRect(Sprite(s), f)).(*ebiten.Image)
+		}
+	}
+}
+
+// spriteCellRect is where frame f of s sits on the sheet
+func spriteCellRect(s Sprite, f int) image.Rectangle {
+	return image.Rect(f*spriteCell, int(s)*spriteCell, (f+1)*spriteCell, (int(s)+1)*spriteCell)
+}
+
+// turretArt picks the turret artwork and its tint for a tower type
+func turretArt(typ string) (Sprite, color.RGBA) {
+	switch towerKind(typ) {
+	case "flame":
+		return SpriteTurretFlame, pal.Fire
+	case "slow":
+		return SpriteTurretSlow, pal.Ice
+	}
+	return SpriteTurretNormal, pal.Metal
+}
+
+// spriteOp is reused between sprite draws to keep Draw allocation-free
+var spriteOp = ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+
+// drawSprite draws frame of s centred on x,y, rotated by angle (radians), scaled
+// so the artwork has the given radius, and tinted with col
+func drawSprite(dst *ebiten.Image, s Sprite, frame int, x, y, angle, radius float64, col color.Color) {
+	op := &spriteOp
+	op.GeoM.Reset()
+	op.ColorScale.Reset()
+	op.GeoM.Translate(-spriteCell/2, -spriteCell/2)
+	op.GeoM.Rotate(angle)
+	op.GeoM.Scale(radius/spriteRadius, radius/spriteRadius)
+	op.GeoM.Translate(x, y)
+	op.GeoM.Scale(pixelScale, pixelScale)
+	op.ColorScale.ScaleWithColor(col)
+	dst.DrawImage(sprites[s][frame], op)
+}
+
+// aim turns a tower toward a map position
+func (tw *Tower) aim(x, y float64) {
+	tw.Angle = math.Atan2(y-tw.Y, x-tw.X)
+}
@@ -0,0 +1,99 @@
+package datagame
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// statusKind identifies a status effect shown next to an enemy's health bar
+type statusKind int
+
+const (
+	statusBurn statusKind = iota
+	statusSlow
+)
+
+// status is one active effect: its kind, stack count and fraction of duration left
+type status struct {
+	kind   statusKind
+	stacks int
+	left   float64 // 0-1
+}
+
+// maxStatusMS is the duration that fills an effect's timer pip
+const maxStatusMS = 5000.0
+
+// statuses lists the enemy's active effects in a fixed display order
+func (e *Enemy) statuses() []status {
+	var out []status
+	if e.BurnTime > 0 {
+		out = append(out, status{statusBurn, e.BurnLevel, e.BurnTime / maxStatusMS})
+	}
+	if e.SlowTime > 0 {
+		out = append(out, status{statusSlow, 1, e.SlowTime / maxStatusMS})
+	}
+	return out
+}
+
+// drawStatuses draws the effect icons in a row starting at x, vertically centered on y
+func drawStatuses(screen *ebiten.Image, e *Enemy, x, y float64) {
+	for _, s := range e.statuses() {
+		drawStatusIcon(screen, s.kind, x, y-4)
+		// remaining-duration pip under the icon
+		if w := 8 * s.left; w >= 1 {
+			rect(screen, x, y+5, min(w, 8), 1, pal.Text)
+		}
+		x += 10
+		if s.stacks > 1 {
+			n := fmt.Sprint(s.stacks)
+			drawText(screen, n, int(x)-1, int(y)+5, pal.Text)
+			x += textWidth(n)
+		}
+	}
+}
+
+// drawStatusMarks overlays an enemy's body with shapes for its statuses, so
+// they don't rely on the tint alone: slowed enemies are hatched, and each
+// effect's glyph sits on the body in a dark badge
+func drawStatusMarks(screen *ebiten.Image, e *Enemy, p Vec, radius float64) {
+	if e.SlowTime > 0 {
+		hatch(screen, p.X, p.Y, radius-2, 5, fade(pal.IceCore, 0xC0))
+	}
+	sts := e.statuses()
+	x := p.X - float64(len(sts)-1)*7
+	for _, s := range sts {
+		disc(screen, x, p.Y, 6.5, fade(pal.Scrim, 0xB0))
+		drawStatusIcon(screen, s.kind, x-4, p.Y-4)
+		x += 14
+	}
+}
+
+// hatch strokes diagonal lines every gap pixels across a circle
+func hatch(screen *ebiten.Image, cx, cy, r, gap float64, c color.Color) {
+	for d := -r + gap/2; d < r; d += gap {
+		h := math.Sqrt(r*r - d*d)
+		// chord at distance d from the centre, perpendicular to the (1,1) direction
+		ox, oy := d*math.Sqrt2/2, -d*math.Sqrt2/2
+		dx, dy := h*math.Sqrt2/2, h*math.Sqrt2/2
+		line(screen, cx+ox-dx, cy+oy-dy, cx+ox+dx, cy+oy+dy, 1.5, c)
+	}
+}
+
+// drawStatusIcon draws an 8x8 glyph for an effect with its top-left at x,y
+func drawStatusIcon(screen *ebiten.Image, k statusKind, x, y float64) {
+	switch k {
+	case statusBurn:
+		// flame: orange body with a yellow core
+		rect(screen, x+3, y, 2, 2, pal.Fire)
+		rect(screen, x+1, y+2, 6, 6, pal.Fire)
+		rect(screen, x+3, y+4, 2, 3, pal.FireCore)
+	case statusSlow:
+		// snowflake: blue cross with a pale centre
+		rect(screen, x+3, y, 2, 8, pal.Ice)
+		rect(screen, x, y+3, 8, 2, pal.Ice)
+		rect(screen, x+3, y+3, 2, 2, pal.IceCore)
+	}
+}
@@ -0,0 +1,22 @@
+package datagame
+
+import "io/fs"
+
+// Storage is where the game keeps its files: settings, the profile, the
+// teacher's settings, replays, user translations and voice packs. Names are
+// slash-separated and relative to the game's own folder, e.g.
+// "replays/run.json". On the desktop that folder is in the user config
+// directory (storage_desktop.go); in a browser the files live in localStorage
+// (storage_js.go).
+type Storage interface {
+	ReadFile(name string) ([]byte, error)
+	// WriteFile creates or replaces a file, and any folders it needs
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	// List returns the names of the files directly in dir
+	List(dir string) ([]string, error)
+	// Export hands the player a file meant to be taken away, a screenshot or
+	// a session log, and returns where it went
+	Export(name string, data []byte) (string, error)
+}
+
+var storage Storage = newStorage()
@@ -0,0 +1,65 @@
+//go:build !js
+
+package datagame
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// dirStorage keeps the game's files in a folder on disk
+type dirStorage struct {
+	dir string
+}
+
+// newStorage uses the datagame folder in the user config directory, or in
+// the working directory if there is none
+func newStorage() Storage {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return dirStorage{dir: filepath.Join(dir, "datagame")}
+}
+
+// SetStorageDir keeps the game's files in dir from now on, for platforms
+// without a usable user config directory, such as Android
+func SetStorageDir(dir string) {
+	storage = dirStorage{dir: dir}
+}
+
+func (s dirStorage) path(name string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(name))
+}
+
+func (s dirStorage) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(s.path(name))
+}
+
+func (s dirStorage) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	path := s.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, perm)
+}
+
+func (s dirStorage) List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(s.path(dir))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+// Export saves the file alongside the others and returns its full path
+func (s dirStorage) Export(name string, data []byte) (string, error) {
+	return s.path(name), s.WriteFile(name, data, 0o644)
+}
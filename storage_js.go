@@ -0,0 +1,92 @@
+//go:build js
+
+package datagame
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+	"syscall/js"
+)
+
+// storageKeyPrefix starts every localStorage key the game uses, keeping its
+// files apart from anything else served from the same site
+const storageKeyPrefix = "datagame/"
+
+// localStorage keeps the game's files in the browser's localStorage, one key
+// per file, base64-encoded since localStorage only holds strings
+type localStorage struct {
+	ls js.Value
+}
+
+func newStorage() Storage {
+	return localStorage{ls: js.Global().Get("localStorage")}
+}
+
+func (s localStorage) ReadFile(name string) ([]byte, error) {
+	if !s.ls.Truthy() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.ErrUnsupported}
+	}
+	v := s.ls.Call("getItem", storageKeyPrefix+name)
+	if v.IsNull() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return base64.StdEncoding.DecodeString(v.String())
+}
+
+// WriteFile stores the file; perm means nothing in a browser. A full
+// localStorage (a few MB per site) is reported as an error.
+func (s localStorage) WriteFile(name string, data []byte, perm fs.FileMode) (err error) {
+	if !s.ls.Truthy() {
+		return &fs.PathError{Op: "write", Path: name, Err: errors.ErrUnsupported}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			jsErr, ok := r.(js.Error)
+			if !ok {
+				panic(r)
+			}
+			err = &fs.PathError{Op: "write", Path: name, Err: jsErr}
+		}
+	}()
+	s.ls.Call("setItem", storageKeyPrefix+name, base64.StdEncoding.EncodeToString(data))
+	return nil
+}
+
+func (s localStorage) List(dir string) ([]string, error) {
+	if !s.ls.Truthy() {
+		return nil, &fs.PathError{Op: "list", Path: dir, Err: errors.ErrUnsupported}
+	}
+	prefix := storageKeyPrefix + strings.TrimSuffix(dir, "/") + "/"
+	var names []string
+	for i := range s.ls.Get("length").Int() {
+		key := s.ls.Call("key", i).String()
+		if name, ok := strings.CutPrefix(key, prefix); ok && !strings.Contains(name, "/") {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// Export downloads the file, as a browser game has no folder to show the
+// player; it returns the file's name
+func (s localStorage) Export(name string, data []byte) (string, error) {
+	doc := js.Global().Get("document")
+	if !doc.Truthy() {
+		return "", &fs.PathError{Op: "export", Path: name, Err: errors.ErrUnsupported}
+	}
+	buf := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(buf, data)
+	url := js.Global().Get("URL")
+	href := url.Call("createObjectURL", js.Global().Get("Blob").New([]any{buf}))
+	a := doc.Call("createElement", "a")
+	a.Set("href", href)
+	a.Set("download", path.Base(name))
+	a.Call("click")
+	// revoked once the download has had time to start, as some browsers
+	// cancel it if the URL goes away straight after the click
+	js.Global().Call("setTimeout", url.Get("revokeObjectURL").Call("bind", url, href), 10000)
+	return path.Base(name), nil
+}
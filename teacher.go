@@ -0,0 +1,316 @@
+package datagame
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"image/color"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// TeacherConfig is the classroom curriculum, edited behind a PIN and stored
+// separately from the player's own settings
+type TeacherConfig struct {
+	Version        int             `json:"version"`         // teacherVersion when saved
+	PINHash        string          `json:"pin_hash"`        // sha256 of the PIN; empty until first set
+	Topics         map[string]bool `json:"topics"`          // allowed question topics (Question.Op); missing means allowed
+	SessionMinutes int             `json:"session_minutes"` // 0 = unlimited
+	MinPerWave     int             `json:"min_per_wave"`    // questions to answer before the next wave may start
+	AutoExport     bool            `json:"auto_export"`     // write a results CSV when each run ends
+	newer          bool            // a newer game wrote teacher.json; see Settings.newer
+}
+
+// teacher overlay states
+const (
+	teacherClosed = iota
+	teacherPIN
+	teacherPanel
+)
+
+// teacherVersion is the layout of teacher.json this game writes; see
+// settingsVersion
+const teacherVersion = 1
+
+// teacherMigrations bring an old teacher.json up to date, like settingsMigrations
+var teacherMigrations = []migration{
+	// 0 to 1: unversioned files only gain the number
+	func(map[string]json.RawMessage) {},
+}
+
+func loadTeacherConfig() *TeacherConfig {
+	c := &TeacherConfig{Topics: map[string]bool{}}
+	from, err := loadVersioned("teacher.json", c, teacherVersion, teacherMigrations)
+	if err != nil {
+		return c
+	}
+	if c.Topics == nil {
+		c.Topics = map[string]bool{}
+	}
+	c.newer = from > teacherVersion
+	if from < teacherVersion {
+		c.Save()
+	}
+	return c
+}
+
+func (c *TeacherConfig) Save() error {
+	if c.newer {
+		return errNewerFile
+	}
+	c.Version = teacherVersion
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile("teacher.json", data, 0o600)
+}
+
+func hashPIN(pin string) string {
+	sum := sha256.Sum256([]byte("datagame:" + pin))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *TeacherConfig) Allowed(topic string) bool {
+	on, ok := c.Topics[topic]
+	return !ok || on
+}
+
+// allowedTopics lists the enabled topics; an empty result means the filter is off
+func (c *TeacherConfig) allowedTopics() []string {
+	var out []string
+	for _, t := range Topics {
+		if c.Allowed(t) {
+			out = append(out, t)
+		}
+	}
+	if len(out) == len(Topics) {
+		return nil
+	}
+	return out
+}
+
+// filterTopic replaces q with an allowed topic when the teacher has restricted topics
+func (g *Game) filterTopic(q *Question, level int) *Question {
+	allowed := g.teacher.allowedTopics()
+	if allowed == nil || g.teacher.Allowed(q.Op) {
+		return q
+	}
+	return genTopicQuestion(g.qrand, allowed[g.qrand.Intn(len(allowed))], level)
+}
+
+// openTeacher starts PIN entry; with no PIN set yet the entry becomes the new PIN
+func (g *Game) openTeacher() {
+	g.closeChallenge()
+	g.teacherState = teacherPIN
+	g.inputBuf = ""
+	g.teacherMsg = ""
+}
+
+func (g *Game) updateTeacher() {
+	switch g.teacherState {
+	case teacherPIN:
+		submitted, cancelled := g.readAnswerInput()
+		if cancelled {
+			g.teacherState = teacherClosed
+			g.inputBuf = ""
+			return
+		}
+		if !submitted {
+			return
+		}
+		if len(g.inputBuf) < 4 {
+			g.teacherMsg = tr("PIN must be at least 4 digits")
+			g.inputBuf = ""
+			return
+		}
+		if g.teacher.PINHash == "" {
+			g.teacher.PINHash = hashPIN(g.inputBuf)
+			g.teacher.Save()
+		} else if hashPIN(g.inputBuf) != g.teacher.PINHash {
+			g.teacherMsg = tr("Wrong PIN")
+			g.inputBuf = ""
+			return
+		}
+		g.inputBuf = ""
+		g.teacherState = teacherPanel
+		g.teacherRow = 0
+	case teacherPanel:
+		rows := g.teacherRows()
+		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) || inpututil.IsKeyJustPressed(ebiten.KeyT) {
+			g.teacherState = teacherClosed
+			return
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyUp) {
+			g.teacherRow = (g.teacherRow + len(rows) - 1) % len(rows)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+			g.teacherRow = (g.teacherRow + 1) % len(rows)
+		}
+		dir := 0
+		if inpututil.IsKeyJustPressed(ebiten.KeyLeft) {
+			dir = -1
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyRight) || inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+			dir = 1
+		}
+		if dir != 0 {
+			rows[g.teacherRow].adjust(dir)
+			g.teacher.Save()
+		}
+	}
+}
+
+func (g *Game) teacherRows() []settingRow {
+	c := g.teacher
+	var rows []settingRow
+	for _, t := range Topics {
+		t := t
+		rows = append(rows, settingRow{
+			label:  func() string { return trf("Topic %-6s %s", t, onOff(c.Allowed(t))) },
+			adjust: func(int) { c.Topics[t] = !c.Allowed(t) },
+		})
+	}
+	rows = append(rows,
+		settingRow{
+			label: func() string {
+				if c.SessionMinutes == 0 {
+					return tr("Session length: unlimited")
+				}
+				return trf("Session length: %d min", c.SessionMinutes)
+			},
+			adjust: func(dir int) { c.SessionMinutes = clampInt(c.SessionMinutes+5*dir, 0, 120) },
+		},
+		settingRow{
+			label:  func() string { return trf("Min questions per wave: %d", c.MinPerWave) },
+			adjust: func(dir int) { c.MinPerWave = clampInt(c.MinPerWave+dir, 0, 20) },
+		},
+		settingRow{
+			label:  func() string { return tr("Export results when a run ends: ") + onOff(c.AutoExport) },
+			adjust: func(int) { c.AutoExport = !c.AutoExport },
+		},
+		settingRow{
+			label: func() string { return tr("Export this session now") },
+			adjust: func(int) {
+				if path, err := g.exportSession(); err != nil {
+					g.teacherMsg = tr("Export failed: ") + err.Error()
+				} else {
+					g.teacherMsg = tr("Saved ") + path
+				}
+			},
+		},
+	)
+	return rows
+}
+
+func (g *Game) drawTeacher(screen *ebiten.Image) {
+	w := 520.0
+	if g.teacherState == teacherPIN {
+		h := 120.0
+		x0 := (g.viewW - int(w)) / 2
+		y0 := g.viewH/2 - 100
+		rect(screen, float64(x0), float64(y0), w, h, fade(pal.Scrim, 0xE0))
+		title := tr("Teacher mode - enter PIN")
+		if g.teacher.PINHash == "" {
+			title = tr("Teacher mode - choose a new PIN (4+ digits)")
+		}
+		drawText(screen, title, x0+10, y0+24, pal.Text)
+		masked := ""
+		for range g.inputBuf {
+			masked += "*"
+		}
+		drawText(screen, tr("PIN: ")+masked, x0+10, y0+54, pal.Text)
+		drawText(screen, g.teacherMsg, x0+10, y0+80, pal.Warn)
+		drawText(screen, tr("Enter to confirm, Esc to cancel"), x0+10, y0+108, pal.TextDim)
+		g.drawKeypad(screen)
+		return
+	}
+	rows := g.teacherRows()
+	h := 90.0 + float64(len(rows))*22
+	r := g.centered(w, h)
+	x0, y0 := int(r.X), int(r.Y)
+	rect(screen, float64(x0), float64(y0), w, h, fade(pal.Scrim, 0xE0))
+	drawText(screen, tr("Teacher configuration (T or Esc to close)"), x0+10, y0+20, pal.Text)
+	for i, r := range rows {
+		col := color.Color(pal.Text)
+		prefix := "  "
+		if i == g.teacherRow {
+			col = pal.Warn
+			prefix = "> "
+		}
+		drawText(screen, prefix+r.label(), x0+10, y0+46+i*22, col)
+	}
+	drawText(screen, g.teacherMsg, x0+10, y0+int(h)-30, pal.Warn)
+	drawText(screen, tr("Up/Down select, Left/Right/Enter change"), x0+10, y0+int(h)-10, pal.TextDim)
+}
+
+// exportSession exports the run's question log as a CSV, into the sessions
+// folder (or as a download in a browser)
+func (g *Game) exportSession() (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"level", "question", "given", "answer", "correct", "seconds"})
+	for _, e := range g.history {
+		w.Write([]string{strconv.Itoa(e.Level), e.Text, e.Given, e.Answer, strconv.FormatBool(e.Correct), strconv.FormatFloat(e.MS/1000.0, 'f', 1, 64)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	return storage.Export("sessions/session-"+g.sessionStart.Format("20060102-150405")+".csv", buf.Bytes())
+}
+
+// endRun stops the tower defense and shows the end-of-run screen
+func (g *Game) endRun(reason string) {
+	if g.gameOver {
+		return
+	}
+	g.gameOver = true
+	g.endReason = reason
+	// file the level in progress and the final gold so the graphs run to the end
+	g.finishWave()
+	g.goldHistory = append(g.goldHistory, g.playerGold)
+	g.earnTech()
+	g.recordScore()
+	if g.teacher.AutoExport {
+		g.exportSession()
+	}
+	g.saveReplay()
+	g.submitScore()
+	g.versusEnded()
+}
+
+// sessionExpired reports whether the teacher's session length has been used
+// up; never under lockstep, where ending the run alone would stall the other
+// game
+func (g *Game) sessionExpired() bool {
+	return g.lockstep == nil && g.teacher.SessionMinutes > 0 && g.playTime >= float64(g.teacher.SessionMinutes)*60000
+}
+
+// waveGateRemaining is how many more answers the teacher requires before the
+// next wave. Under lockstep the wave starts on both games at once, so the
+// gate, which counts this player's answers alone, is off.
+func (g *Game) waveGateRemaining() int {
+	if g.lockstep != nil {
+		return 0
+	}
+	n := g.teacher.MinPerWave - g.waveAnswered
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
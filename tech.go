@@ -0,0 +1,226 @@
+package datagame
+
+import (
+	"encoding/json"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// The tech tree is progress that lasts between runs: every run earns tech
+// points, a point a level cleared and one for every techAnswers right
+// answers, and the game-over screen's tech tree (Tab) spends them on
+// unlocks: new tower types, starting gold, and stronger challenge upgrades.
+// Each unlock needs the one above it in the tree. Unlocks apply to fresh runs
+// of your own, not to daily challenges, ghost races or versus and networked
+// games, which have to start everyone the same; and runs with unlocks are left
+// off the score boards and map records, like runs started past level 1.
+
+const techAnswers = 10 // right answers per tech point
+
+// TechNode is one unlock of the tree
+type TechNode struct {
+	ID       string
+	Name     string
+	Desc     string
+	Cost     int    // in tech points
+	Requires string // the node it hangs from, "" for a root
+	// what it gives a run; towers it unlocks name it in towers.json
+	Gold         int     // starting gold
+	UpgradeBonus float64 // added to each challenge upgrade's effect, as a fraction
+}
+
+var techNodes = []TechNode{
+	{ID: "savings", Name: "Savings", Desc: "Start runs with 50 gold", Cost: 3, Gold: 50},
+	{ID: "trust_fund", Name: "Trust fund", Desc: "Start runs with 100 more gold", Cost: 8, Requires: "savings", Gold: 100},
+	{ID: "sniper", Name: "Sniper tower", Desc: "Unlocks the sniper: long range, heavy, slow shots", Cost: 5},
+	{ID: "inferno", Name: "Inferno tower", Desc: "Unlocks the inferno: short range, long fierce burns", Cost: 10, Requires: "sniper"},
+	{ID: "sharp_mind", Name: "Sharp mind", Desc: "Challenge upgrades are 25% stronger", Cost: 4, UpgradeBonus: 0.25},
+	{ID: "genius", Name: "Genius", Desc: "Challenge upgrades are 25% stronger again", Cost: 12, Requires: "sharp_mind", UpgradeBonus: 0.25},
+}
+
+// techNode finds a node by ID, nil for none
+func techNode(id string) *TechNode {
+	for i := range techNodes {
+		if techNodes[i].ID == id {
+			return &techNodes[i]
+		}
+	}
+	return nil
+}
+
+// techDepth is how far down the tree a node hangs
+func techDepth(n *TechNode) int {
+	d := 0
+	for n.Requires != "" {
+		n = techNode(n.Requires)
+		d++
+	}
+	return d
+}
+
+// TechTree is the player's tech points and unlocks, kept in tech.json
+type TechTree struct {
+	Points   int             `json:"points"`
+	Earned   int             `json:"earned"` // over all runs
+	Unlocked map[string]bool `json:"unlocked"`
+	// Off leaves the unlocks out of the player's runs, which then count for
+	// the score boards and map records (O on the tech tree)
+	Off bool `json:"off"`
+}
+
+// runTech is the tree the run in play uses, nil when unlocks are off for it;
+// towerTypeList leaves out the towers it hasn't unlocked
+var runTech *TechTree
+
+// loadTechTree reads the saved tree; a missing or unreadable file gives an empty one
+func loadTechTree() *TechTree {
+	t := &TechTree{Unlocked: map[string]bool{}}
+	data, err := storage.ReadFile("tech.json")
+	if err != nil {
+		return t
+	}
+	if err := json.Unmarshal(data, t); err != nil || t.Unlocked == nil {
+		t.Unlocked = map[string]bool{}
+	}
+	return t
+}
+
+func (t *TechTree) Save() error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile("tech.json", data, 0o644)
+}
+
+// has reports whether the run's tree has unlocked id; "" is always unlocked
+func (t *TechTree) has(id string) bool {
+	return id == "" || (t != nil && t.Unlocked[id])
+}
+
+// canBuy reports whether n is next in the tree and affordable
+func (t *TechTree) canBuy(n *TechNode) bool {
+	return !t.Unlocked[n.ID] && t.has(n.Requires) && t.Points >= n.Cost
+}
+
+// useTech gives a fresh run of the player's own its unlocks, unless they
+// are turned off
+func (g *Game) useTech() {
+	if g.tech.Off {
+		return
+	}
+	runTech = g.tech
+	towerTypes = towerTypeList(towerStats)
+	for _, n := range techNodes {
+		if g.tech.Unlocked[n.ID] {
+			g.playerGold += n.Gold
+			g.upgradeBonus += n.UpgradeBonus
+		}
+	}
+}
+
+// usedTech reports whether the run is playing with unlocks
+func (g *Game) usedTech() bool {
+	return runTech != nil && len(runTech.Unlocked) > 0
+}
+
+// earnTech pays the run's tech points as it ends
+func (g *Game) earnTech() {
+	correct := 0
+	for _, w := range g.waves {
+		correct += w.Correct
+	}
+	g.techEarned = max(0, g.level-max(1, g.startLevel)) + correct/techAnswers
+	if g.techEarned == 0 {
+		return
+	}
+	g.tech.Points += g.techEarned
+	g.tech.Earned += g.techEarned
+	g.tech.Save()
+}
+
+// TechScreen is the tech tree overlay on the game-over screen
+type TechScreen struct {
+	row int
+	msg string
+}
+
+// updateTech handles the tech tree: Up/Down select, Enter unlocks, O turns
+// the unlocks on or off for the next runs, Esc or Tab go back to the
+// game-over screen
+func (g *Game) updateTech() {
+	s := g.techScreen
+	n := len(techNodes)
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape), inpututil.IsKeyJustPressed(ebiten.KeyTab):
+		g.techScreen = nil
+	case inpututil.IsKeyJustPressed(ebiten.KeyUp):
+		s.row = (s.row + n - 1) % n
+	case inpututil.IsKeyJustPressed(ebiten.KeyDown):
+		s.row = (s.row + 1) % n
+	case inpututil.IsKeyJustPressed(ebiten.KeyO):
+		g.tech.Off = !g.tech.Off
+		g.tech.Save()
+		s.msg = tr("Unlocks are on from your next run")
+		if g.tech.Off {
+			s.msg = tr("Unlocks are off from your next run, so it can go on the boards")
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter), inpututil.IsKeyJustPressed(ebiten.KeyKPEnter):
+		node := &techNodes[s.row]
+		t := g.tech
+		switch {
+		case t.Unlocked[node.ID]:
+			s.msg = tr("Already unlocked")
+		case !t.has(node.Requires):
+			s.msg = trf("Unlock %s first", tr(techNode(node.Requires).Name))
+		case t.Points < node.Cost:
+			s.msg = trf("%d more tech points needed", node.Cost-t.Points)
+		default:
+			t.Points -= node.Cost
+			t.Unlocked[node.ID] = true
+			t.Save()
+			s.msg = trf("Unlocked %s - it applies from your next run", tr(node.Name))
+		}
+	}
+}
+
+func (g *Game) drawTech(screen *ebiten.Image) {
+	s := g.techScreen
+	w, h := 620.0, 130.0+float64(len(techNodes))*22
+	r := g.centered(w, h)
+	x0, y0 := int(r.X), int(r.Y)
+	rect(screen, r.X, r.Y, w, h, fade(pal.Scrim, 0xF0))
+	drawText(screen, tr("Tech tree (Tab or Esc to go back)"), x0+10, y0+20, pal.Text)
+	points := trf("Tech points: %d", g.tech.Points)
+	drawText(screen, points, x0+int(w-textWidth(points))-10, y0+20, pal.Gold)
+	for i := range techNodes {
+		n := &techNodes[i]
+		col := color.Color(pal.TextDim)
+		state := trf("%d points", n.Cost)
+		switch {
+		case g.tech.Unlocked[n.ID]:
+			col, state = pal.Good, tr("unlocked")
+		case g.tech.canBuy(n):
+			col = pal.Text
+		}
+		prefix := "  "
+		if i == s.row {
+			prefix = "> "
+			if !g.tech.Unlocked[n.ID] {
+				col = pal.Warn
+			}
+		}
+		indent := strings.Repeat("    ", techDepth(n))
+		drawText(screen, prefix+indent+tr(n.Name)+" - "+state, x0+10, y0+46+i*22, col)
+	}
+	drawText(screen, tr(techNodes[s.row].Desc), x0+10, y0+int(h)-54, pal.TextSoft)
+	drawText(screen, s.msg, x0+10, y0+int(h)-32, pal.Warn)
+	drawText(screen, tr("Up/Down select, Enter unlock, O unlocks on/off"), x0+10, y0+int(h)-10, pal.TextDim)
+	if g.tech.Off {
+		off := tr("Unlocks off")
+		drawText(screen, off, x0+int(w-textWidth(off))-10, y0+int(h)-10, pal.Warn)
+	}
+}
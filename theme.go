@@ -0,0 +1,182 @@
+package datagame
+
+import "image/color"
+
+// Palette is one colour theme. Draw code takes every colour from the active
+// palette (pal) so a theme can restyle the whole game; translucent variants
+// are derived with fade.
+type Palette struct {
+	ID   string // saved in settings.json
+	Name string // shown in the settings overlay
+
+	// map
+	Sky, Letterbox                 color.RGBA
+	Grass, Road, RoadEdge          color.RGBA
+	Foliage, Rock, Flower          color.RGBA
+	Enemy, EnemyBurning, EnemySlow color.RGBA
+	Boss, Shield                   color.RGBA
+	Hit                            color.RGBA // enemy flash when a shot lands
+	Tower, TowerSelected, Bullet   color.RGBA
+	Tracer                         color.RGBA // normal tower bullet trails
+	BarBack                        color.RGBA // behind enemy health bars
+	Fire, FireCore, Ice, IceCore   color.RGBA
+
+	// meaning shared by HUD and world
+	Good, Bad, Danger, Flash, Warn, Leak, Timing color.RGBA
+	Gold, GoldDark, Armor, ArmorDark             color.RGBA
+	Stone, StoneDark, Metal, Wood                color.RGBA
+
+	// text and overlays
+	Text, TextDim, TextSoft, TextInverse color.RGBA
+	Scrim                                color.RGBA // overlay backdrops, always faded
+	PanelFill, PanelBorder, PanelTitle   color.RGBA
+	TooltipFill                          color.RGBA
+	Track, TrackLight                    color.RGBA
+	Debug                                color.RGBA
+
+	// buttons and the on-screen keypad
+	Button, ButtonHover, ButtonPressed, ButtonDisabled, ButtonActive color.RGBA
+	Key, KeyHover, KeyPressed                                        color.RGBA
+
+	// coverage heatmap by number of towers in range, capped at the last entry
+	Coverage []color.RGBA
+}
+
+var themes = []Palette{
+	{
+		ID: "default", Name: "Default",
+		Sky: rgb(0xA7D0FF), Letterbox: rgb(0x556B88),
+		Grass: rgb(0x8BC34A), Road: rgb(0xC8A26E), RoadEdge: rgb(0x8D6E47),
+		Foliage: rgb(0x3E8E3E), Rock: rgb(0x9E9E9E), Flower: rgb(0xFF8AB3),
+		Enemy: rgb(0xD9534F), EnemyBurning: rgb(0xFF8866), EnemySlow: rgb(0x6699FF),
+		Boss: rgb(0x803090), Shield: rgb(0x44DDFF), Hit: rgb(0xFFFFFF),
+		Tower: rgb(0x2B6CB0), TowerSelected: rgb(0xFFCC00), Bullet: rgb(0x222222), Tracer: rgb(0xFFE9A0), BarBack: rgb(0xFFFFFF),
+		Fire: rgb(0xFF6600), FireCore: rgb(0xFFDD33), Ice: rgb(0x6699FF), IceCore: rgb(0xDDEEFF),
+
+		Good: rgb(0x5CB85C), Bad: rgb(0xD9534F), Danger: rgb(0xE03A3A), Flash: rgb(0xFF3030),
+		Warn: rgb(0xFFCC00), Leak: rgb(0xFF8888), Timing: rgb(0xF0AD4E),
+		Gold: rgb(0xFFD740), GoldDark: rgb(0xC89610), Armor: rgb(0x9AB4D0), ArmorDark: rgb(0x5A7090),
+		Stone: rgb(0x666666), StoneDark: rgb(0x333333), Metal: rgb(0xDDDDDD), Wood: rgb(0x8B5A2B),
+
+		Text: rgb(0xFFFFFF), TextDim: rgb(0xCCCCCC), TextSoft: rgb(0xDDDDDD), TextInverse: rgb(0x000000), Scrim: rgb(0x000000),
+		PanelFill: fade(rgb(0x101828), 0xC8), PanelBorder: fade(rgb(0xFFFFFF), 0x40), PanelTitle: rgb(0xFFDD88),
+		TooltipFill: fade(rgb(0x202020), 0xEE), Track: rgb(0x333333), TrackLight: rgb(0x444444), Debug: rgb(0x88FF88),
+
+		Button: rgb(0x339933), ButtonHover: rgb(0x44B244), ButtonPressed: rgb(0x226622),
+		ButtonDisabled: rgb(0x555555), ButtonActive: rgb(0xC88A1E),
+		Key: rgb(0x333333), KeyHover: rgb(0x555555), KeyPressed: rgb(0x222222),
+
+		Coverage: []color.RGBA{{}, fade(rgb(0x2B6CB0), 0x40), fade(rgb(0x5CB85C), 0x58), fade(rgb(0xF0C030), 0x68), fade(rgb(0xE05030), 0x78)},
+	},
+	{
+		ID: "dark", Name: "Dark",
+		Sky: rgb(0x1C2331), Letterbox: rgb(0x0B0E14),
+		Grass: rgb(0x34502F), Road: rgb(0x5A4A38), RoadEdge: rgb(0x2E251C),
+		Foliage: rgb(0x27552C), Rock: rgb(0x5C6068), Flower: rgb(0xB86A88),
+		Enemy: rgb(0xE0625E), EnemyBurning: rgb(0xFF9A70), EnemySlow: rgb(0x7FAAFF),
+		Boss: rgb(0xB060C8), Shield: rgb(0x44DDFF), Hit: rgb(0xFFFFFF),
+		Tower: rgb(0x4A8FD8), TowerSelected: rgb(0xFFCC00), Bullet: rgb(0xE8E8E8), Tracer: rgb(0xFFE9A0), BarBack: rgb(0x3A3F4B),
+		Fire: rgb(0xFF7A1A), FireCore: rgb(0xFFDD33), Ice: rgb(0x7FAAFF), IceCore: rgb(0xDDEEFF),
+
+		Good: rgb(0x4CAF50), Bad: rgb(0xE0625E), Danger: rgb(0xE84444), Flash: rgb(0xFF3030),
+		Warn: rgb(0xFFC940), Leak: rgb(0xFF9090), Timing: rgb(0xE8A045),
+		Gold: rgb(0xFFD740), GoldDark: rgb(0xB08410), Armor: rgb(0x9AB4D0), ArmorDark: rgb(0x4A6080),
+		Stone: rgb(0x80858F), StoneDark: rgb(0x50555F), Metal: rgb(0xDDDDDD), Wood: rgb(0x8B5A2B),
+
+		Text: rgb(0xE6E6E6), TextDim: rgb(0xA0A6B0), TextSoft: rgb(0xC8CCD2), TextInverse: rgb(0x101010), Scrim: rgb(0x05070A),
+		PanelFill: fade(rgb(0x0A0E16), 0xE0), PanelBorder: fade(rgb(0xFFFFFF), 0x30), PanelTitle: rgb(0xE8C878),
+		TooltipFill: fade(rgb(0x141820), 0xF0), Track: rgb(0x262A33), TrackLight: rgb(0x343944), Debug: rgb(0x88FF88),
+
+		Button: rgb(0x2E5E8C), ButtonHover: rgb(0x3B76AE), ButtonPressed: rgb(0x1F4468),
+		ButtonDisabled: rgb(0x3A3F4B), ButtonActive: rgb(0xB07A1A),
+		Key: rgb(0x262A33), KeyHover: rgb(0x3A3F4B), KeyPressed: rgb(0x15181E),
+
+		Coverage: []color.RGBA{{}, fade(rgb(0x4A8FD8), 0x50), fade(rgb(0x4CAF50), 0x60), fade(rgb(0xF0C030), 0x70), fade(rgb(0xE05030), 0x80)},
+	},
+	{
+		ID: "high-contrast", Name: "High contrast",
+		Sky: rgb(0x000000), Letterbox: rgb(0x202020),
+		Grass: rgb(0x1A1A1A), Road: rgb(0x505050), RoadEdge: rgb(0xFFFFFF),
+		Foliage: rgb(0x2A6A2A), Rock: rgb(0x707070), Flower: rgb(0xA0A0A0),
+		Enemy: rgb(0xFF2020), EnemyBurning: rgb(0xFF9900), EnemySlow: rgb(0x00E5FF),
+		Boss: rgb(0xFF00FF), Shield: rgb(0x00FFFF), Hit: rgb(0xFFFFFF),
+		Tower: rgb(0x3399FF), TowerSelected: rgb(0xFFFF00), Bullet: rgb(0xFFFFFF), Tracer: rgb(0xFFFF00), BarBack: rgb(0x404040),
+		Fire: rgb(0xFF9900), FireCore: rgb(0xFFFF00), Ice: rgb(0x00E5FF), IceCore: rgb(0xFFFFFF),
+
+		Good: rgb(0x00FF00), Bad: rgb(0xFF2020), Danger: rgb(0xFF0000), Flash: rgb(0xFFFFFF),
+		Warn: rgb(0xFFFF00), Leak: rgb(0xFF6060), Timing: rgb(0xFFAA00),
+		Gold: rgb(0xFFFF00), GoldDark: rgb(0xCC9900), Armor: rgb(0xBBDDFF), ArmorDark: rgb(0x3366AA),
+		Stone: rgb(0xAAAAAA), StoneDark: rgb(0xFFFFFF), Metal: rgb(0xFFFFFF), Wood: rgb(0xCC8844),
+
+		Text: rgb(0xFFFFFF), TextDim: rgb(0xFFFFFF), TextSoft: rgb(0xFFFFFF), TextInverse: rgb(0x000000), Scrim: rgb(0x000000),
+		PanelFill: fade(rgb(0x000000), 0xF0), PanelBorder: rgb(0xFFFFFF), PanelTitle: rgb(0xFFFF00),
+		TooltipFill: fade(rgb(0x000000), 0xF8), Track: rgb(0x404040), TrackLight: rgb(0x606060), Debug: rgb(0x00FF00),
+
+		Button: rgb(0x0044CC), ButtonHover: rgb(0x0066FF), ButtonPressed: rgb(0x002288),
+		ButtonDisabled: rgb(0x404040), ButtonActive: rgb(0xCC6600),
+		Key: rgb(0x202020), KeyHover: rgb(0x0044CC), KeyPressed: rgb(0x002288),
+
+		Coverage: []color.RGBA{{}, fade(rgb(0x0066FF), 0x70), fade(rgb(0x00FF00), 0x70), fade(rgb(0xFFFF00), 0x80), fade(rgb(0xFF0000), 0x90)},
+	},
+	{
+		// Okabe-Ito colours, which stay distinct with red-green and blue-yellow
+		// colour blindness; burning and slowed also differ in brightness
+		ID: "colorblind", Name: "Colorblind safe",
+		Sky: rgb(0xA7D0FF), Letterbox: rgb(0x556B88),
+		Grass: rgb(0x9DB88A), Road: rgb(0xD2B48C), RoadEdge: rgb(0x8D6E47),
+		Foliage: rgb(0x4F7F4F), Rock: rgb(0x9E9E9E), Flower: rgb(0xF0E442),
+		Enemy: rgb(0xCC79A7), EnemyBurning: rgb(0xE69F00), EnemySlow: rgb(0x0072B2),
+		Boss: rgb(0x5A2D82), Shield: rgb(0x56B4E9), Hit: rgb(0xFFFFFF),
+		Tower: rgb(0x0072B2), TowerSelected: rgb(0xF0E442), Bullet: rgb(0x222222), Tracer: rgb(0xF0E442), BarBack: rgb(0xFFFFFF),
+		Fire: rgb(0xE69F00), FireCore: rgb(0xF0E442), Ice: rgb(0x56B4E9), IceCore: rgb(0xDDEEFF),
+
+		Good: rgb(0x009E73), Bad: rgb(0xD55E00), Danger: rgb(0xD55E00), Flash: rgb(0xFFFFFF),
+		Warn: rgb(0xF0E442), Leak: rgb(0xE69F00), Timing: rgb(0xE69F00),
+		Gold: rgb(0xF0E442), GoldDark: rgb(0xB8A000), Armor: rgb(0x9AB4D0), ArmorDark: rgb(0x5A7090),
+		Stone: rgb(0x666666), StoneDark: rgb(0x333333), Metal: rgb(0xDDDDDD), Wood: rgb(0x8B5A2B),
+
+		Text: rgb(0xFFFFFF), TextDim: rgb(0xCCCCCC), TextSoft: rgb(0xDDDDDD), TextInverse: rgb(0x000000), Scrim: rgb(0x000000),
+		PanelFill: fade(rgb(0x101828), 0xD0), PanelBorder: fade(rgb(0xFFFFFF), 0x50), PanelTitle: rgb(0xF0E442),
+		TooltipFill: fade(rgb(0x202020), 0xEE), Track: rgb(0x333333), TrackLight: rgb(0x444444), Debug: rgb(0x56B4E9),
+
+		Button: rgb(0x0072B2), ButtonHover: rgb(0x2A8CC8), ButtonPressed: rgb(0x005080),
+		ButtonDisabled: rgb(0x555555), ButtonActive: rgb(0xE69F00),
+		Key: rgb(0x333333), KeyHover: rgb(0x555555), KeyPressed: rgb(0x222222),
+
+		Coverage: []color.RGBA{{}, fade(rgb(0x56B4E9), 0x50), fade(rgb(0x009E73), 0x60), fade(rgb(0xF0E442), 0x70), fade(rgb(0xD55E00), 0x80)},
+	},
+}
+
+// pal is the active theme
+var pal = &themes[0]
+
+// setTheme selects a palette by ID; unknown IDs mean the default theme
+func setTheme(id string) {
+	pal = &themes[themeIndex(id)]
+}
+
+func themeIndex(id string) int {
+	for i, t := range themes {
+		if t.ID == id {
+			return i
+		}
+	}
+	return 0
+}
+
+// rgb builds an opaque colour from 0xRRGGBB
+func rgb(hex uint32) color.RGBA {
+	return color.RGBA{uint8(hex >> 16), uint8(hex >> 8), uint8(hex), 0xFF}
+}
+
+// fade returns c at alpha a, keeping color.RGBA's premultiplied form
+func fade(c color.RGBA, a uint8) color.RGBA {
+	scale := func(v uint8) uint8 { return uint8(uint32(v) * uint32(a) / 0xFF) }
+	return color.RGBA{scale(c.R), scale(c.G), scale(c.B), a}
+}
+
+// lerpRGBA blends from a to b as t goes from 0 to 1
+func lerpRGBA(a, b color.RGBA, t float64) color.RGBA {
+	mix := func(x, y uint8) uint8 { return uint8(float64(x) + (float64(y)-float64(x))*t) }
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
@@ -0,0 +1,136 @@
+package datagame
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Tooltip is a small description box drawn next to the cursor
+type Tooltip struct {
+	Title string
+	Lines []Label
+}
+
+// Draw places the box below-right of (x, y), flipping it to stay inside the view
+func (t Tooltip) Draw(screen *ebiten.Image, x, y float64, viewW, viewH int) {
+	w := textWidth(t.Title)
+	for _, l := range t.Lines {
+		w = max(w, textWidth(l.Text))
+	}
+	bw := w + 16
+	bh := float64(len(t.Lines)*16 + 28)
+	bx, by := x+14, y+18
+	if bx+bw > float64(viewW) {
+		bx = x - bw - 4
+	}
+	if by+bh > float64(viewH) {
+		by = y - bh - 4
+	}
+	if bx < 0 {
+		bx = 0
+	}
+	if by < 0 {
+		by = 0
+	}
+	rect(screen, bx, by, bw, bh, pal.TooltipFill)
+	rect(screen, bx, by, bw, 1, pal.PanelBorder)
+	drawText(screen, t.Title, int(bx)+8, int(by)+16, pal.PanelTitle)
+	for i, l := range t.Lines {
+		col := l.Color
+		if col == nil {
+			col = pal.TextSoft
+		}
+		drawText(screen, l.Text, int(bx)+8, int(by)+34+i*16, col)
+	}
+}
+
+// hoverTooltip describes whatever UI element or tower is under the cursor
+func (g *Game) hoverTooltip() (Tooltip, bool) {
+	x, y := cursor()
+	if g.shopActive {
+		items := g.visibleShopItems()
+		if i := g.shopLineAt(x, y); i >= 0 && i < len(items) {
+			it := items[i]
+			t := Tooltip{Title: tr(it.label), Lines: []Label{
+				{Text: tr(it.desc)},
+				{Text: trf("Level %d -> %d", it.level, it.level+1)},
+				{Text: trf("Cost: %d gold", it.cost)},
+			}}
+			if g.playerGold < it.cost {
+				t.Lines = append(t.Lines, Label{Text: trf("Need %d more gold", it.cost-g.playerGold), Color: pal.Warn})
+			}
+			if g.mathGated {
+				t.Lines = append(t.Lines, Label{Text: trf("Math-gated: question difficulty %d", purchaseDifficulty(it.cost)), Color: pal.Warn})
+			}
+			return t, true
+		}
+		return Tooltip{}, false
+	}
+	if g.interLevelActive && g.startButtonRect().Contains(x, y) {
+		t := Tooltip{Title: tr("Start level now"), Lines: []Label{{Text: tr("Skip the rest of the pause")}}}
+		if n := g.waveGateRemaining(); n > 0 {
+			t.Lines = append(t.Lines, Label{Text: trf("Answer %d more question(s) first", n), Color: pal.Warn})
+		}
+		return t, true
+	}
+	if g.challengeButtonRect().Contains(x, y) {
+		t := Tooltip{Title: tr("Challenge"), Lines: []Label{{Text: tr("Answer a question to upgrade the selected")}, {Text: tr("tower, or build one at the placement point")}}}
+		if g.mathGated {
+			t.Lines = append(t.Lines, Label{Text: trf("Build cost: %d gold", g.buildCost())})
+		}
+		return t, true
+	}
+	if s := g.statsPanelRect(); s.Contains(x, y) {
+		// one row per resource, 22px apart, starting at the panel's first baseline
+		_, by := Panel{Rect: s}.Content()
+		switch row := int(y-float64(by-15)) / 22; row {
+		case 0:
+			return Tooltip{Title: tr("Health"), Lines: []Label{{Text: tr("Lost when enemies reach the end of the path")}, {Text: tr("The run ends at 0")}}}, true
+		case 1:
+			return Tooltip{Title: tr("Armor"), Lines: []Label{{Text: trf("Each escaping enemy deals %.0f less damage", g.playerArmor)}}}, true
+		default:
+			return Tooltip{Title: tr("Gold"), Lines: []Label{{Text: tr("Earned by defeating enemies")}, {Text: trf("Spend it in the shop (%s)", g.settings.Keys.Shop)}}}, true
+		}
+	}
+	if g.wavePanelRect().Contains(x, y) {
+		t := Tooltip{Title: trf("Level %d", g.level), Lines: []Label{
+			{Text: trf("%d enemies this level", g.enemiesToSpawn)},
+			{Text: trf("A boss arrives every %d levels", BossEveryLevels)},
+		}}
+		return t, true
+	}
+	if i := g.towerAt(g.toWorld(x, y)); i >= 0 {
+		return g.towerTooltip(g.towers[i]), true
+	}
+	return Tooltip{}, false
+}
+
+func (g *Game) towerTooltip(tw *Tower) Tooltip {
+	t := Tooltip{Title: trf("%s tower", tr(tw.Type))}
+	switch towerKind(tw.Type) {
+	case "flame":
+		t.Lines = append(t.Lines, Label{Text: trf("Sets enemies on fire for %.1fs", tw.FlameDuration/1000)})
+	case "slow":
+		t.Lines = append(t.Lines, Label{Text: trf("Halves enemy speed for %.1fs", tw.PulseDuration/1000)})
+	default:
+		t.Lines = append(t.Lines, Label{Text: trf("Damage: %.0f", tw.Damage)})
+	}
+	t.Lines = append(t.Lines,
+		Label{Text: trf("Range: %.0f", tw.Range)},
+		Label{Text: trf("Fire every %.0f ms", tw.Fire)},
+		Label{Text: trf("Upgrades: %d", tw.Upgrades)},
+		Label{Text: tr("Select it and answer a challenge to upgrade"), Color: pal.PanelTitle},
+	)
+	return t
+}
+
+// drawTooltip shows the hover tooltip unless a modal overlay owns the screen
+func (g *Game) drawTooltip(screen *ebiten.Image) {
+	if g.challengeActive || g.gameOver || g.teacherState != teacherClosed || g.confirm != nil ||
+		g.settingsActive || g.historyActive || g.reportActive || g.online.active {
+		return
+	}
+	if t, ok := g.hoverTooltip(); ok {
+		x, y := cursor()
+		t.Draw(screen, x, y, g.viewW, g.viewH)
+	}
+}
@@ -0,0 +1,141 @@
+package datagame
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Touch input. One finger works like the left mouse button: a tap clicks, a
+// drag from a tower moves it and a drag anywhere else pans the map. Two
+// fingers pinch to zoom and drag to pan, and a two-finger tap backs out like a
+// right click. Game code asks pointerDown, pointerJustPressed,
+// pointerJustReleased, cancelJustPressed and cursor rather than the mouse, so
+// it takes either.
+
+const touchSlop = 10.0 // view px a finger moves before a press becomes a drag
+
+// touchInput is the touch screen's state, updated once per tick
+type touchInput struct {
+	ids     []ebiten.TouchID
+	used    bool // a touch has been seen, so the touch-only buttons show
+	recent  bool // the last pointer input was a touch, so cursor follows it
+	mouse   Vec  // the mouse position, to tell when it moves again
+	ignore  bool // a finger left over from a pinch, ignored until it lifts
+	down    bool // one finger is on the screen
+	pressed bool // it went down this tick
+	tapped  bool // it lifted this tick without dragging
+	dragged bool // it has moved past touchSlop
+	start   Vec
+	pos     Vec // the finger, or the midpoint of two, in view px
+	pan     Vec // how far pos moved this tick
+	// two fingers
+	pinch      bool
+	pinchDist  float64
+	pinchMoved bool    // the pinch zoomed or panned, so it isn't a tap
+	zoom       float64 // this tick's pinch factor, 1 for none
+	cancelled  bool    // a two-finger tap ended this tick
+}
+
+var touch = touchInput{zoom: 1}
+
+// touchPos is a touch's position in view px
+func touchPos(id ebiten.TouchID) Vec {
+	x, y := ebiten.TouchPosition(id)
+	return Vec{float64(x) / pixelScale, float64(y) / pixelScale}
+}
+
+// update reads this tick's touches
+func (t *touchInput) update() {
+	t.pressed, t.tapped, t.cancelled = false, false, false
+	t.pan, t.zoom = Vec{}, 1
+	if mx, my := ebiten.CursorPosition(); (Vec{float64(mx), float64(my)}) != t.mouse {
+		t.mouse, t.recent = Vec{float64(mx), float64(my)}, false
+	}
+	t.ids = ebiten.AppendTouchIDs(t.ids[:0])
+	if len(t.ids) > 0 {
+		t.used, t.recent = true, true
+	}
+	if t.pinch && len(t.ids) < 2 {
+		t.pinch = false
+		t.cancelled = !t.pinchMoved
+		t.ignore = len(t.ids) > 0
+	}
+	switch {
+	case len(t.ids) >= 2:
+		a, b := touchPos(t.ids[0]), touchPos(t.ids[1])
+		mid, d := Vec{(a.X + b.X) / 2, (a.Y + b.Y) / 2}, math.Hypot(a.X-b.X, a.Y-b.Y)
+		if !t.pinch {
+			// a second finger turns a press into a pinch
+			t.pinch, t.pinchMoved, t.down = true, false, false
+			t.start, t.pos, t.pinchDist = mid, mid, d
+			return
+		}
+		t.pan = Vec{mid.X - t.pos.X, mid.Y - t.pos.Y}
+		if t.pinchDist > 0 && d > 0 {
+			t.zoom = d / t.pinchDist
+		}
+		t.pos, t.pinchDist = mid, d
+		if dist(mid, t.start) >= touchSlop || math.Abs(t.zoom-1) > 0.02 {
+			t.pinchMoved = true
+		}
+	case len(t.ids) == 1:
+		if t.ignore {
+			return
+		}
+		p := touchPos(t.ids[0])
+		if !t.down {
+			t.down, t.pressed, t.dragged = true, true, false
+			t.start, t.pos = p, p
+		}
+		t.pan = Vec{p.X - t.pos.X, p.Y - t.pos.Y}
+		t.pos = p
+		if dist(p, t.start) >= touchSlop {
+			t.dragged = true
+		}
+	default:
+		t.tapped = t.down && !t.dragged
+		t.down, t.ignore = false, false
+	}
+}
+
+// pointerDown reports whether the left mouse button or a finger is down
+func pointerDown() bool {
+	return ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) || touch.down
+}
+
+// pointerJustPressed reports whether the left mouse button or a finger went down this tick
+func pointerJustPressed() bool {
+	return inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) || touch.pressed
+}
+
+// pointerJustReleased reports a click: the left mouse button let go, or a
+// tap. A finger lifted after panning isn't one.
+func pointerJustReleased() bool {
+	return inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) || touch.tapped
+}
+
+// cancelJustPressed reports a right click or a two-finger tap
+func cancelJustPressed() bool {
+	return inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) || touch.cancelled
+}
+
+// updateTouchCamera pans the map with one finger dragged from open ground and
+// pans and zooms it with two
+func (g *Game) updateTouchCamera() {
+	c := &g.camera
+	t := &touch
+	if t.pressed {
+		c.touchPan = !g.overUI(t.pos.X, t.pos.Y) && !g.challengeActive
+	}
+	switch {
+	case t.pinch:
+		c.zoomAt(t.zoom, t.pos.X, t.pos.Y, g.viewW, g.viewH)
+		c.X -= t.pan.X / c.Zoom
+		c.Y -= t.pan.Y / c.Zoom
+	case t.down && t.dragged && c.touchPan && g.drag == nil:
+		c.X -= t.pan.X / c.Zoom
+		c.Y -= t.pan.Y / c.Zoom
+	}
+}
@@ -0,0 +1,123 @@
+package datagame
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Question read-aloud through the operating system's speech tools:
+// System.Speech via PowerShell on Windows, `say` on macOS and espeak / spd-say
+// on Linux. Missing tools are silently ignored so the game never depends on them.
+
+var (
+	speechMu  sync.Mutex
+	speechCmd *exec.Cmd
+)
+
+// speak starts reading text aloud in the background, cutting off any earlier utterance
+func speak(text string) {
+	speechMu.Lock()
+	defer speechMu.Unlock()
+	if speechCmd != nil && speechCmd.Process != nil {
+		speechCmd.Process.Kill()
+	}
+	speechCmd = speechCommand(text)
+	if speechCmd == nil {
+		return
+	}
+	cmd := speechCmd
+	if err := cmd.Start(); err != nil {
+		speechCmd = nil
+		return
+	}
+	// reap the process so it doesn't linger as a zombie
+	go cmd.Wait()
+}
+
+func speechCommand(text string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "windows":
+		// single quotes are doubled inside a PowerShell literal
+		lit := strings.ReplaceAll(text, "'", "''")
+		script := "Add-Type -AssemblyName System.Speech; (New-Object System.Speech.Synthesis.SpeechSynthesizer).Speak('" + lit + "')"
+		return exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	case "darwin":
+		return exec.Command("say", text)
+	case "linux", "freebsd", "openbsd":
+		for _, tool := range []string{"espeak-ng", "espeak", "spd-say"} {
+			if path, err := exec.LookPath(tool); err == nil {
+				return exec.Command(path, text)
+			}
+		}
+	}
+	return nil
+}
+
+// spokenText turns question text into words a speech engine reads naturally,
+// e.g. "3x + 2 = 23" -> "3 x plus 2 equals 23", "$1.20" -> "1.20 dollars"
+func spokenText(text string) string {
+	var b strings.Builder
+	fields := strings.Fields(text)
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		switch f {
+		case "+":
+			b.WriteString(tr("plus"))
+		case "-":
+			b.WriteString(tr("minus"))
+		case "*":
+			b.WriteString(tr("times"))
+		case "/":
+			b.WriteString(tr("divided by"))
+		case "=":
+			b.WriteString(tr("equals"))
+		default:
+			w := f
+			// "(3" / "4)" - speak parentheses as grouping words
+			if strings.HasPrefix(w, "(") {
+				b.WriteString(tr("open bracket") + " ")
+				w = strings.TrimPrefix(w, "(")
+			}
+			closing := strings.HasSuffix(w, ")")
+			w = strings.TrimSuffix(w, ")")
+			// coefficient form "3x" reads as "3 x"
+			if strings.HasSuffix(w, "x") && len(w) > 1 {
+				w = strings.TrimSuffix(w, "x") + " x"
+			}
+			if strings.HasPrefix(w, "-") && len(w) > 1 {
+				w = tr("negative") + " " + w[1:]
+			}
+			if strings.HasPrefix(w, "$") {
+				q := strings.TrimSuffix(w[1:], "?")
+				w = q + " " + tr("dollars")
+				if strings.HasSuffix(f, "?") {
+					w += "?"
+				}
+			}
+			b.WriteString(w)
+			if closing {
+				b.WriteString(" " + tr("close bracket"))
+			}
+		}
+	}
+	return b.String()
+}
+
+// readQuestion speaks the current question if read-aloud is enabled, from
+// the recorded voice pack when callouts are on and it covers the question
+func (g *Game) readQuestion() {
+	if !g.settings.ReadQuestions || g.question == nil {
+		return
+	}
+	if g.settings.VoiceCallouts {
+		if pcm, ok := voice.callout(g.settings.Language, g.question.Text); ok {
+			sound.PlayVoice(pcm)
+			return
+		}
+	}
+	speak(spokenText(g.question.Text))
+}
@@ -0,0 +1,355 @@
+package datagame
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Versus: two players on a network play the same map from the same seed, each
+// in their own lane (their own game), and every right answer sends enemies
+// down the opponent's lane. The first to lose ends the match. One player hosts
+// with -host and the other joins with -join; until they connect the game waits
+// in a lobby, where Esc gives up and plays solo.
+//
+// The two games exchange newline-separated JSON versusMsgs over TCP. Each side
+// only simulates its own lane; the opponent is shown from their status reports.
+
+var (
+	versusHost = flag.String("host", "", "host a versus match, listening on this address, e.g. :7777")
+	versusJoin = flag.String("join", "", "join the versus match hosted at this address, e.g. 192.168.1.20:7777")
+)
+
+const (
+	versusProtocol    = 1
+	versusSendCount   = 3      // enemies a right answer sends
+	versusSpawnMS     = 400.0  // gap between sent enemies arriving (game time)
+	versusStatusMS    = 1000.0 // how often each side reports its level and HP
+	versusDialTimeout = 10 * time.Second
+)
+
+// versusMsg is one message between the two games. "hello" (host to joiner)
+// starts the match with Seed; "send" adds Count enemies to the receiver's
+// lane; "status" reports the sender's Level and HP; "lost" says the sender's
+// run ended; "chat" carries a chat message and "emote" an emote id, in Text.
+// "mismatch" (joiner to host) answers a hello from a different Protocol just
+// before the joiner hangs up.
+// "closed" is never sent: the connection goroutines post it when the link
+// drops, with err set.
+type versusMsg struct {
+	Type     string  `json:"type"`
+	Protocol int     `json:"protocol,omitempty"`
+	Seed     int64   `json:"seed,omitempty"`
+	Count    int     `json:"count,omitempty"`
+	Level    int     `json:"level,omitempty"`
+	HP       float64 `json:"hp,omitempty"`
+	Text     string  `json:"text,omitempty"`
+	err      error
+}
+
+type versusState int
+
+const (
+	versusLobby versusState = iota
+	versusPlaying
+	versusWon
+	versusLost
+	versusDropped // the opponent disconnected mid-match; the run goes on solo
+)
+
+// versus is the match's connection and what is known about the opponent
+type versus struct {
+	host bool
+	addr string
+	in   chan versusMsg // from the connection goroutines, drained each Update
+	out  chan versusMsg // to the writer goroutine
+	done chan struct{}  // closed by close, to stop the writer
+	// mu guards what the connecting goroutine sets, so close can undo it
+	mu       sync.Mutex
+	listener net.Listener
+	conn     net.Conn
+	closed   bool
+	state    versusState
+	err      error
+	// the opponent's last status report
+	opp versusMsg
+	// enemies the opponent sent that haven't arrived yet
+	incoming int
+	spawnMS  float64
+	statusMS float64
+}
+
+// versusFromFlags starts hosting or joining when -host or -join is given, else returns nil
+func versusFromFlags() *versus {
+	switch {
+	case *versusHost != "":
+		return startVersus(true, *versusHost)
+	case *versusJoin != "":
+		return startVersus(false, *versusJoin)
+	}
+	return nil
+}
+
+// startVersus listens for or dials the opponent in the background
+func startVersus(host bool, addr string) *versus {
+	v := &versus{host: host, addr: addr, in: make(chan versusMsg, 64), out: make(chan versusMsg, 64), done: make(chan struct{})}
+	go func() {
+		conn, err := v.connect()
+		if err != nil {
+			v.in <- versusMsg{Type: "closed", err: err}
+			return
+		}
+		v.mu.Lock()
+		v.conn = conn
+		if v.closed {
+			conn.Close()
+		}
+		v.mu.Unlock()
+		go v.read(conn)
+		go v.write(conn)
+		if host {
+			hello := versusMsg{Type: "hello", Protocol: versusProtocol, Seed: time.Now().UnixNano()}
+			v.out <- hello
+			v.in <- hello
+		}
+	}()
+	return v
+}
+
+func (v *versus) connect() (net.Conn, error) {
+	if !v.host {
+		return net.DialTimeout("tcp", v.addr, versusDialTimeout)
+	}
+	l, err := net.Listen("tcp", v.addr)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+	v.mu.Lock()
+	v.listener = l
+	if v.closed {
+		l.Close()
+	}
+	v.mu.Unlock()
+	return l.Accept()
+}
+
+func (v *versus) read(conn net.Conn) {
+	dec := json.NewDecoder(conn)
+	for {
+		var m versusMsg
+		if err := dec.Decode(&m); err != nil {
+			v.in <- versusMsg{Type: "closed", err: err}
+			return
+		}
+		v.in <- m
+	}
+}
+
+func (v *versus) write(conn net.Conn) {
+	enc := json.NewEncoder(conn)
+	for {
+		select {
+		case m := <-v.out:
+			err := enc.Encode(m)
+			if m.Type == "mismatch" {
+				// the host has been told; now hang up
+				v.close()
+				return
+			}
+			if err != nil {
+				return
+			}
+		case <-v.done:
+			return
+		}
+	}
+}
+
+// send queues m for the opponent; if the link is backed up it is dropped
+// rather than stalling the frame
+func (v *versus) send(m versusMsg) {
+	if v.state != versusPlaying {
+		return
+	}
+	select {
+	case v.out <- m:
+	default:
+	}
+}
+
+// close hangs up, or stops listening or dialling if still in the lobby
+func (v *versus) close() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.closed {
+		return
+	}
+	v.closed = true
+	close(v.done)
+	if v.listener != nil {
+		v.listener.Close()
+	}
+	if v.conn != nil {
+		v.conn.Close()
+	}
+}
+
+// updateVersus handles the opponent's messages and the match's timers. It
+// reports true while the lobby is up, when nothing else should run.
+func (g *Game) updateVersus(dt float64) bool {
+	v := g.versus
+	for {
+		var m versusMsg
+		select {
+		case m = <-v.in:
+		default:
+			return g.updateVersusMatch(dt)
+		}
+		switch m.Type {
+		case "hello":
+			if m.Protocol != versusProtocol {
+				v.err = errors.New(tr("the other game is a different version"))
+				// tell the host why before hanging up; the writer closes after it
+				select {
+				case v.out <- versusMsg{Type: "mismatch", Protocol: versusProtocol}:
+				default:
+					v.close()
+				}
+				continue
+			}
+			// both games start the same run from the host's seed
+			g.startRun(newGameSeeded(m.Seed))
+			g.versus = v
+			v.state = versusPlaying
+			g.levelMsg = tr("Versus match started: right answers send enemies to your opponent")
+			g.levelMsgTimer = 3000
+		case "send":
+			v.incoming += m.Count
+			g.levelMsg = trf("Your opponent sent %d enemies!", m.Count)
+			g.levelMsgTimer = 1500
+		case "status":
+			v.opp = m
+		case "chat":
+			g.chat.say(tr("Opponent"), m.Text)
+		case "emote":
+			if text := emoteText(m.Text); text != "" {
+				g.chat.say(tr("Opponent"), text)
+			}
+		case "lost":
+			if v.state == versusPlaying {
+				v.state = versusWon
+				g.endRun("YOU WIN")
+			}
+		case "mismatch":
+			v.state = versusDropped
+			v.close()
+			g.levelMsg = tr("Your opponent's game is a different version; play on solo")
+			g.levelMsgTimer = 3000
+		case "closed":
+			switch v.state {
+			case versusLobby:
+				// keep an earlier, more useful reason, such as a version mismatch
+				if v.err == nil {
+					v.err = m.err
+				}
+			case versusPlaying:
+				v.state = versusDropped
+				g.levelMsg = tr("Your opponent disconnected; play on solo")
+				g.levelMsgTimer = 3000
+			}
+		}
+	}
+}
+
+// updateVersusMatch waits in the lobby for the match to start (Esc plays solo
+// instead), then reports status and lets sent enemies into the lane
+func (g *Game) updateVersusMatch(dt float64) bool {
+	v := g.versus
+	if v.state == versusLobby {
+		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			v.close()
+			g.versus = nil
+		}
+		return true
+	}
+	if v.state != versusPlaying {
+		return false
+	}
+	v.statusMS -= dt
+	if v.statusMS <= 0 {
+		v.statusMS = versusStatusMS
+		v.send(versusMsg{Type: "status", Level: g.level, HP: g.playerHP})
+	}
+	// sent enemies keep to game time, so they hold off while paused
+	if v.incoming > 0 && !g.interLevelActive {
+		v.spawnMS -= g.simDT(dt)
+		if v.spawnMS <= 0 {
+			v.spawnMS = versusSpawnMS
+			v.incoming--
+			g.spawnEnemy()
+		}
+	}
+	return false
+}
+
+// versusSend sends enemies to the opponent for a right answer
+func (g *Game) versusSend() {
+	if g.versus != nil {
+		g.versus.send(versusMsg{Type: "send", Count: versusSendCount})
+	}
+}
+
+// versusEnded tells the opponent this run is over, unless they lost first
+func (g *Game) versusEnded() {
+	v := g.versus
+	if v == nil || v.state != versusPlaying {
+		return
+	}
+	v.send(versusMsg{Type: "lost"})
+	v.state = versusLost
+}
+
+// drawVersus draws the lobby while waiting, then the opponent's last status
+// under the wave panel
+func (g *Game) drawVersus(screen *ebiten.Image) {
+	v := g.versus
+	if v == nil {
+		return
+	}
+	if v.state == versusLobby {
+		p := Panel{Rect: g.centered(440, 110), Title: tr("Versus")}
+		p.Draw(screen)
+		x, y := p.Content()
+		msg := g.hud.versusLobby.get(0, 0, 0, func() string {
+			if v.host {
+				return trf("Waiting for an opponent on %s", v.addr)
+			}
+			return trf("Connecting to %s...", v.addr)
+		})
+		drawText(screen, msg, x, y, pal.Text)
+		if v.err != nil {
+			drawText(screen, tr("Couldn't connect: ")+v.err.Error(), x, y+22, pal.Bad)
+		}
+		drawText(screen, tr("Esc: play solo instead"), x, y+44, pal.TextDim)
+		return
+	}
+	if v.state == versusDropped || v.opp.Type == "" {
+		return
+	}
+	r := g.wavePanelRect()
+	r.Y += r.H + hudMargin
+	r.H = 44
+	p := Panel{Rect: r, Title: tr("Opponent")}
+	p.Draw(screen)
+	x, y := p.Content()
+	opp := g.hud.opponent.get(float64(v.opp.Level), math.Round(v.opp.HP), 0, func() string { return trf("Level %d, %.0f HP", v.opp.Level, v.opp.HP) })
+	drawText(screen, opp, x, y, pal.Text)
+}
@@ -0,0 +1,211 @@
+package datagame
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+)
+
+// Recorded voice callouts read questions from a pack of short WAV clips, one
+// per number word and operator, in datagame/voice/<language>/ under the user
+// config directory; e.g. "7 * 8" plays 7.wav, times.wav and 8.wav. Numbers
+// without a clip of their own are built from smaller ones ("56" is 50.wav,
+// 6.wav), so a pack needs 0-20, the tens, hundred and thousand at least.
+// If any clip is missing the question is read by the speech engine instead.
+
+// calloutGap is the silence between clips (seconds)
+const calloutGap = 0.06
+
+// calloutTokens turns question text into clip names, or false if some part
+// of it has no callout (such as a word problem)
+func calloutTokens(text string) ([]string, bool) {
+	var out []string
+	if p := tr("Solve for x: "); strings.HasPrefix(text, p) {
+		out = append(out, "solve_for_x")
+		text = strings.TrimPrefix(text, p)
+	}
+	for _, f := range strings.Fields(text) {
+		switch f {
+		case "+":
+			out = append(out, "plus")
+			continue
+		case "-":
+			out = append(out, "minus")
+			continue
+		case "*":
+			out = append(out, "times")
+			continue
+		case "/":
+			out = append(out, "divided_by")
+			continue
+		case "=":
+			out = append(out, "equals")
+			continue
+		}
+		w := f
+		if strings.HasPrefix(w, "(") {
+			out = append(out, "open_bracket")
+			w = w[1:]
+		}
+		closing := strings.HasSuffix(w, ")")
+		w = strings.TrimSuffix(w, ")")
+		dollars := strings.HasPrefix(w, "$")
+		w = strings.TrimSuffix(strings.TrimPrefix(w, "$"), "?")
+		if strings.HasPrefix(w, "-") && len(w) > 1 {
+			out = append(out, "negative")
+			w = w[1:]
+		}
+		coef := false
+		if w == "x" {
+			out = append(out, "x")
+			w = ""
+		} else if strings.HasSuffix(w, "x") {
+			coef = true
+			w = strings.TrimSuffix(w, "x")
+		}
+		if w != "" {
+			words, ok := numberTokens(w)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, words...)
+		}
+		if coef {
+			out = append(out, "x")
+		}
+		if dollars {
+			out = append(out, "dollars")
+		}
+		if closing {
+			out = append(out, "close_bracket")
+		}
+	}
+	return out, len(out) > 0
+}
+
+// numberTokens names the clips for a number such as "56" or "1.20": whole
+// part in words, then "point" and each decimal digit
+func numberTokens(s string) ([]string, bool) {
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	n, err := strconv.Atoi(whole)
+	if err != nil || n < 0 || n >= 1_000_000 {
+		return nil, false
+	}
+	out := intTokens(n)
+	if hasFrac {
+		out = append(out, "point")
+		for _, d := range frac {
+			if d < '0' || d > '9' {
+				return nil, false
+			}
+			out = append(out, string(d))
+		}
+	}
+	return out, true
+}
+
+// intTokens spells out 0 <= n < 1,000,000 as clip names
+func intTokens(n int) []string {
+	switch {
+	case n <= 20:
+		return []string{strconv.Itoa(n)}
+	case n < 100:
+		out := []string{strconv.Itoa(n / 10 * 10)}
+		if n%10 != 0 {
+			out = append(out, strconv.Itoa(n%10))
+		}
+		return out
+	case n < 1000:
+		out := append(intTokens(n/100), "hundred")
+		if n%100 != 0 {
+			out = append(out, intTokens(n%100)...)
+		}
+		return out
+	}
+	out := append(intTokens(n/1000), "thousand")
+	if n%1000 != 0 {
+		out = append(out, intTokens(n%1000)...)
+	}
+	return out
+}
+
+// voicePack loads and caches clips for one language
+type voicePack struct {
+	lang  string
+	clips map[string][]byte // nil value: known to be missing
+}
+
+var voice = &voicePack{}
+
+// clip returns the PCM for a clip, trying a number's own recording before its parts
+func (v *voicePack) clip(lang, name string) []byte {
+	if v.lang != lang {
+		*v = voicePack{lang: lang, clips: map[string][]byte{}}
+	}
+	if pcm, ok := v.clips[name]; ok {
+		return pcm
+	}
+	pcm, err := loadClip("voice/" + lang + "/" + name + ".wav")
+	if err != nil {
+		pcm = nil
+	}
+	v.clips[name] = pcm
+	return pcm
+}
+
+func loadClip(name string) ([]byte, error) {
+	data, err := storage.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	s, err := wav.DecodeWithSampleRate(sampleRate, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(s)
+}
+
+// callout joins the clips for text into one recording, or returns false if
+// the text can't be voiced from the pack
+func (v *voicePack) callout(lang, text string) ([]byte, bool) {
+	tokens, ok := calloutTokens(text)
+	if !ok {
+		return nil, false
+	}
+	gap := make([]byte, int(calloutGap*sampleRate)*4)
+	var out []byte
+	for i := 0; i < len(tokens); i++ {
+		pcm := v.clip(lang, tokens[i])
+		// a whole number recorded on its own ("56") beats its parts
+		if n, ok := wholeNumberAt(tokens, i); ok {
+			if whole := v.clip(lang, strconv.Itoa(n.value)); whole != nil {
+				pcm, i = whole, i+n.parts-1
+			}
+		}
+		if pcm == nil {
+			return nil, false
+		}
+		out = append(out, pcm...)
+		out = append(out, gap...)
+	}
+	return out, true
+}
+
+type spelledNumber struct{ value, parts int }
+
+// wholeNumberAt recognises a tens word followed by a units word ("50", "6")
+// at tokens[i], which a pack may have recorded as a single clip
+func wholeNumberAt(tokens []string, i int) (spelledNumber, bool) {
+	if i+1 >= len(tokens) {
+		return spelledNumber{}, false
+	}
+	tens, err1 := strconv.Atoi(tokens[i])
+	ones, err2 := strconv.Atoi(tokens[i+1])
+	if err1 != nil || err2 != nil || tens < 30 || tens%10 != 0 || tens >= 100 || ones < 1 || ones > 9 {
+		return spelledNumber{}, false
+	}
+	return spelledNumber{tens + ones, 2}, true
+}
@@ -0,0 +1,42 @@
+package datagame
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// WaveStats counts what happened during one level, for the summary shown in the inter-level pause
+type WaveStats struct {
+	Level    int
+	Kills    int
+	Leaks    int
+	HPLost   float64
+	Gold     int
+	Interest int // paid on the gold saved through the level
+	Answered int
+	Correct  int
+}
+
+// finishWave files the current level's stats as the last-wave summary and starts counting the next
+func (g *Game) finishWave() {
+	g.lastWave = g.wave
+	g.waves = append(g.waves, g.wave)
+	g.wave = WaveStats{Level: g.level}
+}
+
+// drawWaveSummary lists the last wave's stats inside the inter-level box, from baseline y
+func (g *Game) drawWaveSummary(screen *ebiten.Image, x, y int) {
+	w := g.lastWave
+	drawText(screen, trf("Level %d summary", w.Level), x, y, pal.PanelTitle)
+	Label{Icon: IconEnemy, Text: trf("Enemies defeated: %d", w.Kills)}.Draw(screen, x, y+22)
+	leaks := Label{Icon: IconHP, Text: trf("Leaked: %d (-%.0f HP)", w.Leaks, w.HPLost)}
+	if w.Leaks > 0 {
+		leaks.Color = pal.Leak
+	}
+	leaks.Draw(screen, x, y+42)
+	gold := trf("Gold earned: %d", w.Gold)
+	if w.Interest > 0 {
+		gold = trf("Gold earned: %d (+%d interest)", w.Gold, w.Interest)
+	}
+	Label{Icon: IconGold, Text: gold}.Draw(screen, x, y+62)
+	drawText(screen, trf("Questions correct: %d / %d", w.Correct, w.Answered), x+20, y+82, pal.Text)
+}
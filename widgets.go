@@ -0,0 +1,200 @@
+package datagame
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// --- small widget layer for the HUD and menus ---
+
+// Panel is a translucent box with a thin border and an optional title line
+type Panel struct {
+	Rect
+	Title string
+}
+
+func (p Panel) Draw(screen *ebiten.Image) {
+	rect(screen, p.X, p.Y, p.W, p.H, pal.PanelFill)
+	rect(screen, p.X, p.Y, p.W, 1, pal.PanelBorder)
+	rect(screen, p.X, p.Y+p.H-1, p.W, 1, pal.PanelBorder)
+	rect(screen, p.X, p.Y, 1, p.H, pal.PanelBorder)
+	rect(screen, p.X+p.W-1, p.Y, 1, p.H, pal.PanelBorder)
+	if p.Title != "" {
+		drawTextSize(screen, p.Title, int(p.X)+8, int(p.Y)+20, FontHeading, pal.PanelTitle)
+	}
+}
+
+// Content returns the area below the title where rows start (baseline of the first row)
+func (p Panel) Content() (x, y int) {
+	y = int(p.Y) + 18
+	if p.Title != "" {
+		y += 22
+	}
+	return int(p.X) + 8, y
+}
+
+// Icon identifies one of the small glyphs drawn next to HUD labels
+type Icon int
+
+const (
+	IconNone Icon = iota
+	IconHP
+	IconArmor
+	IconGold
+	IconEnemy
+	IconTower
+	IconDamage
+	IconFireRate
+	IconPierce
+	IconBlast
+	IconFlame
+	IconSlow
+)
+
+// Label is a line of text with an optional leading icon
+type Label struct {
+	Icon  Icon
+	Text  string
+	Color color.Color
+}
+
+// Draw places the label with its text baseline at y
+func (l Label) Draw(screen *ebiten.Image, x, y int) {
+	col := l.Color
+	if col == nil {
+		col = pal.Text
+	}
+	if l.Icon != IconNone {
+		drawIcon(screen, l.Icon, float64(x), float64(y-11))
+		x += 20
+	}
+	drawText(screen, l.Text, x, y, col)
+}
+
+// Button is a clickable box with a centered caption and hover/pressed shading.
+// Buttons are rebuilt each frame from game state, so the same value serves
+// both Draw and the click handling in pressButton.
+type Button struct {
+	Rect
+	Text     string
+	Disabled bool
+	Active   bool // highlighted as the current choice of a group
+	OnClick  func()
+}
+
+// Click runs OnClick if (x, y) is on an enabled button; it reports whether the
+// click landed on the button at all, so disabled buttons still swallow it
+func (b Button) Click(x, y float64) bool {
+	if !b.Contains(x, y) {
+		return false
+	}
+	if !b.Disabled && b.OnClick != nil {
+		b.OnClick()
+	}
+	return true
+}
+
+// pressButton delivers a click to the first button under it
+func pressButton(btns []Button, x, y float64) bool {
+	for _, b := range btns {
+		if b.Click(x, y) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b Button) Hovered() bool { return !b.Disabled && b.Contains(cursor()) }
+
+func (b Button) Draw(screen *ebiten.Image) {
+	col := pal.Button
+	switch {
+	case b.Disabled:
+		col = pal.ButtonDisabled
+	case b.Hovered() && pointerDown():
+		col = pal.ButtonPressed
+	case b.Hovered():
+		col = pal.ButtonHover
+	case b.Active:
+		col = pal.ButtonActive
+	}
+	rect(screen, b.X, b.Y, b.W, b.H, col)
+	// subtle border
+	rect(screen, b.X-1, b.Y-1, b.W+2, 1, fade(pal.Scrim, 0x60))
+	rect(screen, b.X-1, b.Y+b.H, b.W+2, 1, fade(pal.Scrim, 0x60))
+	drawText(screen, b.Text, int(b.X+(b.W-textWidth(b.Text))/2), int(b.Y+b.H/2)+4, pal.Text)
+}
+
+// ProgressBar draws a filled bar for frac (0-1) over a dark track
+func ProgressBar(screen *ebiten.Image, r Rect, frac float64, fill color.Color) {
+	rect(screen, r.X, r.Y, r.W, r.H, pal.Track)
+	if w := r.W * math.Max(0, math.Min(1, frac)); w >= 1 {
+		rect(screen, r.X, r.Y, w, r.H, fill)
+	}
+}
+
+// drawIcon draws a 14x14 icon with its top-left corner at x,y
+func drawIcon(screen *ebiten.Image, ic Icon, x, y float64) {
+	switch ic {
+	case IconHP:
+		// heart: two lobes over a tapering point
+		red := pal.Danger
+		disc(screen, x+4, y+4, 3.5, red)
+		disc(screen, x+10, y+4, 3.5, red)
+		for i := 0.0; i < 7; i++ {
+			rect(screen, x+i, y+5+i, 14-2*i, 1, red)
+		}
+	case IconArmor:
+		// shield: square top narrowing to a point
+		steel := pal.Armor
+		rect(screen, x+1, y, 12, 7, steel)
+		for i := 0.0; i < 6; i++ {
+			rect(screen, x+1+i, y+7+i, 12-2*i, 1, steel)
+		}
+		rect(screen, x+6, y+2, 2, 8, pal.ArmorDark)
+	case IconGold:
+		disc(screen, x+7, y+7, 6.5, pal.GoldDark)
+		disc(screen, x+7, y+7, 4.5, pal.Gold)
+	case IconEnemy:
+		disc(screen, x+7, y+7, 6, pal.Enemy)
+		rect(screen, x+4, y+5, 2, 2, pal.TextInverse)
+		rect(screen, x+8, y+5, 2, 2, pal.TextInverse)
+	case IconTower:
+		rect(screen, x+2, y+6, 10, 8, pal.Stone)
+		rect(screen, x+6, y, 2, 7, pal.StoneDark)
+	case IconDamage:
+		// sword: blade over a crossguard
+		rect(screen, x+6, y, 2, 9, pal.Metal)
+		rect(screen, x+3, y+9, 8, 2, pal.GoldDark)
+		rect(screen, x+6, y+11, 2, 3, pal.Wood)
+	case IconFireRate:
+		// three stacked chevrons
+		for i := 0.0; i < 3; i++ {
+			rect(screen, x+2+i*4, y+3, 2, 8, pal.Good)
+		}
+	case IconPierce:
+		// arrow through a plate
+		rect(screen, x+9, y+1, 3, 12, pal.Armor)
+		rect(screen, x, y+6, 14, 2, pal.Metal)
+		rect(screen, x+11, y+4, 2, 6, pal.Metal)
+	case IconBlast:
+		disc(screen, x+7, y+7, 6.5, pal.Fire)
+		disc(screen, x+7, y+7, 3.5, pal.FireCore)
+	case IconFlame:
+		// flame: wide base tapering upwards
+		for i := 0.0; i < 7; i++ {
+			rect(screen, x+1+i, y+13-2*i, 12-2*i, 2, lerpRGBA(pal.Fire, pal.FireCore, i/6))
+		}
+	case IconSlow:
+		// snowflake
+		ice := pal.Ice
+		rect(screen, x+6, y, 2, 14, ice)
+		rect(screen, x, y+6, 14, 2, ice)
+		for i := 0.0; i < 10; i++ {
+			rect(screen, x+2+i, y+2+i, 1, 1, ice)
+			rect(screen, x+11-i, y+2+i, 1, 1, ice)
+		}
+	}
+}